@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// delayedListener holds each accepted connection for a fixed delay before handing it to the
+// server. Connections are accepted one at a time, so a burst queues up behind the delay like a
+// listener whose backlog drains slowly.
+type delayedListener struct {
+	net.Listener
+	delay     time.Duration
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// newDelayedListener wraps l so every connection waits delay before it is served
+func newDelayedListener(l net.Listener, delay time.Duration) *delayedListener {
+	return &delayedListener{Listener: l, delay: delay, closed: make(chan struct{})}
+}
+
+func (l *delayedListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(l.delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return conn, nil
+	case <-l.closed:
+		_ = conn.Close()
+		return nil, net.ErrClosed
+	}
+}
+
+// Close stops the listener, dropping any connection still waiting out its delay
+func (l *delayedListener) Close() error {
+	l.closeOnce.Do(func() { close(l.closed) })
+	return l.Listener.Close()
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcceptDelay(t *testing.T) {
+	const delay = 200 * time.Millisecond
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &http.Server{Handler: okHandler, ReadHeaderTimeout: 5 * time.Second}
+	go func() { _ = server.Serve(newDelayedListener(ln, delay)) }()
+	t.Cleanup(func() { _ = server.Close() })
+
+	client := &http.Client{Timeout: 5 * time.Second, Transport: &http.Transport{}}
+	url := "http://" + ln.Addr().String() + "/"
+	get := func(t *testing.T) time.Duration {
+		t.Helper()
+		start := time.Now()
+		resp, err := client.Get(url)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		return time.Since(start)
+	}
+
+	assert.GreaterOrEqual(t, get(t), delay, "a new connection waits out the delay")
+	assert.Less(t, get(t), delay, "a kept-alive connection is not delayed again")
+}
+
+func TestAcceptDelayClose(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	delayed := newDelayedListener(ln, time.Hour)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := delayed.Accept()
+		done <- err
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+
+	// Give Accept time to pick up the connection and start waiting
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, delayed.Close())
+
+	select {
+	case err := <-done:
+		assert.True(t, errors.Is(err, net.ErrClosed), err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Accept did not return after Close")
+	}
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// clockResponse is the /clock response body
+type clockResponse struct {
+	Time    string `json:"time"`
+	Epoch   int64  `json:"epoch"`
+	EpochMs int64  `json:"epoch_ms"`
+	Skew    string `json:"skew"`
+}
+
+// now is the server clock, replaceable in tests
+var now = time.Now
+
+// handleClock reports the server time offset by --clock-skew, for testing how clients cope with
+// servers whose clocks disagree with their own
+func handleClock(logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		t := now().Add(clockSkew).UTC()
+		logger.Debug("Clock request", slog.Time("reported_time", t), slog.Duration("skew", clockSkew))
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(clockResponse{
+			Time:    t.Format(time.RFC3339Nano),
+			Epoch:   t.Unix(),
+			EpochMs: t.UnixMilli(),
+			Skew:    clockSkew.String(),
+		}); err != nil {
+			logger.Error("Failed to write clock response", slog.String("error", err.Error()))
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClock(t *testing.T) {
+	logger := createTestLogger()
+	mux := newServeMux(okHandler, "test-service", newDrainer("test-service", 0, logger), logger)
+
+	fixed := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return fixed }
+	t.Cleanup(func() { now, clockSkew = time.Now, 0 })
+
+	clock := func(t *testing.T) clockResponse {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/clock", nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+		var body clockResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		return body
+	}
+
+	tests := []struct {
+		name string
+		skew time.Duration
+		want time.Time
+	}{
+		{name: "no skew", skew: 0, want: fixed},
+		{name: "ahead", skew: 90 * time.Second, want: fixed.Add(90 * time.Second)},
+		{name: "behind", skew: -2 * time.Hour, want: fixed.Add(-2 * time.Hour)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clockSkew = tt.skew
+			body := clock(t)
+
+			reported, err := time.Parse(time.RFC3339Nano, body.Time)
+			require.NoError(t, err)
+			assert.True(t, tt.want.Equal(reported), "got %s, want %s", reported, tt.want)
+			assert.Equal(t, tt.want.Unix(), body.Epoch)
+			assert.Equal(t, tt.want.UnixMilli(), body.EpochMs)
+			assert.Equal(t, tt.skew.String(), body.Skew)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/liamawhite/microservice/pkg/proxy"
+)
+
+// drainer tracks whether the server has been asked to drain. Once draining,
+// readiness reports not-ready so orchestrators stop routing new traffic, and
+// after the optional grace period new proxy requests are rejected outright.
+type drainer struct {
+	serviceName string
+	gracePeriod time.Duration
+	logger      *slog.Logger
+
+	once      sync.Once
+	draining  atomic.Bool
+	rejecting atomic.Bool
+}
+
+// newDrainer creates a drainer; a zero grace period keeps serving proxy requests while draining
+func newDrainer(serviceName string, gracePeriod time.Duration, logger *slog.Logger) *drainer {
+	return &drainer{
+		serviceName: serviceName,
+		gracePeriod: gracePeriod,
+		logger:      logger,
+	}
+}
+
+// start flips readiness to not-ready and schedules rejection of new proxy requests
+func (d *drainer) start() {
+	d.once.Do(func() {
+		d.draining.Store(true)
+		d.logger.Info("Drain started", slog.Duration("grace_period", d.gracePeriod))
+
+		if d.gracePeriod > 0 {
+			time.AfterFunc(d.gracePeriod, func() {
+				d.rejecting.Store(true)
+				d.logger.Info("Drain grace period elapsed, rejecting new proxy requests")
+			})
+		}
+	})
+}
+
+// handleDrain starts draining on POST /drain
+func (d *drainer) handleDrain(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, r, http.StatusMethodNotAllowed, proxy.ErrCodeMethodNotAllowed, "method not allowed", d.serviceName, d.logger)
+		return
+	}
+
+	d.start()
+	writeStatus(w, http.StatusAccepted, "draining", d.serviceName, d.logger)
+}
+
+// handleReadyz reports 200 until draining starts, then 503
+func (d *drainer) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if d.draining.Load() {
+		writeStatus(w, http.StatusServiceUnavailable, "draining", d.serviceName, d.logger)
+		return
+	}
+	writeStatus(w, http.StatusOK, "ready", d.serviceName, d.logger)
+}
+
+// handleLivez always reports 200 while the process is running, including while draining
+func (d *drainer) handleLivez(w http.ResponseWriter, r *http.Request) {
+	writeStatus(w, http.StatusOK, "alive", d.serviceName, d.logger)
+}
+
+// middleware rejects requests with 503 once the drain grace period has elapsed
+func (d *drainer) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d.rejecting.Load() {
+			w.Header().Set("Connection", "close")
+			writeError(w, r, http.StatusServiceUnavailable, proxy.ErrCodeDraining, "service is draining", d.serviceName, d.logger)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeStatus writes a small JSON status document used by the health-style endpoints
+func writeStatus(w http.ResponseWriter, statusCode int, status, serviceName string, logger *slog.Logger) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if _, err := fmt.Fprint(w, `{"status":"`+status+`","service":"`+serviceName+`"}`); err != nil {
+		logger.Error("Failed to write status response", slog.String("error", err.Error()))
+	}
+}
+
+// writeError writes an error in the proxy handler's format, as JSON or as plain text depending
+// on the request's Accept header
+func writeError(w http.ResponseWriter, r *http.Request, statusCode int, code, message, serviceName string, logger *slog.Logger) {
+	response := proxy.ErrorResponse{Error: message, Code: code, Service: serviceName}
+	if err := proxy.WriteError(w, r, statusCode, response); err != nil {
+		logger.Error("Failed to write error response", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/liamawhite/microservice/pkg/proxy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createTestLogger creates a logger that writes to stderr for debugging
+func createTestLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+}
+
+// okHandler stands in for the proxy handler
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+func TestDrain(t *testing.T) {
+	logger := createTestLogger()
+
+	get := func(mux http.Handler, path string) int {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr.Code
+	}
+
+	t.Run("drain flips readiness but not liveness", func(t *testing.T) {
+		mux := newServeMux(okHandler, "test-service", newDrainer("test-service", 0, logger), logger)
+
+		assert.Equal(t, http.StatusOK, get(mux, "/readyz"))
+		assert.Equal(t, http.StatusOK, get(mux, "/livez"))
+
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/drain", nil))
+		require.Equal(t, http.StatusAccepted, rr.Code)
+
+		assert.Equal(t, http.StatusServiceUnavailable, get(mux, "/readyz"))
+		assert.Equal(t, http.StatusOK, get(mux, "/livez"))
+		assert.Equal(t, http.StatusOK, get(mux, "/health"))
+		// Without a grace period proxy requests keep being served
+		assert.Equal(t, http.StatusOK, get(mux, "/proxy/svc:8080"))
+	})
+
+	t.Run("drain requires POST", func(t *testing.T) {
+		mux := newServeMux(okHandler, "test-service", newDrainer("test-service", 0, logger), logger)
+
+		assert.Equal(t, http.StatusMethodNotAllowed, get(mux, "/drain"))
+		assert.Equal(t, http.StatusOK, get(mux, "/readyz"))
+	})
+
+	t.Run("proxy requests rejected after grace period", func(t *testing.T) {
+		mux := newServeMux(okHandler, "test-service", newDrainer("test-service", 10*time.Millisecond, logger), logger)
+
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/drain", nil))
+		require.Equal(t, http.StatusAccepted, rr.Code)
+
+		assert.Equal(t, http.StatusOK, get(mux, "/proxy/svc:8080"))
+		assert.Eventually(t, func() bool {
+			return get(mux, "/proxy/svc:8080") == http.StatusServiceUnavailable
+		}, time.Second, 5*time.Millisecond)
+		assert.Equal(t, http.StatusOK, get(mux, "/livez"))
+
+		req := httptest.NewRequest(http.MethodGet, "/proxy/svc:8080", nil)
+		req.Header.Set("Accept", "application/json")
+		rr = httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"error":"service is draining","code":"PROXY_DRAINING","service":"test-service"}`, rr.Body.String())
+	})
+	t.Run("chain endpoints rejected after grace period", func(t *testing.T) {
+		handler, err := proxy.NewHandler(5*time.Second, "test-service", logger)
+		require.NoError(t, err)
+		drain := newDrainer("test-service", 10*time.Millisecond, logger)
+		mux := newServeMux(okHandler, "test-service", drain, logger)
+		registerHandlerEndpoints(mux, handler, drain)
+
+		post := func(path, body string) int {
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+			return rr.Code
+		}
+
+		assert.Equal(t, http.StatusOK, post("/compose", `{}`))
+		assert.Equal(t, http.StatusOK, post("/replay", `{"method":"GET","path":"/"}`))
+		require.Equal(t, http.StatusAccepted, post("/drain", ""))
+		assert.Eventually(t, func() bool {
+			return post("/compose", `{}`) == http.StatusServiceUnavailable
+		}, time.Second, 5*time.Millisecond)
+		assert.Equal(t, http.StatusServiceUnavailable, post("/replay", `{"method":"GET","path":"/"}`))
+	})
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// envPrefix prefixes the environment variable bound to each serve flag, e.g. MICROSERVICE_LOG_LEVEL
+const envPrefix = "MICROSERVICE"
+
+// bindEnv applies MICROSERVICE_<FLAG_NAME> environment variables to every flag of cmd that was
+// not set on the command line, so flags take precedence over the environment
+func bindEnv(cmd *cobra.Command) error {
+	v := viper.New()
+	v.SetEnvPrefix(envPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+
+	var err error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Changed {
+			return
+		}
+		if bindErr := v.BindEnv(f.Name); bindErr != nil {
+			err = bindErr
+			return
+		}
+		if !v.IsSet(f.Name) {
+			return
+		}
+		if setErr := cmd.Flags().Set(f.Name, v.GetString(f.Name)); setErr != nil {
+			err = fmt.Errorf("invalid value for %s from environment: %w", envName(f.Name), setErr)
+		}
+	})
+	return err
+}
+
+// envName returns the environment variable bound to a flag
+func envName(flag string) string {
+	return envPrefix + "_" + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// bindEnvAndValidate is the serve command's PreRunE, applying the environment before validation
+func bindEnvAndValidate(cmd *cobra.Command, args []string) error {
+	if err := bindEnv(cmd); err != nil {
+		return err
+	}
+	return validateFlags(cmd, args)
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBindEnv(t *testing.T) {
+	// Restore serveCmd's flags, and the globals they are bound to, after each case
+	reset := func() {
+		serveCmd.Flags().VisitAll(func(f *pflag.Flag) {
+			if !f.Changed {
+				return
+			}
+			if sv, ok := f.Value.(pflag.SliceValue); ok {
+				_ = sv.Replace(nil)
+			} else {
+				_ = f.Value.Set(f.DefValue)
+			}
+			f.Changed = false
+		})
+	}
+
+	t.Run("environment sets unset flags", func(t *testing.T) {
+		t.Cleanup(reset)
+		t.Setenv("MICROSERVICE_PORT", "9090")
+		t.Setenv("MICROSERVICE_LOG_LEVEL", "debug")
+		t.Setenv("MICROSERVICE_SERVICE_NAME", "from-env")
+		t.Setenv("MICROSERVICE_TIMEOUT", "45s")
+		t.Setenv("MICROSERVICE_RETRY_ON_STATUS", "502,503")
+
+		require.NoError(t, bindEnv(serveCmd))
+		assert.Equal(t, 9090, port)
+		assert.Equal(t, "debug", logLevel)
+		assert.Equal(t, "from-env", serviceName)
+		assert.Equal(t, 45*time.Second, timeout)
+		assert.Equal(t, []int{502, 503}, retryOnStatus)
+		assert.NoError(t, validateFlags(serveCmd, nil))
+	})
+
+	t.Run("flags take precedence over the environment", func(t *testing.T) {
+		t.Cleanup(reset)
+		t.Setenv("MICROSERVICE_PORT", "9090")
+		t.Setenv("MICROSERVICE_SERVICE_NAME", "from-env")
+		require.NoError(t, serveCmd.ParseFlags([]string{"--port=7070"}))
+
+		require.NoError(t, bindEnv(serveCmd))
+		assert.Equal(t, 7070, port)
+		assert.Equal(t, "from-env", serviceName)
+	})
+
+	t.Run("environment values are validated", func(t *testing.T) {
+		t.Cleanup(reset)
+		t.Setenv("MICROSERVICE_LOG_LEVEL", "verbose")
+
+		require.NoError(t, bindEnv(serveCmd))
+		assert.ErrorContains(t, bindEnvAndValidate(serveCmd, nil), "log-level")
+	})
+
+	t.Run("unparseable values name the variable", func(t *testing.T) {
+		t.Cleanup(reset)
+		t.Setenv("MICROSERVICE_PORT", "not-a-port")
+
+		assert.ErrorContains(t, bindEnv(serveCmd), "MICROSERVICE_PORT")
+	})
+}
@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"log/slog"
+)
+
+// logHandshake returns a tls.Config VerifyConnection callback that logs the negotiated parameters
+// of every completed server handshake at debug level. It never rejects a connection.
+func logHandshake(logger *slog.Logger) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		logger.Debug("TLS handshake completed",
+			slog.String("tls_version", tls.VersionName(cs.Version)),
+			slog.String("cipher_suite", tls.CipherSuiteName(cs.CipherSuite)),
+			slog.String("sni", cs.ServerName),
+			slog.String("alpn", cs.NegotiatedProtocol),
+			slog.Bool("resumed", cs.DidResume))
+		return nil
+	}
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogHandshake(t *testing.T) {
+	tlsCertFile, tlsKeyFile = generateTestCertificates(t)
+	t.Cleanup(func() { tlsCertFile, tlsKeyFile = "", "" })
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	cfg, err := newTLSConfig(logger)
+	require.NoError(t, err)
+
+	clientConn, serverConn := net.Pipe()
+	defer func() { _ = clientConn.Close() }()
+	defer func() { _ = serverConn.Close() }()
+
+	server := tls.Server(serverConn, cfg)
+	done := make(chan error, 1)
+	go func() { done <- server.Handshake() }()
+
+	client := tls.Client(clientConn, &tls.Config{
+		ServerName:         "tenant.example.com",
+		InsecureSkipVerify: true, // #nosec G402 -- self-signed test certificate
+		MaxVersion:         tls.VersionTLS12,
+		CipherSuites:       []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+	})
+	require.NoError(t, client.Handshake())
+	require.NoError(t, <-done)
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "TLS handshake completed", entry["msg"])
+	assert.Equal(t, "DEBUG", entry["level"])
+	assert.Equal(t, "TLS 1.2", entry["tls_version"])
+	assert.Equal(t, tls.CipherSuiteName(client.ConnectionState().CipherSuite), entry["cipher_suite"])
+	assert.Contains(t, entry["cipher_suite"], "AES_128_GCM")
+	assert.Equal(t, "tenant.example.com", entry["sni"])
+	assert.Equal(t, false, entry["resumed"])
+}
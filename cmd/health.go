@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/liamawhite/microservice/pkg/proxy"
+)
+
+// processStart is when the process started, used to report uptime
+var processStart = time.Now()
+
+// healthFailing is set by POST /health/fail to make /health report 503 until POST /health/recover
+var healthFailing atomic.Bool
+
+// detailedHealth is the /health response when --detailed-health is enabled
+type detailedHealth struct {
+	Status         string  `json:"status"`
+	Service        string  `json:"service"`
+	Goroutines     int     `json:"goroutines"`
+	HeapAllocBytes uint64  `json:"heap_alloc_bytes"`
+	UptimeSeconds  float64 `json:"uptime_seconds"`
+}
+
+// handleHealth reports 200 "healthy", or 503 "unhealthy" while failure has been requested
+func handleHealth(w http.ResponseWriter, r *http.Request, serviceName string, logger *slog.Logger) {
+	logger.Debug("Health check request",
+		slog.String("remote_addr", r.RemoteAddr),
+		slog.String("user_agent", r.UserAgent()),
+	)
+
+	statusCode, status := http.StatusOK, "healthy"
+	if healthFailing.Load() {
+		statusCode, status = http.StatusServiceUnavailable, "unhealthy"
+	}
+	if detailedHealthEnabled {
+		writeDetailedHealth(w, statusCode, status, serviceName, logger)
+		return
+	}
+	writeStatus(w, statusCode, status, serviceName, logger)
+}
+
+// handleHealthToggle returns a POST-only handler that sets whether /health reports failure
+func handleHealthToggle(failing bool, serviceName string, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeError(w, r, http.StatusMethodNotAllowed, proxy.ErrCodeMethodNotAllowed, "method not allowed", serviceName, logger)
+			return
+		}
+
+		healthFailing.Store(failing)
+		status := "recovered"
+		if failing {
+			status = "failing"
+		}
+		logger.Info("Health state changed", slog.Bool("failing", failing))
+		writeStatus(w, http.StatusOK, status, serviceName, logger)
+	}
+}
+
+// writeDetailedHealth writes the health status along with runtime stats useful for spotting leaks
+func writeDetailedHealth(w http.ResponseWriter, statusCode int, status, serviceName string, logger *slog.Logger) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	health := detailedHealth{
+		Status:         status,
+		Service:        serviceName,
+		Goroutines:     runtime.NumGoroutine(),
+		HeapAllocBytes: mem.HeapAlloc,
+		UptimeSeconds:  time.Since(processStart).Seconds(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(health); err != nil {
+		logger.Error("Failed to write health response", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetailedHealth(t *testing.T) {
+	logger := createTestLogger()
+	mux := newServeMux(okHandler, "health-service", newDrainer("health-service", 0, logger), logger)
+
+	getHealth := func(t *testing.T) map[string]any {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/health", nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		return body
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		detailedHealthEnabled = false
+
+		body := getHealth(t)
+		assert.Equal(t, "healthy", body["status"])
+		assert.Equal(t, "health-service", body["service"], "the service name is passed to newServeMux, not read from --service-name")
+		assert.NotContains(t, body, "goroutines")
+	})
+
+	t.Run("includes runtime stats when enabled", func(t *testing.T) {
+		detailedHealthEnabled = true
+		t.Cleanup(func() { detailedHealthEnabled = false })
+
+		body := getHealth(t)
+		assert.Equal(t, "healthy", body["status"])
+		assert.GreaterOrEqual(t, body["goroutines"], float64(1))
+		assert.Greater(t, body["heap_alloc_bytes"], float64(0))
+		assert.Greater(t, body["uptime_seconds"], float64(0))
+	})
+}
+
+func TestHealthFailToggle(t *testing.T) {
+	logger := createTestLogger()
+	mux := newServeMux(okHandler, "test-service", newDrainer("test-service", 0, logger), logger)
+	t.Cleanup(func() { healthFailing.Store(false) })
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(method, path, nil))
+		return rr
+	}
+	healthStatus := func(t *testing.T) (int, string) {
+		rr := do(http.MethodGet, "/health")
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		return rr.Code, body["status"].(string)
+	}
+
+	code, status := healthStatus(t)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "healthy", status)
+
+	require.Equal(t, http.StatusOK, do(http.MethodPost, "/health/fail").Code)
+	code, status = healthStatus(t)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "unhealthy", status)
+
+	t.Run("detailed health reports failure too", func(t *testing.T) {
+		detailedHealthEnabled = true
+		t.Cleanup(func() { detailedHealthEnabled = false })
+
+		code, status := healthStatus(t)
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, "unhealthy", status)
+	})
+
+	// Proxy traffic and other probes are unaffected
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/proxy/svc:8080").Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/readyz").Code)
+
+	require.Equal(t, http.StatusOK, do(http.MethodPost, "/health/recover").Code)
+	code, status = healthStatus(t)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "healthy", status)
+
+	t.Run("toggles require POST", func(t *testing.T) {
+		assert.Equal(t, http.StatusMethodNotAllowed, do(http.MethodGet, "/health/fail").Code)
+		assert.Equal(t, http.StatusMethodNotAllowed, do(http.MethodGet, "/health/recover").Code)
+		code, _ := healthStatus(t)
+		assert.Equal(t, http.StatusOK, code)
+	})
+}
+
+func TestDisableHealthRoute(t *testing.T) {
+	logger := createTestLogger()
+	disableHealthRoute = true
+	t.Cleanup(func() { disableHealthRoute = false })
+
+	var proxied []string
+	proxyHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusTeapot)
+	})
+	mux := newServeMux(proxyHandler, "test-service", newDrainer("test-service", 0, logger), logger)
+
+	do := func(method, path string) int {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(method, path, nil))
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusTeapot, do(http.MethodGet, "/health"))
+	assert.Equal(t, http.StatusTeapot, do(http.MethodPost, "/health/fail"))
+	assert.Equal(t, []string{"GET /health", "POST /health/fail"}, proxied)
+	assert.False(t, healthFailing.Load(), "the failure toggle is not served")
+
+	// Probes that don't share the /health path keep working
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/livez"))
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/readyz"))
+}
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// shutdownTimeout bounds how long in-flight requests get to complete once shutdown starts
+const shutdownTimeout = 10 * time.Second
+
+// listenSpec describes a single address the server listens on
+type listenSpec struct {
+	addr string
+	tls  bool
+}
+
+// parseListenSpec parses a --listen value of the form "addr" or "addr,tls"
+func parseListenSpec(spec string) (listenSpec, error) {
+	addr, opt, hasOpt := strings.Cut(spec, ",")
+	addr = strings.TrimSpace(addr)
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return listenSpec{}, fmt.Errorf("invalid listen address %q: %w", addr, err)
+	}
+
+	ls := listenSpec{addr: addr}
+	if hasOpt {
+		if strings.TrimSpace(opt) != "tls" {
+			return listenSpec{}, fmt.Errorf("invalid listen option %q in %q: only \"tls\" is supported", opt, spec)
+		}
+		ls.tls = true
+	}
+	return ls, nil
+}
+
+// parseBindAddress parses a --bind-address value, an IP address optionally wrapped in brackets
+// (e.g. 0.0.0.0, [::], or ::1), returning the bare host
+func parseBindAddress(addr string) (string, error) {
+	host := strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+	if net.ParseIP(host) == nil {
+		return "", fmt.Errorf("invalid bind address %q: must be an IP address such as 0.0.0.0 or [::]", addr)
+	}
+	return host, nil
+}
+
+// listenSpecs returns the configured listeners, falling back to --bind-address and --port (with
+// TLS when a certificate is configured) when no --listen flags were given
+func listenSpecs() ([]listenSpec, error) {
+	if len(listenAddrs) == 0 {
+		addr := fmt.Sprintf(":%d", port)
+		if bindAddress != "" {
+			host, err := parseBindAddress(bindAddress)
+			if err != nil {
+				return nil, err
+			}
+			addr = net.JoinHostPort(host, strconv.Itoa(port))
+		}
+		return []listenSpec{{
+			addr: addr,
+			tls:  tlsCertFile != "" && tlsKeyFile != "",
+		}}, nil
+	}
+
+	specs := make([]listenSpec, 0, len(listenAddrs))
+	for _, spec := range listenAddrs {
+		ls, err := parseListenSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, ls)
+	}
+	return specs, nil
+}
+
+// boundListener pairs a listen spec with its open socket
+type boundListener struct {
+	spec     listenSpec
+	listener net.Listener
+}
+
+// bindListeners opens a socket for every spec so address conflicts fail before any server starts
+func bindListeners(specs []listenSpec) ([]boundListener, error) {
+	bound := make([]boundListener, 0, len(specs))
+	for _, spec := range specs {
+		ln, err := net.Listen("tcp", spec.addr)
+		if err != nil {
+			for _, b := range bound {
+				_ = b.listener.Close()
+			}
+			return nil, fmt.Errorf("listening on %s: %w", spec.addr, err)
+		}
+		bound = append(bound, boundListener{spec: spec, listener: ln})
+	}
+	return bound, nil
+}
+
+// serveListeners runs one http.Server per listener until ctx is cancelled or any server fails,
+// then gracefully shuts all of them down and returns the first error encountered
+func serveListeners(ctx context.Context, listeners []boundListener, handler http.Handler, logger *slog.Logger) error {
+	// Load certificates once and share them across every TLS listener
+	var tlsConfig *tls.Config
+	for _, bl := range listeners {
+		if bl.spec.tls {
+			cfg, err := newTLSConfig(logger)
+			if err != nil {
+				for _, bl := range listeners {
+					_ = bl.listener.Close()
+				}
+				return err
+			}
+			tlsConfig = cfg
+			break
+		}
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+
+	servers := make([]*http.Server, len(listeners))
+	for i, bl := range listeners {
+		server := newHTTPServer(bl.listener.Addr().String(), handler)
+		servers[i] = server
+
+		protocol := "http"
+		if bl.spec.tls {
+			protocol = "https"
+			server.TLSConfig = tlsConfig
+		}
+
+		g.Go(func() error {
+			logger.Info("Server listening",
+				slog.String("addr", server.Addr),
+				slog.String("protocol", protocol))
+
+			var err error
+			if bl.spec.tls {
+				err = server.ServeTLS(bl.listener, "", "")
+			} else {
+				err = server.Serve(bl.listener)
+			}
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Error("Server error", slog.String("addr", server.Addr), slog.String("protocol", protocol), slog.String("error", err.Error()))
+				return fmt.Errorf("%s server on %s: %w", protocol, server.Addr, err)
+			}
+			return nil
+		})
+	}
+
+	// Shut every server down once the context ends, whether from a signal or a sibling failing
+	g.Go(func() error {
+		<-ctx.Done()
+		logger.Info("Shutting down servers")
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		var errs []error
+		for _, server := range servers {
+			if err := server.Shutdown(shutdownCtx); err != nil {
+				errs = append(errs, fmt.Errorf("shutting down %s: %w", server.Addr, err))
+			}
+		}
+		return errors.Join(errs...)
+	})
+
+	return g.Wait()
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseListenSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    listenSpec
+		wantErr bool
+	}{
+		{name: "port only", spec: ":8080", want: listenSpec{addr: ":8080"}},
+		{name: "host and port", spec: "127.0.0.1:8080", want: listenSpec{addr: "127.0.0.1:8080"}},
+		{name: "tls", spec: ":8443,tls", want: listenSpec{addr: ":8443", tls: true}},
+		{name: "missing port", spec: "localhost", wantErr: true},
+		{name: "unknown option", spec: ":8443,mtls", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseListenSpec(tt.spec)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestServeListeners(t *testing.T) {
+	certPath, keyPath := generateTestCertificates(t)
+	tlsCertFile, tlsKeyFile = certPath, keyPath
+	t.Cleanup(func() { tlsCertFile, tlsKeyFile = "", "" })
+
+	logger := createTestLogger()
+	mux := newServeMux(okHandler, "test-service", newDrainer("test-service", 0, logger), logger)
+
+	listeners, err := bindListeners([]listenSpec{
+		{addr: "127.0.0.1:0"},
+		{addr: "127.0.0.1:0", tls: true},
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- serveListeners(ctx, listeners, mux, logger) }()
+
+	client := &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, // #nosec G402 -- self-signed test certificate
+		},
+	}
+
+	urls := []string{
+		fmt.Sprintf("http://%s/health", listeners[0].listener.Addr()),
+		fmt.Sprintf("https://%s/health", listeners[1].listener.Addr()),
+	}
+	for _, url := range urls {
+		resp, err := client.Get(url)
+		require.NoError(t, err, url)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode, url)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("servers did not shut down")
+	}
+}
+
+func TestBindListenersConflict(t *testing.T) {
+	listeners, err := bindListeners([]listenSpec{{addr: "127.0.0.1:0"}})
+	require.NoError(t, err)
+	defer func() { _ = listeners[0].listener.Close() }()
+
+	_, err = bindListeners([]listenSpec{{addr: listeners[0].listener.Addr().String()}})
+	assert.Error(t, err)
+}
+
+func TestBindAddress(t *testing.T) {
+	t.Cleanup(func() { bindAddress, port = "", 8080 })
+
+	tests := []struct {
+		name        string
+		bindAddress string
+		wantAddr    string
+	}{
+		{name: "ipv4 loopback", bindAddress: "127.0.0.1", wantAddr: "127.0.0.1:8080"},
+		{name: "bracketed ipv6 loopback", bindAddress: "[::1]", wantAddr: "[::1]:8080"},
+		{name: "bare ipv6 any", bindAddress: "::", wantAddr: "[::]:8080"},
+		{name: "unset", wantAddr: ":8080"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bindAddress, port = tt.bindAddress, 8080
+			specs, err := listenSpecs()
+			require.NoError(t, err)
+			require.Len(t, specs, 1)
+			assert.Equal(t, tt.wantAddr, specs[0].addr)
+		})
+	}
+
+	t.Run("serves health on each address", func(t *testing.T) {
+		logger := createTestLogger()
+		mux := newServeMux(okHandler, "test-service", newDrainer("test-service", 0, logger), logger)
+
+		for _, addr := range []string{"127.0.0.1", "[::1]"} {
+			t.Run(addr, func(t *testing.T) {
+				// Port 0 picks a free port; the spec is otherwise built exactly as runServer does
+				bindAddress, port = addr, 0
+				specs, err := listenSpecs()
+				require.NoError(t, err)
+
+				listeners, err := bindListeners(specs)
+				if err != nil && addr == "[::1]" {
+					t.Skipf("IPv6 loopback unavailable: %v", err)
+				}
+				require.NoError(t, err)
+
+				ctx, cancel := context.WithCancel(context.Background())
+				done := make(chan error, 1)
+				go func() { done <- serveListeners(ctx, listeners, mux, logger) }()
+
+				resp, err := http.Get(fmt.Sprintf("http://%s/health", listeners[0].listener.Addr()))
+				require.NoError(t, err)
+				_ = resp.Body.Close()
+				assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+				cancel()
+				require.NoError(t, <-done)
+			})
+		}
+	})
+
+	t.Run("rejects hostnames", func(t *testing.T) {
+		bindAddress = "localhost"
+		_, err := listenSpecs()
+		assert.Error(t, err)
+	})
+}
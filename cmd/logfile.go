@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"io"
+	"os"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// nopCloser wraps a writer that must not be closed, such as stdout
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+// newLogWriter returns where logs are written: stdout by default, or a size-rotated --log-file,
+// optionally teed to stdout. The caller closes it on shutdown to release the log file.
+func newLogWriter() io.WriteCloser {
+	if logFile == "" {
+		return nopCloser{os.Stdout}
+	}
+
+	rotating := &lumberjack.Logger{
+		Filename:   logFile,
+		MaxSize:    logMaxSize,
+		MaxBackups: logMaxBackups,
+		MaxAge:     logMaxAge,
+	}
+	if logTeeStdout {
+		return struct {
+			io.Writer
+			io.Closer
+		}{io.MultiWriter(rotating, os.Stdout), rotating}
+	}
+	return rotating
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogFileRotation(t *testing.T) {
+	dir := t.TempDir()
+	logFile = filepath.Join(dir, "service.log")
+	logMaxSize = 1
+	logMaxBackups = 0
+	logMaxAge = 0
+	logTeeStdout = false
+	t.Cleanup(func() {
+		logFile = ""
+		logMaxSize = 100
+	})
+
+	out := newLogWriter()
+	logger := setupLogger(out, "info", "json", "test-service")
+
+	// Write well over the 1MB limit so the file rotates at least once
+	padding := strings.Repeat("x", 1024)
+	for i := 0; i < 1500; i++ {
+		logger.Info("filler", "padding", padding)
+	}
+	require.NoError(t, out.Close())
+
+	backups, err := filepath.Glob(filepath.Join(dir, "service-*.log"))
+	require.NoError(t, err)
+	assert.NotEmpty(t, backups, "expected a rotated backup file")
+
+	info, err := os.Stat(logFile)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, info.Size(), int64(1024*1024))
+}
+
+func TestLogWriterDefaultsToStdout(t *testing.T) {
+	logFile = ""
+	out := newLogWriter()
+	assert.Equal(t, nopCloser{os.Stdout}, out)
+	assert.NoError(t, out.Close())
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/liamawhite/microservice/pkg/proxy"
+)
+
+// logLevels maps --log-level names to slog levels
+var logLevels = map[string]slog.Level{
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+}
+
+// logLevelVar is the level of the logger built by setupLogger, adjustable at runtime through
+// POST /admin/loglevel
+var logLevelVar slog.LevelVar
+
+// logLevelRequest is the body of /admin/loglevel requests and responses
+type logLevelRequest struct {
+	Level string `json:"level"`
+}
+
+// handleLogLevel reports the current log level on GET and changes it on POST with a body such as
+// {"level":"debug"}, so verbosity can be raised while debugging without a restart. Errors answer
+// as serviceName.
+func handleLogLevel(serviceName string, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			var req logLevelRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeError(w, r, http.StatusBadRequest, proxy.ErrCodeBadRequest, fmt.Sprintf("invalid request body: %v", err), serviceName, logger)
+				return
+			}
+			level, ok := logLevels[strings.ToLower(req.Level)]
+			if !ok {
+				writeError(w, r, http.StatusBadRequest, proxy.ErrCodeBadRequest, fmt.Sprintf("level must be one of [debug, info, warn, error], got %q", req.Level), serviceName, logger)
+				return
+			}
+
+			previous := logLevelVar.Level()
+			logLevelVar.Set(level)
+			logger.Warn("Log level changed", slog.String("from", levelName(previous)), slog.String("to", levelName(level)))
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			writeError(w, r, http.StatusMethodNotAllowed, proxy.ErrCodeMethodNotAllowed, "method not allowed", serviceName, logger)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(logLevelRequest{Level: levelName(logLevelVar.Level())}); err != nil {
+			logger.Error("Failed to write log level response", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// levelName returns the --log-level name of a level
+func levelName(level slog.Level) string {
+	return strings.ToLower(level.String())
+}
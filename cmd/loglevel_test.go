@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminLogLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := setupLogger(&buf, "info", "json", "test-service")
+	t.Cleanup(func() { logLevelVar.Set(logLevels["info"]) })
+	mux := newServeMux(okHandler, "mux-service", newDrainer("mux-service", 0, logger), logger)
+
+	do := func(method, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(method, "/admin/loglevel", strings.NewReader(body)))
+		return rr
+	}
+
+	logger.Debug("hidden before")
+	assert.NotContains(t, buf.String(), "hidden before")
+
+	rr := do(http.MethodGet, "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"level":"info"}`, rr.Body.String())
+
+	rr = do(http.MethodPost, `{"level":"debug"}`)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"level":"debug"}`, rr.Body.String())
+
+	logger.Debug("visible after")
+	assert.Contains(t, buf.String(), "visible after")
+
+	rr = do(http.MethodPost, `{"level":"error"}`)
+	require.Equal(t, http.StatusOK, rr.Code)
+	buf.Reset()
+	logger.Warn("suppressed warning")
+	logger.Error("reported error")
+	assert.NotContains(t, buf.String(), "suppressed warning")
+	assert.Contains(t, buf.String(), "reported error")
+
+	t.Run("rejects unknown levels", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, `{"level":"verbose"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, `not json`).Code)
+		assert.JSONEq(t, `{"level":"error"}`, do(http.MethodGet, "").Body.String())
+	})
+
+	t.Run("rejects other methods", func(t *testing.T) {
+		rr := do(http.MethodDelete, "")
+		assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+		assert.Contains(t, rr.Body.String(), `"code":"PROXY_METHOD_NOT_ALLOWED"`)
+	})
+
+	t.Run("errors answer as the mux's service", func(t *testing.T) {
+		rr := do(http.MethodPost, `{"level":"verbose"}`)
+		assert.Contains(t, rr.Body.String(), `"service":"mux-service"`)
+	})
+}
@@ -0,0 +1,17 @@
+package cmd
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// handleNoop answers /noop with an empty 200 for throughput benchmarking. It skips path parsing
+// and JSON encoding entirely; with --quiet-noop it skips the debug log line too.
+func handleNoop(logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !quietNoop {
+			logger.Debug("Noop request", slog.String("remote_addr", r.RemoteAddr))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/liamawhite/microservice/pkg/proxy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNoop(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	mux := newServeMux(okHandler, "test-service", newDrainer("test-service", 0, logger), logger)
+
+	noop := func() *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/noop", nil))
+		return rr
+	}
+
+	rr := noop()
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Body.String())
+	assert.Contains(t, buf.String(), "Noop request")
+
+	t.Run("quiet skips the debug log", func(t *testing.T) {
+		quietNoop = true
+		t.Cleanup(func() { quietNoop = false })
+		buf.Reset()
+
+		rr := noop()
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Empty(t, buf.String())
+	})
+}
+
+// BenchmarkNoop compares /noop with the cheapest proxy path, a single-hop "/" request
+func BenchmarkNoop(b *testing.B) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	handler, err := proxy.NewHandler(5*time.Second, "bench-service", logger)
+	require.NoError(b, err)
+	mux := newServeMux(handler, "bench-service", newDrainer("bench-service", 0, logger), logger)
+
+	quietNoop = true
+	b.Cleanup(func() { quietNoop = false })
+
+	for name, path := range map[string]string{"noop": "/noop", "proxy": "/"} {
+		b.Run(name, func(b *testing.B) {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			b.ReportAllocs()
+			for b.Loop() {
+				rr := httptest.NewRecorder()
+				mux.ServeHTTP(rr, req)
+			}
+		})
+	}
+}
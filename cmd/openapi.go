@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/liamawhite/microservice/pkg/proxy"
+)
+
+// openAPIDocument is the subset of an OpenAPI 3 document served at /openapi.json
+type openAPIDocument struct {
+	OpenAPI string                                 `json:"openapi"`
+	Info    openAPIInfo                            `json:"info"`
+	Servers []openAPIServer                        `json:"servers,omitempty"`
+	Paths   map[string]map[string]openAPIOperation `json:"paths"`
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+type openAPIServer struct {
+	URL string `json:"url"`
+}
+
+type openAPIOperation struct {
+	Summary     string                     `json:"summary"`
+	Tags        []string                   `json:"tags"`
+	Parameters  []openAPIParameter         `json:"parameters,omitempty"`
+	Responses   map[string]openAPIResponse `json:"responses"`
+	Description string                     `json:"description,omitempty"`
+}
+
+type openAPIParameter struct {
+	Name     string            `json:"name"`
+	In       string            `json:"in"`
+	Required bool              `json:"required"`
+	Schema   map[string]string `json:"schema"`
+}
+
+type openAPIResponse struct {
+	Description string `json:"description"`
+}
+
+// apiEndpoint describes a built-in endpoint for /openapi.json
+type apiEndpoint struct {
+	path    string
+	methods []string
+	summary string
+	enabled func() bool // nil when always served
+}
+
+// apiEndpoints lists the built-in endpoints served alongside the proxy routes
+var apiEndpoints = []apiEndpoint{
+	{path: "/health", methods: []string{http.MethodGet}, summary: "Report service health", enabled: healthRouteEnabled},
+	{path: "/health/fail", methods: []string{http.MethodPost}, summary: "Make /health report 503 until recovered", enabled: healthRouteEnabled},
+	{path: "/health/recover", methods: []string{http.MethodPost}, summary: "Make /health report healthy again", enabled: healthRouteEnabled},
+	{path: "/livez", methods: []string{http.MethodGet}, summary: "Report that the process is running"},
+	{path: "/readyz", methods: []string{http.MethodGet}, summary: "Report whether the service is ready for traffic"},
+	{path: "/drain", methods: []string{http.MethodPost}, summary: "Start draining so /readyz reports not ready"},
+	{path: "/admin/loglevel", methods: []string{http.MethodGet, http.MethodPost}, summary: "Report or change the log level"},
+	{path: "/noop", methods: []string{http.MethodGet}, summary: "Answer an empty 200, for benchmarking"},
+	{path: "/clock", methods: []string{http.MethodGet}, summary: "Report the server time, offset by --clock-skew"},
+	{path: "/inspect", methods: []string{http.MethodGet}, summary: "Show how a chain path given as ?path= would be parsed"},
+	{path: "/replay", methods: []string{http.MethodPost}, summary: "Run a request recorded by --record-file again"},
+	{path: "/compose", methods: []string{http.MethodPost}, summary: "Run the chain described by a JSON topology spec"},
+	{path: "/admin/warmup", methods: []string{http.MethodPost}, summary: "Open a pooled connection to each listed upstream and report the outcome"},
+	{path: "/openapi.json", methods: []string{http.MethodGet}, summary: "This document"},
+	{path: staticPrefix + "{file}", methods: []string{http.MethodGet}, summary: "Serve a file from --static-dir",
+		enabled: func() bool { return staticDir != "" }},
+}
+
+// healthRouteEnabled reports whether the built-in /health endpoints are served
+func healthRouteEnabled() bool {
+	return !disableHealthRoute
+}
+
+// pathParam matches {name} parameters in path templates
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+// newOpenAPIDocument describes the endpoints and path directives of this build as configured
+func newOpenAPIDocument() openAPIDocument {
+	doc := openAPIDocument{
+		OpenAPI: "3.0.3",
+		Info: openAPIInfo{
+			Title:       "microservice",
+			Description: "Synthetic service for testing proxy chains. Directives can be combined in one path, e.g. /delay/100/proxy/service-b:8080/fault/503, and each hop applies the directives up to the next /proxy/.",
+			Version:     Version,
+		},
+		Paths: map[string]map[string]openAPIOperation{},
+	}
+	if prefix, err := normalizePathPrefix(pathPrefix); err == nil && prefix != "" {
+		doc.Servers = []openAPIServer{{URL: prefix}}
+	}
+
+	for _, e := range apiEndpoints {
+		if e.enabled != nil && !e.enabled() {
+			continue
+		}
+		item := map[string]openAPIOperation{}
+		for _, method := range e.methods {
+			item[strings.ToLower(method)] = newOpenAPIOperation(e.path, e.summary, "endpoints", "")
+		}
+		doc.Paths[e.path] = item
+	}
+
+	for _, d := range proxy.Directives() {
+		description := "Example: " + d.Example
+		if d.Terminal {
+			description += ". Must be the last directive in the path."
+		}
+		doc.Paths[d.Format] = map[string]openAPIOperation{
+			"get": newOpenAPIOperation(d.Format, d.Summary, "directives", description),
+		}
+	}
+	return doc
+}
+
+// newOpenAPIOperation describes an operation on path, declaring its {parameters}
+func newOpenAPIOperation(path, summary, tag, description string) openAPIOperation {
+	op := openAPIOperation{
+		Summary:     summary,
+		Tags:        []string{tag},
+		Description: description,
+		Responses:   map[string]openAPIResponse{"default": {Description: "Response from this service or a later hop"}},
+	}
+	for _, match := range pathParam.FindAllStringSubmatch(path, -1) {
+		op.Parameters = append(op.Parameters, openAPIParameter{
+			Name:     match[1],
+			In:       "path",
+			Required: true,
+			Schema:   map[string]string{"type": "string"},
+		})
+	}
+	return op
+}
+
+// handleOpenAPI serves a minimal OpenAPI document listing the built-in endpoints and path
+// directives, so tooling can discover what this build supports
+func handleOpenAPI(logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(newOpenAPIDocument()); err != nil {
+			logger.Error("Failed to write OpenAPI document", slog.String("error", err.Error()))
+		}
+	}
+}
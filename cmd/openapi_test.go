@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPI(t *testing.T) {
+	logger := createTestLogger()
+	mux := newServeMux(okHandler, "test-service", newDrainer("test-service", 0, logger), logger)
+
+	fetch := func(t *testing.T) map[string]any {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+		var doc map[string]any
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &doc))
+		return doc
+	}
+
+	doc := fetch(t)
+	assert.Equal(t, "3.0.3", doc["openapi"])
+	paths, ok := doc["paths"].(map[string]any)
+	require.True(t, ok)
+	for _, path := range []string{"/health", "/readyz", "/inspect", "/compose", "/proxy/{target}", "/fault/{code}", "/delay/{ms}", "/chunked"} {
+		assert.Contains(t, paths, path)
+	}
+	assert.NotContains(t, paths, "/static/{file}")
+	assert.NotContains(t, doc, "servers")
+
+	t.Run("path parameters are declared", func(t *testing.T) {
+		get := paths["/trailer/{name}/{value}"].(map[string]any)["get"].(map[string]any)
+		params := get["parameters"].([]any)
+		require.Len(t, params, 2)
+		assert.Equal(t, "name", params[0].(map[string]any)["name"])
+		assert.Equal(t, "value", params[1].(map[string]any)["name"])
+	})
+
+	t.Run("reflects configuration", func(t *testing.T) {
+		staticDir, pathPrefix = t.TempDir(), "/svc"
+		t.Cleanup(func() { staticDir, pathPrefix = "", "" })
+
+		doc := fetch(t)
+		assert.Contains(t, doc["paths"], "/static/{file}")
+		assert.Equal(t, []any{map[string]any{"url": "/svc"}}, doc["servers"])
+	})
+
+	t.Run("omits disabled health route", func(t *testing.T) {
+		disableHealthRoute = true
+		t.Cleanup(func() { disableHealthRoute = false })
+
+		doc := fetch(t)
+		assert.NotContains(t, doc["paths"], "/health")
+		assert.Contains(t, doc["paths"], "/livez")
+	})
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// normalizePathPrefix checks a --path-prefix value and returns it without a trailing slash.
+// "/" and "" both mean no prefix.
+func normalizePathPrefix(prefix string) (string, error) {
+	if prefix == "" {
+		return "", nil
+	}
+	if !strings.HasPrefix(prefix, "/") || strings.ContainsAny(prefix, "?#") {
+		return "", fmt.Errorf("path-prefix must be a path starting with /, got %q", prefix)
+	}
+	return strings.TrimRight(prefix, "/"), nil
+}
+
+// pathPrefixMiddleware strips prefix from request paths so every route, including health and
+// admin endpoints, is reachable under it. Paths outside the prefix are served unchanged, so hops
+// calling each other directly keep working.
+func pathPrefixMiddleware(prefix string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, ok := stripPathPrefix(r.URL.Path, prefix)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Shallow copy the request so the caller's URL is left untouched, as http.StripPrefix does
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = path
+		if r.URL.RawPath != "" {
+			r2.URL.RawPath, _ = stripPathPrefix(r.URL.RawPath, prefix)
+		}
+		next.ServeHTTP(w, r2)
+	})
+}
+
+// stripPathPrefix removes prefix from path when path is the prefix or lies beneath it
+func stripPathPrefix(path, prefix string) (string, bool) {
+	if path == prefix {
+		return "/", true
+	}
+	if rest, ok := strings.CutPrefix(path, prefix); ok && strings.HasPrefix(rest, "/") {
+		return rest, true
+	}
+	return path, false
+}
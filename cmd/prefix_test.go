@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/liamawhite/microservice/pkg/proxy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathPrefix(t *testing.T) {
+	logger := createTestLogger()
+	handler, err := proxy.NewHandler(30*time.Second, "test-service", logger)
+	require.NoError(t, err)
+	mux := newServeMux(handler, "test-service", newDrainer("test-service", 0, logger), logger)
+
+	get := func(t *testing.T, root http.Handler, path string) *httptest.ResponseRecorder {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		root.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr
+	}
+
+	t.Run("without a prefix", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, get(t, mux, "/health").Code)
+		assert.Equal(t, http.StatusServiceUnavailable, get(t, mux, "/fault/503").Code)
+		assert.Equal(t, http.StatusNotFound, get(t, mux, "/svc/fault/503").Code, "unknown directive")
+	})
+
+	t.Run("with a prefix", func(t *testing.T) {
+		root := pathPrefixMiddleware("/svc", mux)
+
+		assert.Equal(t, http.StatusServiceUnavailable, get(t, root, "/svc/fault/503").Code)
+		assert.Equal(t, http.StatusOK, get(t, root, "/svc").Code)
+		assert.Equal(t, http.StatusOK, get(t, root, "/svc/").Code)
+		assert.Equal(t, http.StatusOK, get(t, root, "/svc/readyz").Code)
+
+		rr := get(t, root, "/svc/health")
+		require.Equal(t, http.StatusOK, rr.Code)
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, "healthy", body["status"])
+
+		rr = httptest.NewRecorder()
+		root.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/svc/admin/loglevel", strings.NewReader(`{"level":"info"}`)))
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("paths outside the prefix are served unchanged", func(t *testing.T) {
+		root := pathPrefixMiddleware("/svc", mux)
+
+		assert.Equal(t, http.StatusOK, get(t, root, "/health").Code)
+		assert.Equal(t, http.StatusServiceUnavailable, get(t, root, "/fault/503").Code)
+		assert.Equal(t, http.StatusNotFound, get(t, root, "/svcx/fault/503").Code, "prefix must end at a segment boundary")
+	})
+}
+
+func TestNormalizePathPrefix(t *testing.T) {
+	tests := []struct {
+		prefix  string
+		want    string
+		wantErr bool
+	}{
+		{prefix: "", want: ""},
+		{prefix: "/", want: ""},
+		{prefix: "/svc", want: "/svc"},
+		{prefix: "/svc/", want: "/svc"},
+		{prefix: "/team/svc/", want: "/team/svc"},
+		{prefix: "svc", wantErr: true},
+		{prefix: "/svc?x=1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			got, err := normalizePathPrefix(tt.prefix)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
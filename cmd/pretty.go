@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ANSI escape codes used by the pretty handler
+const (
+	ansiReset  = "\033[0m"
+	ansiDim    = "\033[2m"
+	ansiRed    = "\033[31m"
+	ansiGreen  = "\033[32m"
+	ansiYellow = "\033[33m"
+	ansiBlue   = "\033[34m"
+)
+
+// prettyMessageWidth is the column messages are padded to so fields line up
+const prettyMessageWidth = 32
+
+// prettyHandler is a human-friendly slog.Handler for local development. Each record is written
+// as a single line of time, level, message, and key=value fields, colorized when enabled.
+type prettyHandler struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	opts   slog.HandlerOptions
+	color  bool
+	attrs  string // Preformatted attributes from WithAttrs
+	prefix string // Key prefix from WithGroup
+}
+
+// newPrettyHandler returns a pretty handler writing to out, using ANSI colors when color is set
+func newPrettyHandler(out io.Writer, opts *slog.HandlerOptions, color bool) *prettyHandler {
+	h := &prettyHandler{mu: &sync.Mutex{}, out: out, color: color}
+	if opts != nil {
+		h.opts = *opts
+	}
+	return h
+}
+
+// isTerminal reports whether w writes to a terminal
+func isTerminal(w io.Writer) bool {
+	if nc, ok := w.(nopCloser); ok {
+		w = nc.Writer
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+func (h *prettyHandler) Enabled(_ context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+func (h *prettyHandler) Handle(_ context.Context, r slog.Record) error {
+	var buf bytes.Buffer
+
+	if !r.Time.IsZero() {
+		buf.WriteString(h.paint(ansiDim, r.Time.Format(time.TimeOnly+".000")))
+		buf.WriteByte(' ')
+	}
+	buf.WriteString(h.paint(levelColor(r.Level), fmt.Sprintf("%-5s", r.Level.String())))
+	buf.WriteByte(' ')
+	buf.WriteString(fmt.Sprintf("%-*s", prettyMessageWidth, r.Message))
+
+	buf.WriteString(h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		h.appendAttr(&buf, h.prefix, a)
+		return true
+	})
+	buf.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.out.Write(buf.Bytes())
+	return err
+}
+
+func (h *prettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var buf bytes.Buffer
+	for _, a := range attrs {
+		h.appendAttr(&buf, h.prefix, a)
+	}
+	clone := *h
+	clone.attrs = h.attrs + buf.String()
+	return &clone
+}
+
+func (h *prettyHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.prefix = h.prefix + name + "."
+	return &clone
+}
+
+// appendAttr writes a as " key=value", flattening groups into dotted keys
+func (h *prettyHandler) appendAttr(buf *bytes.Buffer, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		groupPrefix := prefix
+		if a.Key != "" {
+			groupPrefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			h.appendAttr(buf, groupPrefix, ga)
+		}
+		return
+	}
+
+	value := a.Value.String()
+	if strings.ContainsAny(value, " \t\n\"=") {
+		value = fmt.Sprintf("%q", value)
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(h.paint(ansiDim, prefix+a.Key+"="))
+	buf.WriteString(value)
+}
+
+// paint wraps s in the given color when colors are enabled
+func (h *prettyHandler) paint(color, s string) string {
+	if !h.color {
+		return s
+	}
+	return color + s + ansiReset
+}
+
+// levelColor returns the color for a log level
+func levelColor(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return ansiRed
+	case level >= slog.LevelWarn:
+		return ansiYellow
+	case level >= slog.LevelInfo:
+		return ansiGreen
+	default:
+		return ansiBlue
+	}
+}
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrettyHandler(t *testing.T) {
+	t.Run("colorized output", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(newPrettyHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}, true))
+
+		logger.Warn("Slow upstream", slog.Int("status_code", 503))
+
+		line := buf.String()
+		assert.Contains(t, line, ansiYellow+"WARN "+ansiReset)
+		assert.Contains(t, line, "Slow upstream")
+		assert.Contains(t, line, ansiDim+"status_code="+ansiReset+"503")
+		assert.True(t, strings.HasSuffix(line, "\n"))
+	})
+
+	t.Run("fields are aligned and groups flattened", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(newPrettyHandler(&buf, nil, false)).
+			With(slog.String("service", "svc")).
+			WithGroup("req")
+
+		logger.Info("Incoming request", slog.String("path", "/proxy/b"), slog.Group("headers", slog.String("X-Test", "a b")), slog.Group("empty"))
+		logger.Info("Done")
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 2)
+		assert.Contains(t, lines[0], `service=svc req.path=/proxy/b req.headers.X-Test="a b"`)
+		assert.NotContains(t, lines[0], "empty")
+
+		// Fields start in the same column regardless of message length
+		assert.Equal(t, strings.Index(lines[0], " service="), strings.Index(lines[1], " service="))
+	})
+
+	t.Run("respects level", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(newPrettyHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}, false))
+
+		logger.Info("hidden")
+		assert.Empty(t, buf.String())
+	})
+
+	t.Run("non-TTY output disables colors", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := setupLogger(&buf, "info", "pretty", "test-service")
+
+		logger.Error("Something failed", slog.String("error", "boom"))
+
+		line := buf.String()
+		assert.Contains(t, line, "ERROR Something failed")
+		assert.Contains(t, line, "error=boom")
+		assert.NotContains(t, line, "\033[")
+	})
+}
+
+func TestIsTerminal(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer func() { _ = r.Close(); _ = w.Close() }()
+
+	assert.False(t, isTerminal(w))
+	assert.False(t, isTerminal(nopCloser{w}))
+	assert.False(t, isTerminal(&bytes.Buffer{}))
+}
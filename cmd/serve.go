@@ -4,10 +4,14 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
+	"io"
 	"log/slog"
+	"mime"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 	"time"
 
 	"github.com/liamawhite/microservice/pkg/proxy"
@@ -20,14 +24,83 @@ var (
 	timeout                  time.Duration
 	serviceName              string
 	logLevel                 string
+	logSampleRate            float64
 	logFormat                string
 	logHeaders               bool
+	logFile                  string
+	logMaxSize               int
+	logMaxBackups            int
+	logMaxAge                int
+	logTeeStdout             bool
 	tlsCertFile              string
 	tlsKeyFile               string
+	tlsCertsFor              []string
 	upstreamTLSInsecure      bool
+	followRedirects          bool
+	hostAliases              []string
+	dnsCacheTTL              time.Duration
+	enableWebSocket          bool
+	enableCoalescing         bool
+	expectContinue           bool
+	forwardFullPath          bool
+	forwardQuery             bool
+	hmacSecret               string
 	upstreamCACerts          []string
 	propagateRequestHeaders  bool
 	propagateResponseHeaders bool
+	drainGracePeriod         time.Duration
+	acceptDelay              time.Duration
+	maxTotalDuration         time.Duration
+	strictAccept             bool
+	maxHeaderBytes           int
+	maxHeaderCount           int
+	disableKeepalive         bool
+	listenAddrs              []string
+	bindAddress              string
+	responseTemplateFile     string
+	transformExpr            string
+	serverHeader             string
+	latencyHeader            bool
+	enableETag               bool
+	lastModified             string
+	emitChecksum             string
+	decompressRequests       bool
+	maxFanout                int
+	maxRepeat                int
+	passiveHealthThreshold   int
+	maxConcurrent            int
+	maxHeapMB                int
+	maxQueueWait             time.Duration
+	upstreamUserAgent        string
+	tracePropagation         string
+	trustProxyHeaders        bool
+	rateLimit                float64
+	rateBurst                int
+	idempotencyTTL           time.Duration
+	staticDir                string
+	propagateDeadline        bool
+	detailedHealthEnabled    bool
+	disableHealthRoute       bool
+	quietNoop                bool
+	clockSkew                time.Duration
+	recordFile               string
+	recordHeaders            bool
+	enableGRPCWeb            bool
+	keepalivePing            time.Duration
+	keepaliveTargets         []string
+	remapStatus              []string
+	maxRetries               int
+	retryOnStatus            []int
+	maxRequestTimeout        time.Duration
+	bodyReadTimeout          time.Duration
+	maxPayloadBytes          int64
+	latencyPerKB             time.Duration
+	responseContentType      string
+	responseStyle            string
+	responseMessage          string
+	pathPrefix               string
+	rejectSuspiciousPaths    bool
+	allowedMethods           []string
 )
 
 // serveCmd represents the serve command
@@ -49,24 +122,93 @@ Examples:
 
   # Configure service name and timeout
   microservice serve -s my-service -t 60s`,
-	PreRunE: validateFlags,
+	PreRunE: bindEnvAndValidate,
 	RunE:    runServer,
 }
 
 func init() {
 	// Define flags with both long and short forms
 	serveCmd.Flags().IntVarP(&port, "port", "p", 8080, "HTTP server port")
+	serveCmd.Flags().StringVar(&bindAddress, "bind-address", "", "IP address to listen on with --port, e.g. 0.0.0.0, [::] for dual-stack, or [::1] (default all interfaces)")
+	serveCmd.Flags().StringArrayVar(&listenAddrs, "listen", nil, "Address to listen on, optionally suffixed with \",tls\" (repeatable, e.g. :8080 and :8443,tls); overrides --port")
 	serveCmd.Flags().DurationVarP(&timeout, "timeout", "t", 30*time.Second, "Request timeout")
+	serveCmd.Flags().DurationVar(&maxRequestTimeout, "max-request-timeout", 0, "Ceiling for per-request ?timeout= overrides (0 caps them at --timeout)")
+	serveCmd.Flags().DurationVar(&bodyReadTimeout, "body-read-timeout", 0, "Maximum time to receive the request body, independent of --timeout; slower bodies get 408 (0 to disable)")
 	serveCmd.Flags().StringVarP(&serviceName, "service-name", "s", "proxy", "Service identifier in responses")
 	serveCmd.Flags().StringVarP(&logLevel, "log-level", "l", "info", "Log level (debug, info, warn, error)")
-	serveCmd.Flags().StringVarP(&logFormat, "log-format", "f", "json", "Log output format (json, text)")
+	serveCmd.Flags().Float64Var(&logSampleRate, "log-sample-rate", 1, "Fraction of requests (0-1) whose info and debug logs are written; warnings and errors are always logged")
+	serveCmd.Flags().StringVarP(&logFormat, "log-format", "f", "json", "Log output format (json, text, pretty)")
 	serveCmd.Flags().BoolVar(&logHeaders, "log-headers", false, "Log all request and response headers with sensitive data redaction")
+	serveCmd.Flags().StringVar(&logFile, "log-file", "", "Write logs to this file with size-based rotation instead of stdout")
+	serveCmd.Flags().IntVar(&logMaxSize, "log-max-size", 100, "Maximum size in megabytes of the log file before it is rotated")
+	serveCmd.Flags().IntVar(&logMaxBackups, "log-max-backups", 0, "Maximum number of rotated log files to keep (0 keeps all)")
+	serveCmd.Flags().IntVar(&logMaxAge, "log-max-age", 0, "Maximum number of days to keep rotated log files (0 keeps all)")
+	serveCmd.Flags().BoolVar(&logTeeStdout, "log-stdout", false, "Also write logs to stdout when --log-file is set")
 	serveCmd.Flags().StringVar(&tlsCertFile, "tls-cert", "", "Path to TLS certificate file (enables HTTPS when provided with --tls-key)")
 	serveCmd.Flags().StringVar(&tlsKeyFile, "tls-key", "", "Path to TLS key file (enables HTTPS when provided with --tls-cert)")
+	serveCmd.Flags().StringArrayVar(&tlsCertsFor, "tls-cert-for", nil, "Serve a different certificate for an SNI hostname as host=cert.pem,key.pem (repeatable); other clients get --tls-cert")
 	serveCmd.Flags().BoolVar(&upstreamTLSInsecure, "upstream-tls-insecure", false, "Skip TLS verification for upstream requests (useful for self-signed certs)")
+	serveCmd.Flags().BoolVar(&followRedirects, "follow-redirects", true, "Follow upstream redirects; when false, 3xx responses are returned to the client as-is")
+	serveCmd.Flags().BoolVar(&enableWebSocket, "enable-websocket", false, "Forward WebSocket and other Connection: Upgrade requests as a bidirectional byte pipe to the next hop")
+	serveCmd.Flags().BoolVar(&enableCoalescing, "enable-coalescing", false, "Share one chain execution among identical in-flight GET and HEAD requests")
+	serveCmd.Flags().BoolVar(&expectContinue, "enable-expect-continue", false, "Answer Expect: 100-continue with 100 Continue as soon as a request arrives, before any directives run")
+	serveCmd.Flags().BoolVar(&forwardQuery, "forward-query", false, "Pass the incoming query string on to the next /proxy/ hop instead of dropping it")
+	serveCmd.Flags().BoolVar(&forwardFullPath, "forward-full-path", false, "Forward the original path after /proxy/<host:port> and the query verbatim, instead of rebuilding the path from parsed directives")
+	serveCmd.Flags().StringVar(&hmacSecret, "hmac-secret", "", "Require an X-Signature header holding the hex HMAC-SHA256 of the request body under this secret, answering 401 otherwise")
+	serveCmd.Flags().StringSliceVar(&hostAliases, "host-alias", nil, "Resolve an upstream hostname to a fixed IP as name=ip, e.g. myservice=10.0.0.5 (comma-separated or repeatable)")
+	serveCmd.Flags().DurationVar(&dnsCacheTTL, "dns-cache-ttl", 0, "Cache the IPs upstream hostnames resolve to for this long instead of looking them up for every new connection (0 disables)")
 	serveCmd.Flags().StringArrayVar(&upstreamCACerts, "additional-ca-cert", nil, "Path to a PEM CA certificate to append to the system trust bundle (repeatable)")
 	serveCmd.Flags().BoolVar(&propagateRequestHeaders, "propagate-request-headers", true, "Propagate incoming request headers to upstream hops")
 	serveCmd.Flags().BoolVar(&propagateResponseHeaders, "propagate-response-headers", true, "Propagate upstream response headers back to the client")
+	serveCmd.Flags().BoolVar(&strictAccept, "strict-accept", false, "Return 406 when the Accept header matches none of json, xml, or plain text (otherwise fall back to JSON)")
+	serveCmd.Flags().StringVar(&tracePropagation, "trace-propagation", proxy.TracePropagationNone, "Continue the caller's trace at each hop and pass it on: w3c for traceparent, b3 for Zipkin B3 headers, or none to forward trace headers untouched")
+	serveCmd.Flags().StringVar(&upstreamUserAgent, "upstream-user-agent", "", "User-Agent for requests to upstream hops; {service} is replaced with the service name (default propagates the client's)")
+	serveCmd.Flags().BoolVar(&trustProxyHeaders, "trust-proxy-headers", false, "Trust X-Forwarded-For and X-Real-IP to identify the client IP (only behind a trusted proxy)")
+	serveCmd.Flags().IntVar(&maxConcurrent, "max-concurrent", 0, "Maximum requests processed at once; excess requests queue for --max-queue-wait then get 503 (0 disables)")
+	serveCmd.Flags().IntVar(&maxHeapMB, "max-heap-mb", 0, "Answer 503 to proxy requests while the Go heap is over this many MiB, checked at most once a second (0 disables)")
+	serveCmd.Flags().DurationVar(&maxQueueWait, "max-queue-wait", 0, "How long a request waits for a slot under --max-concurrent before getting 503 (0 rejects immediately)")
+	serveCmd.Flags().Float64Var(&rateLimit, "rate-limit", 0, "Maximum requests per second per client IP, answered with 429 when exceeded (0 disables)")
+	serveCmd.Flags().IntVar(&rateBurst, "rate-burst", 0, "Requests a client IP may burst above --rate-limit (0 uses the rate rounded up)")
+	serveCmd.Flags().DurationVar(&idempotencyTTL, "idempotency-ttl", 0, "Replay the first response for requests repeating an Idempotency-Key within this window, waiting for it if still in flight; reusing a key for another method or path gets 422 (0 disables)")
+	serveCmd.Flags().BoolVar(&propagateDeadline, "propagate-deadline", false, "Propagate an end-to-end deadline via X-Deadline and return 504 early from hops that would exceed it")
+	serveCmd.Flags().DurationVar(&maxTotalDuration, "max-total-duration", 0, "Answer 504 once the whole chain has run this long since the first hop received the request, carried downstream in X-Deadline (0 disables)")
+	serveCmd.Flags().BoolVar(&detailedHealthEnabled, "detailed-health", false, "Include goroutine count, heap allocation, and uptime in /health responses")
+	serveCmd.Flags().BoolVar(&disableHealthRoute, "disable-health-route", false, "Don't serve the built-in /health endpoints, so /health is handled as a proxy path (use /livez and /readyz for probes)")
+	serveCmd.Flags().DurationVar(&clockSkew, "clock-skew", 0, "Offset the time reported by /clock, e.g. 90s or -2h")
+	serveCmd.Flags().BoolVar(&quietNoop, "quiet-noop", false, "Skip the debug log line for /noop requests, for benchmarking")
+	serveCmd.Flags().BoolVar(&enableGRPCWeb, "enable-grpc-web", false, "Stream gRPC-Web responses unbuffered with trailers preserved")
+	serveCmd.Flags().DurationVar(&keepalivePing, "keepalive-ping", 0, "Interval at which to ping --keepalive-target URLs to keep upstream connections warm (0 disables)")
+	serveCmd.Flags().StringArrayVar(&keepaliveTargets, "keepalive-target", nil, "Upstream URL to ping every --keepalive-ping interval (repeatable)")
+	serveCmd.Flags().IntVar(&maxRetries, "max-retries", 0, "Retry failed upstream requests up to this many times (transport errors, plus --retry-on-status codes)")
+	serveCmd.Flags().IntSliceVar(&retryOnStatus, "retry-on-status", nil, "Upstream status codes that warrant a retry within --max-retries, e.g. 502,503")
+	serveCmd.Flags().StringSliceVar(&remapStatus, "remap-status", nil, "Remap upstream status codes as from:to pairs, e.g. 404:200 (comma-separated or repeatable)")
+	serveCmd.Flags().StringVar(&recordFile, "record-file", "", "Append one JSON line per request (method, path, status, duration, body) to this file")
+	serveCmd.Flags().BoolVar(&recordHeaders, "record-headers", false, "Include request and response headers in --record-file entries (sensitive values redacted)")
+	serveCmd.Flags().StringVar(&pathPrefix, "path-prefix", "", "Base path the service is mounted under, e.g. /svc; stripped from requests before routing")
+	serveCmd.Flags().BoolVar(&rejectSuspiciousPaths, "reject-suspicious-paths", false, "Answer 400 to paths with .. segments, encoded slashes or backslashes instead of cleaning them")
+	serveCmd.Flags().StringSliceVar(&allowedMethods, "allowed-methods", nil, "Only serve these HTTP methods, answering others with 405 and an Allow header, e.g. GET,HEAD (comma-separated or repeatable; default allows all)")
+	serveCmd.Flags().StringVar(&staticDir, "static-dir", "", "Serve files from this directory under /static/")
+	serveCmd.Flags().StringVar(&transformExpr, "transform", "", "jq expression applied to JSON request bodies before forwarding, e.g. '{id: .user.id}'")
+	serveCmd.Flags().BoolVar(&decompressRequests, "decompress-requests", false, "Decompress gzip request bodies (Content-Encoding: gzip) before forwarding them")
+	serveCmd.Flags().BoolVar(&enableETag, "enable-etag", false, "Set a weak ETag on final responses and answer 304 Not Modified to a matching If-None-Match")
+	serveCmd.Flags().StringVar(&lastModified, "last-modified", "", "Set Last-Modified on final responses and answer 304 Not Modified to a later If-Modified-Since: start for the process start time, or an RFC 3339 timestamp")
+	serveCmd.Flags().StringVar(&emitChecksum, "emit-checksum", "", "Checksum generated response bodies: md5 sets Content-MD5, sha256 sets X-Content-SHA256")
+	serveCmd.Flags().StringVar(&responseTemplateFile, "response-template", "", "Path to a Go text/template file used to render final responses")
+	serveCmd.Flags().StringVar(&responseContentType, "response-content-type", "", "Declare final responses as this Content-Type instead of negotiating from Accept (e.g. text/html)")
+	serveCmd.Flags().StringVar(&responseMessage, "response-message", proxy.DefaultResponseMessage, "Message of the final response; {service} is replaced with the service name")
+	serveCmd.Flags().StringVar(&responseStyle, "response-style", "default", "Response field names: default (status,service,message), code (code,name,detail), or a mapping like status=code,service=name,message=detail")
+	serveCmd.Flags().IntVar(&passiveHealthThreshold, "passive-health-threshold", 0, "Skip /try/ targets after this many consecutive failures until their /health probe succeeds (0 disables)")
+	serveCmd.Flags().IntVar(&maxFanout, "max-fanout", proxy.DefaultMaxFanout, "Maximum targets a single /fanout/ or /try/ may name; larger lists get 400 (0 allows any number)")
+	serveCmd.Flags().IntVar(&maxRepeat, "max-repeat", proxy.DefaultMaxRepeat, "Maximum count of a single /repeat/; larger counts get 400 (0 allows any number)")
+	serveCmd.Flags().Int64Var(&maxPayloadBytes, "max-payload-bytes", proxy.DefaultMaxPayloadBytes, "Maximum size in bytes of generated response bodies such as /bytes/<n>, and of request bodies decompressed by --decompress-requests")
+	serveCmd.Flags().DurationVar(&latencyPerKB, "latency-per-kb", 0, "Delay generated responses by this much per KiB of body, simulating serialization cost (0 to disable)")
+	serveCmd.Flags().BoolVar(&latencyHeader, "latency-header", false, "Set X-Response-Time on every proxy response to this hop's handling time in milliseconds")
+	serveCmd.Flags().StringVar(&serverHeader, "server-header", "", "Set this Server header on every response; set it empty to strip Server headers propagated from upstream hops")
+	serveCmd.Flags().IntVar(&maxHeaderBytes, "max-header-bytes", http.DefaultMaxHeaderBytes, "Maximum size of request headers in bytes; larger requests are rejected with 431")
+	serveCmd.Flags().IntVar(&maxHeaderCount, "max-header-count", 0, "Maximum number of request header fields; proxy requests with more are rejected with 431 (0 allows any number)")
+	serveCmd.Flags().DurationVar(&acceptDelay, "accept-delay", 0, "Hold each new connection this long before serving it, one at a time, to simulate a slow listen backlog")
+	serveCmd.Flags().BoolVar(&disableKeepalive, "disable-keepalive", false, "Close every client connection after one request, forcing a new connection per request")
+	serveCmd.Flags().DurationVar(&drainGracePeriod, "drain-grace-period", 0, "After POST /drain, reject new proxy requests once this period elapses (0 keeps serving)")
 }
 
 // validateFlags validates all flag values before starting the server
@@ -76,29 +218,219 @@ func validateFlags(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("port must be between 1 and 65535, got %d", port)
 	}
 
+	// Validate bind address is an IP
+	if bindAddress != "" {
+		if _, err := parseBindAddress(bindAddress); err != nil {
+			return err
+		}
+	}
+
+	// Validate listen addresses, TLS listeners need a certificate
+	for _, spec := range listenAddrs {
+		ls, err := parseListenSpec(spec)
+		if err != nil {
+			return err
+		}
+		if ls.tls && (tlsCertFile == "" || tlsKeyFile == "") {
+			return fmt.Errorf("listener %q requires --tls-cert and --tls-key", spec)
+		}
+	}
+
 	// Validate timeout is positive
 	if timeout < 0 {
 		return fmt.Errorf("timeout must be positive, got %s", timeout)
 	}
 
-	// Validate log level
-	validLevels := map[string]bool{
-		"debug": true,
-		"info":  true,
-		"warn":  true,
-		"error": true,
+	// Validate max request timeout is not negative
+	if maxRequestTimeout < 0 {
+		return fmt.Errorf("max-request-timeout must not be negative, got %s", maxRequestTimeout)
+	}
+
+	// Validate body read timeout is not negative
+	if bodyReadTimeout < 0 {
+		return fmt.Errorf("body-read-timeout must not be negative, got %s", bodyReadTimeout)
+	}
+
+	// Validate idempotency TTL is not negative
+	if idempotencyTTL < 0 {
+		return fmt.Errorf("idempotency-ttl must not be negative, got %s", idempotencyTTL)
+	}
+
+	// Validate keepalive pings have somewhere to go
+	if keepalivePing < 0 {
+		return fmt.Errorf("keepalive-ping must not be negative, got %s", keepalivePing)
+	}
+	if keepalivePing > 0 && len(keepaliveTargets) == 0 {
+		return fmt.Errorf("keepalive-ping requires at least one --keepalive-target")
+	}
+	for _, target := range keepaliveTargets {
+		if err := proxy.ValidateKeepaliveTarget(target); err != nil {
+			return err
+		}
+	}
+
+	// Validate retry settings
+	if maxRetries < 0 {
+		return fmt.Errorf("max-retries must not be negative, got %d", maxRetries)
+	}
+	for _, code := range retryOnStatus {
+		if code < 100 || code > 599 {
+			return fmt.Errorf("retry-on-status codes must be between 100 and 599, got %d", code)
+		}
+	}
+
+	// Validate host aliases
+	if _, err := proxy.ParseHostAliases(hostAliases); err != nil {
+		return err
+	}
+
+	// Validate trace propagation format
+	if err := proxy.ValidateTracePropagation(tracePropagation); err != nil {
+		return err
+	}
+
+	// Validate last modified time
+	if _, err := proxy.ParseLastModified(lastModified, processStart); err != nil {
+		return err
+	}
+
+	// Validate allowed methods
+	if _, err := proxy.ParseAllowedMethods(allowedMethods); err != nil {
+		return err
+	}
+
+	// Validate status remap pairs
+	if _, err := proxy.ParseStatusRemap(remapStatus); err != nil {
+		return err
+	}
+
+	// Validate response content type is a media type
+	if responseContentType != "" {
+		if _, _, err := mime.ParseMediaType(responseContentType); err != nil {
+			return fmt.Errorf("response-content-type %q is not a valid media type: %w", responseContentType, err)
+		}
+	}
+
+	// Validate path prefix is an absolute path
+	if _, err := normalizePathPrefix(pathPrefix); err != nil {
+		return err
+	}
+
+	// Validate response style names a known style or a field mapping
+	if _, err := proxy.ParseResponseStyle(responseStyle); err != nil {
+		return err
+	}
+
+	// Validate checksum algorithm
+	if err := proxy.ValidateChecksum(emitChecksum); err != nil {
+		return err
+	}
+
+	// Validate concurrency settings are not negative
+	if maxConcurrent < 0 {
+		return fmt.Errorf("max-concurrent must not be negative, got %d", maxConcurrent)
+	}
+	if maxQueueWait < 0 {
+		return fmt.Errorf("max-queue-wait must not be negative, got %s", maxQueueWait)
+	}
+
+	// Validate max heap is not negative
+	if maxHeapMB < 0 {
+		return fmt.Errorf("max-heap-mb must not be negative, got %d", maxHeapMB)
+	}
+
+	// Validate rate limit settings are not negative
+	if rateLimit < 0 {
+		return fmt.Errorf("rate-limit must not be negative, got %g", rateLimit)
+	}
+	if rateBurst < 0 {
+		return fmt.Errorf("rate-burst must not be negative, got %d", rateBurst)
+	}
+
+	// Validate max fanout is not negative
+	if maxFanout < 0 {
+		return fmt.Errorf("max-fanout must not be negative, got %d", maxFanout)
+	}
+
+	// Validate max repeat is not negative
+	if maxRepeat < 0 {
+		return fmt.Errorf("max-repeat must not be negative, got %d", maxRepeat)
+	}
+
+	// Validate passive health threshold is not negative
+	if passiveHealthThreshold < 0 {
+		return fmt.Errorf("passive-health-threshold must not be negative, got %d", passiveHealthThreshold)
+	}
+
+	// Validate max payload bytes is not negative
+	if maxPayloadBytes < 0 {
+		return fmt.Errorf("max-payload-bytes must not be negative, got %d", maxPayloadBytes)
+	}
+
+	// Validate latency per KiB is not negative
+	if latencyPerKB < 0 {
+		return fmt.Errorf("latency-per-kb must not be negative, got %s", latencyPerKB)
+	}
+
+	// Validate max header bytes is positive
+	if maxHeaderBytes <= 0 {
+		return fmt.Errorf("max-header-bytes must be positive, got %d", maxHeaderBytes)
+	}
+
+	// Validate max header count is not negative
+	if maxHeaderCount < 0 {
+		return fmt.Errorf("max-header-count must not be negative, got %d", maxHeaderCount)
+	}
+
+	// Validate DNS cache TTL is not negative
+	if dnsCacheTTL < 0 {
+		return fmt.Errorf("dns-cache-ttl must not be negative, got %s", dnsCacheTTL)
+	}
+
+	// Validate max total duration is not negative
+	if maxTotalDuration < 0 {
+		return fmt.Errorf("max-total-duration must not be negative, got %s", maxTotalDuration)
+	}
+
+	// Validate accept delay is not negative
+	if acceptDelay < 0 {
+		return fmt.Errorf("accept-delay must not be negative, got %s", acceptDelay)
 	}
-	if !validLevels[logLevel] {
+
+	// Validate drain grace period is not negative
+	if drainGracePeriod < 0 {
+		return fmt.Errorf("drain-grace-period must not be negative, got %s", drainGracePeriod)
+	}
+
+	// Validate log rotation settings
+	if logMaxSize <= 0 {
+		return fmt.Errorf("log-max-size must be positive, got %d", logMaxSize)
+	}
+	if logMaxBackups < 0 {
+		return fmt.Errorf("log-max-backups must not be negative, got %d", logMaxBackups)
+	}
+	if logMaxAge < 0 {
+		return fmt.Errorf("log-max-age must not be negative, got %d", logMaxAge)
+	}
+
+	// Validate log level
+	if _, ok := logLevels[logLevel]; !ok {
 		return fmt.Errorf("log-level must be one of [debug, info, warn, error], got %q", logLevel)
 	}
 
+	// Validate log sample rate is a fraction
+	if logSampleRate < 0 || logSampleRate > 1 {
+		return fmt.Errorf("log-sample-rate must be between 0 and 1, got %g", logSampleRate)
+	}
+
 	// Validate log format
 	validFormats := map[string]bool{
-		"json": true,
-		"text": true,
+		"json":   true,
+		"text":   true,
+		"pretty": true,
 	}
 	if !validFormats[logFormat] {
-		return fmt.Errorf("log-format must be one of [json, text], got %q", logFormat)
+		return fmt.Errorf("log-format must be one of [json, text, pretty], got %q", logFormat)
 	}
 
 	// Validate TLS configuration - both cert and key must be provided together
@@ -122,6 +454,16 @@ func validateFlags(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Validate per-hostname certificates, which fall back to the default certificate
+	if len(tlsCertsFor) > 0 {
+		if tlsCertFile == "" {
+			return fmt.Errorf("--tls-cert-for requires --tls-cert and --tls-key for clients without a matching hostname")
+		}
+		if _, err := loadSNICertificates(tlsCertsFor); err != nil {
+			return err
+		}
+	}
+
 	// Validate additional CA cert files
 	for _, caFile := range upstreamCACerts {
 		if _, err := os.Stat(caFile); err != nil {
@@ -137,13 +479,33 @@ func validateFlags(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Validate response template file exists; NewHandler parses it
+	if responseTemplateFile != "" {
+		if _, err := os.Stat(responseTemplateFile); err != nil {
+			return fmt.Errorf("cannot access response template %q: %w", responseTemplateFile, err)
+		}
+	}
+
+	// Validate static directory exists
+	if staticDir != "" {
+		info, err := os.Stat(staticDir)
+		if err != nil {
+			return fmt.Errorf("cannot access static directory %q: %w", staticDir, err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("static-dir %q is not a directory", staticDir)
+		}
+	}
+
 	return nil
 }
 
 // runServer starts the HTTP server with the configured settings
 func runServer(cmd *cobra.Command, args []string) error {
 	// Set up structured logging
-	logger := setupLogger(logLevel, logFormat, serviceName)
+	logOutput := newLogWriter()
+	defer func() { _ = logOutput.Close() }()
+	logger := setupLogger(logOutput, logLevel, logFormat, serviceName)
 
 	// Determine if TLS is enabled based on cert/key presence
 	tlsEnabled := tlsCertFile != "" && tlsKeyFile != ""
@@ -151,100 +513,295 @@ func runServer(cmd *cobra.Command, args []string) error {
 	logger.Info("Starting microservice",
 		slog.String("service", serviceName),
 		slog.Int("port", port),
+		slog.String("bind_address", bindAddress),
+		slog.Any("listen", listenAddrs),
 		slog.Duration("timeout", timeout),
+		slog.Duration("max_request_timeout", maxRequestTimeout),
+		slog.Duration("body_read_timeout", bodyReadTimeout),
 		slog.String("log_level", logLevel),
 		slog.String("log_format", logFormat),
+		slog.Float64("log_sample_rate", logSampleRate),
 		slog.Bool("log_headers", logHeaders),
+		slog.String("log_file", logFile),
 		slog.Bool("tls_enabled", tlsEnabled),
+		slog.Int("sni_certificates", len(tlsCertsFor)),
 		slog.Bool("upstream_tls_insecure", upstreamTLSInsecure),
+		slog.Bool("follow_redirects", followRedirects),
+		slog.Any("host_aliases", hostAliases),
+		slog.Duration("dns_cache_ttl", dnsCacheTTL),
+		slog.Bool("enable_websocket", enableWebSocket),
+		slog.Bool("enable_coalescing", enableCoalescing),
+		slog.Bool("enable_expect_continue", expectContinue),
+		slog.Bool("forward_full_path", forwardFullPath),
+		slog.Bool("forward_query", forwardQuery),
+		slog.Bool("hmac_verification", hmacSecret != ""),
 		slog.Any("additional_ca_certs", upstreamCACerts),
 		slog.Bool("propagate_request_headers", propagateRequestHeaders),
 		slog.Bool("propagate_response_headers", propagateResponseHeaders),
+		slog.Bool("strict_accept", strictAccept),
+		slog.String("response_template", responseTemplateFile),
+		slog.String("transform", transformExpr),
+		slog.Bool("enable_etag", enableETag),
+		slog.String("last_modified", lastModified),
+		slog.String("emit_checksum", emitChecksum),
+		slog.Bool("decompress_requests", decompressRequests),
+		slog.String("upstream_user_agent", upstreamUserAgent),
+		slog.String("trace_propagation", tracePropagation),
+		slog.Bool("trust_proxy_headers", trustProxyHeaders),
+		slog.Int("max_concurrent", maxConcurrent),
+		slog.Int("max_heap_mb", maxHeapMB),
+		slog.Duration("max_queue_wait", maxQueueWait),
+		slog.Float64("rate_limit", rateLimit),
+		slog.Int("rate_burst", rateBurst),
+		slog.Duration("idempotency_ttl", idempotencyTTL),
+		slog.Bool("propagate_deadline", propagateDeadline),
+		slog.Duration("max_total_duration", maxTotalDuration),
+		slog.Bool("detailed_health", detailedHealthEnabled),
+		slog.Bool("disable_health_route", disableHealthRoute),
+		slog.Duration("clock_skew", clockSkew),
+		slog.Bool("quiet_noop", quietNoop),
+		slog.Bool("enable_grpc_web", enableGRPCWeb),
+		slog.Duration("keepalive_ping", keepalivePing),
+		slog.Any("keepalive_targets", keepaliveTargets),
+		slog.Int("max_retries", maxRetries),
+		slog.Any("retry_on_status", retryOnStatus),
+		slog.Any("remap_status", remapStatus),
+		slog.String("record_file", recordFile),
+		slog.String("static_dir", staticDir),
+		slog.String("path_prefix", pathPrefix),
+		slog.Bool("reject_suspicious_paths", rejectSuspiciousPaths),
+		slog.Any("allowed_methods", allowedMethods),
+		slog.String("response_content_type", responseContentType),
+		slog.String("response_style", responseStyle),
+		slog.String("response_message", responseMessage),
+		slog.Int("max_fanout", maxFanout),
+		slog.Int("max_repeat", maxRepeat),
+		slog.Int("passive_health_threshold", passiveHealthThreshold),
+		slog.Int64("max_payload_bytes", maxPayloadBytes),
+		slog.Duration("latency_per_kb", latencyPerKB),
+		slog.String("server_header", serverHeader),
+		slog.Bool("latency_header", latencyHeader),
+		slog.Int("max_header_bytes", maxHeaderBytes),
+		slog.Int("max_header_count", maxHeaderCount),
+		slog.Bool("disable_keepalive", disableKeepalive),
+		slog.Duration("drain_grace_period", drainGracePeriod),
+		slog.Duration("accept_delay", acceptDelay),
 	)
 
+	aliases, err := proxy.ParseHostAliases(hostAliases)
+	if err != nil {
+		return err
+	}
+
+	statusRemap, err := proxy.ParseStatusRemap(remapStatus)
+	if err != nil {
+		return err
+	}
+
+	lastModifiedTime, err := proxy.ParseLastModified(lastModified, processStart)
+	if err != nil {
+		return err
+	}
+
+	methods, err := proxy.ParseAllowedMethods(allowedMethods)
+	if err != nil {
+		return err
+	}
+
+	responseFields, err := proxy.ParseResponseStyle(responseStyle)
+	if err != nil {
+		return err
+	}
+
 	handler, err := proxy.NewHandler(timeout, serviceName, logger,
 		proxy.WithHeaderLogging(logHeaders),
+		proxy.WithLogSampleRate(logSampleRate),
 		proxy.WithTLSInsecure(upstreamTLSInsecure),
+		proxy.WithFollowRedirects(followRedirects),
+		proxy.WithHostAliases(aliases),
+		proxy.WithRejectSuspiciousPaths(rejectSuspiciousPaths),
+		proxy.WithAllowedMethods(methods),
+		proxy.WithLatencyHeader(latencyHeader),
+		proxy.WithDNSCacheTTL(dnsCacheTTL),
+		proxy.WithWebSocket(enableWebSocket),
+		proxy.WithCoalescing(enableCoalescing),
+		proxy.WithExpectContinue(expectContinue),
+		proxy.WithForwardFullPath(forwardFullPath),
+		proxy.WithForwardQuery(forwardQuery),
+		proxy.WithMaxHeaderCount(maxHeaderCount),
+		proxy.WithHMACSecret(hmacSecret),
 		proxy.WithCACertFiles(upstreamCACerts),
 		proxy.WithPropagateRequestHeaders(propagateRequestHeaders),
-		proxy.WithPropagateResponseHeaders(propagateResponseHeaders))
+		proxy.WithPropagateResponseHeaders(propagateResponseHeaders),
+		proxy.WithStrictAccept(strictAccept),
+		proxy.WithResponseTemplate(responseTemplateFile),
+		proxy.WithTransform(transformExpr),
+		proxy.WithUpstreamUserAgent(upstreamUserAgent),
+		proxy.WithTracePropagation(tracePropagation),
+		proxy.WithTrustProxyHeaders(trustProxyHeaders),
+		proxy.WithMaxConcurrent(maxConcurrent),
+		proxy.WithMaxHeapMB(maxHeapMB),
+		proxy.WithMaxQueueWait(maxQueueWait),
+		proxy.WithRateLimit(rateLimit, rateBurst),
+		proxy.WithIdempotencyTTL(idempotencyTTL),
+		proxy.WithDeadlinePropagation(propagateDeadline),
+		proxy.WithMaxTotalDuration(maxTotalDuration),
+		proxy.WithRecordFile(recordFile),
+		proxy.WithRecordHeaders(recordHeaders),
+		proxy.WithGRPCWeb(enableGRPCWeb),
+		proxy.WithStatusRemap(statusRemap),
+		proxy.WithMaxRetries(maxRetries),
+		proxy.WithRetryOnStatus(retryOnStatus),
+		proxy.WithMaxRequestTimeout(maxRequestTimeout),
+		proxy.WithBodyReadTimeout(bodyReadTimeout),
+		proxy.WithMaxPayloadBytes(maxPayloadBytes),
+		proxy.WithLatencyPerKB(latencyPerKB),
+		proxy.WithResponseContentType(responseContentType),
+		proxy.WithResponseFields(responseFields),
+		proxy.WithResponseMessage(responseMessage),
+		proxy.WithETag(enableETag),
+		proxy.WithLastModified(lastModifiedTime),
+		proxy.WithChecksum(emitChecksum),
+		proxy.WithDecompressRequests(decompressRequests),
+		proxy.WithMaxFanout(maxFanout),
+		proxy.WithMaxRepeat(maxRepeat),
+		proxy.WithPassiveHealthThreshold(passiveHealthThreshold))
 	if err != nil {
 		logger.Error("Failed to initialize handler", slog.String("error", err.Error()))
 		return err
 	}
+	defer func() {
+		if err := handler.Close(); err != nil {
+			logger.Error("Failed to close handler", slog.String("error", err.Error()))
+		}
+	}()
 
-	mux := http.NewServeMux()
-	mux.Handle("/", handler)
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		logger.Debug("Health check request",
-			slog.String("remote_addr", r.RemoteAddr),
-			slog.String("user_agent", r.UserAgent()),
-		)
-		w.WriteHeader(http.StatusOK)
-		w.Header().Set("Content-Type", "application/json")
-		_, err := fmt.Fprint(w, `{"status":"healthy","service":"`+serviceName+`"}`)
+	drain := newDrainer(serviceName, drainGracePeriod, logger)
+	mux := newServeMux(handler, serviceName, drain, logger)
+	registerHandlerEndpoints(mux, handler, drain)
+
+	if staticDir != "" {
+		static, err := newStaticHandler(staticDir)
 		if err != nil {
-			logger.Error("Failed to write health response", slog.String("error", err.Error()))
+			logger.Error("Failed to initialize static file server", slog.String("error", err.Error()))
+			return err
 		}
-	})
+		mux.Handle(staticPrefix, static)
+	}
 
-	server := &http.Server{
-		Addr:    fmt.Sprintf(":%d", port),
-		Handler: mux,
+	// Apply the Server header to every route, including health and static files
+	var root http.Handler = mux
+	if cmd.Flags().Changed("server-header") {
+		root = serverHeaderMiddleware(serverHeader, mux)
+	}
+
+	// Mount every route under the path prefix
+	prefix, err := normalizePathPrefix(pathPrefix)
+	if err != nil {
+		return err
 	}
+	if prefix != "" {
+		root = pathPrefixMiddleware(prefix, root)
+	}
+
+	// Clean paths before routing, as the router would otherwise redirect unclean ones
+	root = handler.NormalizePaths(root)
 
-	protocol := "http"
-	if tlsEnabled {
-		protocol = "https"
+	specs, err := listenSpecs()
+	if err != nil {
+		logger.Error("Invalid listen configuration", slog.String("error", err.Error()))
+		return err
 	}
-	logger.Info("Server listening",
-		slog.String("addr", server.Addr),
-		slog.String("protocol", protocol))
 
-	if tlsEnabled {
-		if err := server.ListenAndServeTLS(tlsCertFile, tlsKeyFile); err != nil && err != http.ErrServerClosed {
-			logger.Error("HTTPS server error", slog.String("error", err.Error()))
-			return err
-		}
-	} else {
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Error("HTTP server error", slog.String("error", err.Error()))
-			return err
+	listeners, err := bindListeners(specs)
+	if err != nil {
+		logger.Error("Failed to bind listeners", slog.String("error", err.Error()))
+		return err
+	}
+
+	// Hold each new connection before it is served
+	if acceptDelay > 0 {
+		for i := range listeners {
+			listeners[i].listener = newDelayedListener(listeners[i].listener, acceptDelay)
 		}
 	}
 
-	return nil
+	// Stop all listeners gracefully on SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Keep upstream connections warm until shutdown
+	if keepalivePing > 0 {
+		go handler.KeepWarm(ctx, keepalivePing, keepaliveTargets)
+	}
+
+	return serveListeners(ctx, listeners, root, logger)
 }
 
-// setupLogger configures and returns a structured logger
-func setupLogger(level, format, serviceName string) *slog.Logger {
-	var logLevel slog.Level
-	switch level {
-	case "debug":
-		logLevel = slog.LevelDebug
-	case "info":
-		logLevel = slog.LevelInfo
-	case "warn":
-		logLevel = slog.LevelWarn
-	case "error":
-		logLevel = slog.LevelError
-	default:
-		logLevel = slog.LevelInfo
+// newHTTPServer creates the http.Server for the given address with the configured connection limits
+func newHTTPServer(addr string, handler http.Handler) *http.Server {
+	server := &http.Server{
+		Addr:           addr,
+		Handler:        handler,
+		MaxHeaderBytes: maxHeaderBytes,
+	}
+	// Without keep-alives every response carries Connection: close
+	server.SetKeepAlivesEnabled(!disableKeepalive)
+	return server
+}
+
+// registerHandlerEndpoints adds the proxy handler's own endpoints to mux. Those that run requests
+// through the proxy chain are gated by drain, as the proxy handler itself is.
+func registerHandlerEndpoints(mux *http.ServeMux, handler *proxy.Handler, drain *drainer) {
+	mux.HandleFunc("/inspect", handler.ServeInspect)
+	mux.Handle("/replay", drain.middleware(http.HandlerFunc(handler.ServeReplay)))
+	mux.Handle("/compose", drain.middleware(http.HandlerFunc(handler.ServeCompose)))
+	mux.HandleFunc("/admin/warmup", handler.ServeWarmup)
+}
+
+// newServeMux builds the routing table: the built-in health and admin endpoints, answering as serviceName, plus the proxy handler for everything else
+func newServeMux(handler http.Handler, serviceName string, drain *drainer, logger *slog.Logger) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/", drain.middleware(handler))
+	// Without the built-in routes, /health falls through to the proxy handler like any other path
+	if !disableHealthRoute {
+		mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+			handleHealth(w, r, serviceName, logger)
+		})
+		mux.HandleFunc("/health/fail", handleHealthToggle(true, serviceName, logger))
+		mux.HandleFunc("/health/recover", handleHealthToggle(false, serviceName, logger))
 	}
+	mux.HandleFunc("/livez", drain.handleLivez)
+	mux.HandleFunc("/readyz", drain.handleReadyz)
+	mux.HandleFunc("/drain", drain.handleDrain)
+	mux.HandleFunc("/admin/loglevel", handleLogLevel(serviceName, logger))
+	mux.HandleFunc("/noop", handleNoop(logger))
+	mux.HandleFunc("/clock", handleClock(logger))
+	mux.HandleFunc("/openapi.json", handleOpenAPI(logger))
+	return mux
+}
+
+// setupLogger configures and returns a structured logger
+func setupLogger(out io.Writer, level, format, serviceName string) *slog.Logger {
+	// Unknown levels fall back to info, the zero level
+	logLevelVar.Set(logLevels[level])
 
 	opts := &slog.HandlerOptions{
-		Level:     logLevel,
+		Level:     &logLevelVar,
 		AddSource: true,
 	}
 
 	var handler slog.Handler
 	switch format {
 	case "json":
-		handler = slog.NewJSONHandler(os.Stdout, opts)
+		handler = slog.NewJSONHandler(out, opts)
 	case "text":
-		handler = slog.NewTextHandler(os.Stdout, opts)
+		handler = slog.NewTextHandler(out, opts)
+	case "pretty":
+		// Colors only make sense on a terminal; redirected output stays plain
+		handler = newPrettyHandler(out, opts, isTerminal(out))
 	default:
-		handler = slog.NewJSONHandler(os.Stdout, opts)
+		handler = slog.NewJSONHandler(out, opts)
 	}
 
 	logger := slog.New(handler)
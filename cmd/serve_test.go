@@ -1,16 +1,27 @@
 package cmd
 
 import (
+	"bufio"
+	"bytes"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
+	"fmt"
+	"io"
 	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestValidateFlags(t *testing.T) {
@@ -227,6 +238,522 @@ func TestValidateFlags(t *testing.T) {
 			},
 			expectError: false,
 		},
+		{
+			name: "valid listen addresses",
+			setupFlags: func() {
+				listenAddrs = []string{":8080", "127.0.0.1:9090"}
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid listen address",
+			setupFlags: func() {
+				listenAddrs = []string{"8080"}
+			},
+			expectError: true,
+		},
+		{
+			name: "tls listener without certificate",
+			setupFlags: func() {
+				listenAddrs = []string{":8443,tls"}
+			},
+			expectError: true,
+		},
+		{
+			name: "response template not found",
+			setupFlags: func() {
+				responseTemplateFile = "/nonexistent/response.tmpl"
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid idempotency-ttl - negative",
+			setupFlags: func() {
+				idempotencyTTL = -time.Second
+			},
+			expectError: true,
+		},
+		{
+			name: "static directory not found",
+			setupFlags: func() {
+				staticDir = "/nonexistent/static"
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid max-header-bytes - zero",
+			setupFlags: func() {
+				maxHeaderBytes = 0
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid max-header-bytes - negative",
+			setupFlags: func() {
+				maxHeaderBytes = -1
+			},
+			expectError: true,
+		},
+		{
+			name: "valid max-header-count",
+			setupFlags: func() {
+				maxHeaderCount = 100
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid max-header-count - negative",
+			setupFlags: func() {
+				maxHeaderCount = -1
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid drain-grace-period - negative",
+			setupFlags: func() {
+				drainGracePeriod = -time.Second
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid log-max-size - zero",
+			setupFlags: func() {
+				logMaxSize = 0
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid log-max-backups - negative",
+			setupFlags: func() {
+				logMaxBackups = -1
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid log-max-age - negative",
+			setupFlags: func() {
+				logMaxAge = -1
+			},
+			expectError: true,
+		},
+		{
+			name: "valid keepalive ping with target",
+			setupFlags: func() {
+				keepalivePing = time.Second
+				keepaliveTargets = []string{"http://service-b:8080/health"}
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid keepalive ping without target",
+			setupFlags: func() {
+				keepalivePing = time.Second
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid keepalive target without scheme",
+			setupFlags: func() {
+				keepalivePing = time.Second
+				keepaliveTargets = []string{"service-b:8080"}
+			},
+			expectError: true,
+		},
+		{
+			name: "valid host aliases",
+			setupFlags: func() {
+				hostAliases = []string{"myservice=10.0.0.5", "other=::1"}
+			},
+			expectError: false,
+		},
+		{
+			name: "host alias to a hostname",
+			setupFlags: func() {
+				hostAliases = []string{"myservice=example.com"}
+			},
+			expectError: true,
+		},
+		{
+			name: "valid status remap",
+			setupFlags: func() {
+				remapStatus = []string{"404:200", "500:503"}
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid status remap",
+			setupFlags: func() {
+				remapStatus = []string{"404-200"}
+			},
+			expectError: true,
+		},
+		{
+			name: "valid retries on status",
+			setupFlags: func() {
+				maxRetries = 2
+				retryOnStatus = []int{502, 503}
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid max-retries - negative",
+			setupFlags: func() {
+				maxRetries = -1
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid retry-on-status code",
+			setupFlags: func() {
+				retryOnStatus = []int{700}
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid max-request-timeout - negative",
+			setupFlags: func() {
+				maxRequestTimeout = -time.Second
+			},
+			expectError: true,
+		},
+		{
+			name: "valid body-read-timeout",
+			setupFlags: func() {
+				bodyReadTimeout = 5 * time.Second
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid body-read-timeout - negative",
+			setupFlags: func() {
+				bodyReadTimeout = -time.Second
+			},
+			expectError: true,
+		},
+		{
+			name: "valid pretty log format",
+			setupFlags: func() {
+				logFormat = "pretty"
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid max-payload-bytes - negative",
+			setupFlags: func() {
+				maxPayloadBytes = -1
+			},
+			expectError: true,
+		},
+		{
+			name: "valid latency-per-kb",
+			setupFlags: func() {
+				latencyPerKB = time.Millisecond
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid latency-per-kb - negative",
+			setupFlags: func() {
+				latencyPerKB = -time.Millisecond
+			},
+			expectError: true,
+		},
+		{
+			name: "valid response-content-type",
+			setupFlags: func() {
+				responseContentType = "text/html; charset=utf-8"
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid response-content-type",
+			setupFlags: func() {
+				responseContentType = "not a type"
+			},
+			expectError: true,
+		},
+		{
+			name: "valid bind-address - ipv6 any",
+			setupFlags: func() {
+				bindAddress = "[::]"
+			},
+			expectError: false,
+		},
+		{
+			name: "valid bind-address - ipv4",
+			setupFlags: func() {
+				bindAddress = "127.0.0.1"
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid bind-address - hostname",
+			setupFlags: func() {
+				bindAddress = "localhost"
+			},
+			expectError: true,
+		},
+		{
+			name: "valid rate-limit",
+			setupFlags: func() {
+				rateLimit = 2.5
+				rateBurst = 5
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid rate-limit - negative",
+			setupFlags: func() {
+				rateLimit = -1
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid rate-burst - negative",
+			setupFlags: func() {
+				rateBurst = -1
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid max-fanout - negative",
+			setupFlags: func() {
+				maxFanout = -1
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid max-repeat - negative",
+			setupFlags: func() {
+				maxRepeat = -1
+			},
+			expectError: true,
+		},
+		{
+			name: "valid passive-health-threshold",
+			setupFlags: func() {
+				passiveHealthThreshold = 3
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid passive-health-threshold - negative",
+			setupFlags: func() {
+				passiveHealthThreshold = -1
+			},
+			expectError: true,
+		},
+		{
+			name: "valid max-concurrent with queue wait",
+			setupFlags: func() {
+				maxConcurrent = 10
+				maxQueueWait = time.Second
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid max-concurrent - negative",
+			setupFlags: func() {
+				maxConcurrent = -1
+			},
+			expectError: true,
+		},
+		{
+			name: "valid max-heap-mb",
+			setupFlags: func() {
+				maxHeapMB = 512
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid max-heap-mb - negative",
+			setupFlags: func() {
+				maxHeapMB = -1
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid max-queue-wait - negative",
+			setupFlags: func() {
+				maxQueueWait = -time.Second
+			},
+			expectError: true,
+		},
+		{
+			name: "valid path-prefix",
+			setupFlags: func() {
+				pathPrefix = "/svc/"
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid path-prefix - relative",
+			setupFlags: func() {
+				pathPrefix = "svc"
+			},
+			expectError: true,
+		},
+		{
+			name: "valid response-style - code",
+			setupFlags: func() {
+				responseStyle = "code"
+			},
+			expectError: false,
+		},
+		{
+			name: "valid response-style - mapping",
+			setupFlags: func() {
+				responseStyle = "status=http_status,message=reason"
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid response-style - unknown style",
+			setupFlags: func() {
+				responseStyle = "fancy"
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid response-style - duplicate names",
+			setupFlags: func() {
+				responseStyle = "status=name,service=name"
+			},
+			expectError: true,
+		},
+		{
+			name: "valid log-sample-rate",
+			setupFlags: func() {
+				logSampleRate = 0.1
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid log-sample-rate - above one",
+			setupFlags: func() {
+				logSampleRate = 1.5
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid log-sample-rate - negative",
+			setupFlags: func() {
+				logSampleRate = -0.1
+			},
+			expectError: true,
+		},
+		{
+			name: "valid dns-cache-ttl",
+			setupFlags: func() {
+				dnsCacheTTL = 30 * time.Second
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid dns-cache-ttl - negative",
+			setupFlags: func() {
+				dnsCacheTTL = -time.Second
+			},
+			expectError: true,
+		},
+		{
+			name: "valid max-total-duration",
+			setupFlags: func() {
+				maxTotalDuration = 5 * time.Second
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid max-total-duration - negative",
+			setupFlags: func() {
+				maxTotalDuration = -time.Second
+			},
+			expectError: true,
+		},
+		{
+			name: "valid accept-delay",
+			setupFlags: func() {
+				acceptDelay = 500 * time.Millisecond
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid accept-delay - negative",
+			setupFlags: func() {
+				acceptDelay = -time.Second
+			},
+			expectError: true,
+		},
+		{
+			name: "valid allowed-methods",
+			setupFlags: func() {
+				allowedMethods = []string{"get", "HEAD"}
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid allowed-methods",
+			setupFlags: func() {
+				allowedMethods = []string{"GET", "NOT ALLOWED"}
+			},
+			expectError: true,
+		},
+		{
+			name: "valid trace-propagation - b3",
+			setupFlags: func() {
+				tracePropagation = "b3"
+			},
+			expectError: false,
+		},
+		{
+			name: "valid trace-propagation - w3c",
+			setupFlags: func() {
+				tracePropagation = "w3c"
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid trace-propagation",
+			setupFlags: func() {
+				tracePropagation = "jaeger"
+			},
+			expectError: true,
+		},
+		{
+			name: "valid last-modified - start",
+			setupFlags: func() {
+				lastModified = "start"
+			},
+			expectError: false,
+		},
+		{
+			name: "valid last-modified - timestamp",
+			setupFlags: func() {
+				lastModified = "2024-06-01T12:00:00Z"
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid last-modified",
+			setupFlags: func() {
+				lastModified = "yesterday"
+			},
+			expectError: true,
+		},
+		{
+			name: "valid emit-checksum",
+			setupFlags: func() {
+				emitChecksum = "sha256"
+			},
+			expectError: false,
+		},
+		{
+			name: "invalid emit-checksum",
+			setupFlags: func() {
+				emitChecksum = "crc32"
+			},
+			expectError: true,
+		},
 		{
 			name: "additional-ca-cert with non-existent file",
 			setupFlags: func() {
@@ -251,8 +778,49 @@ func TestValidateFlags(t *testing.T) {
 			logHeaders = false
 			tlsCertFile = ""
 			tlsKeyFile = ""
+			tlsCertsFor = nil
 			upstreamTLSInsecure = false
 			upstreamCACerts = nil
+			maxHeaderBytes = 1 << 20
+			maxHeaderCount = 0
+			drainGracePeriod = 0
+			listenAddrs = nil
+			responseTemplateFile = ""
+			idempotencyTTL = 0
+			staticDir = ""
+			logMaxSize = 100
+			logMaxBackups = 0
+			logMaxAge = 0
+			keepalivePing = 0
+			keepaliveTargets = nil
+			remapStatus = nil
+			hostAliases = nil
+			maxRetries = 0
+			retryOnStatus = nil
+			maxRequestTimeout = 0
+			bodyReadTimeout = 0
+			maxPayloadBytes = 10 << 20
+			latencyPerKB = 0
+			responseContentType = ""
+			bindAddress = ""
+			rateLimit = 0
+			rateBurst = 0
+			maxFanout = 64
+			maxRepeat = 100
+			passiveHealthThreshold = 0
+			maxConcurrent = 0
+			maxHeapMB = 0
+			maxQueueWait = 0
+			responseStyle = "default"
+			emitChecksum = ""
+			acceptDelay = 0
+			maxTotalDuration = 0
+			logSampleRate = 1
+			pathPrefix = ""
+			dnsCacheTTL = 0
+			lastModified = ""
+			tracePropagation = "none"
+			allowedMethods = nil
 
 			// Setup test-specific flags
 			tt.setupFlags()
@@ -437,3 +1005,79 @@ func TestValidateFlagsAdditionalCACert(t *testing.T) {
 		}
 	})
 }
+
+func TestMaxHeaderBytes(t *testing.T) {
+	maxHeaderBytes = 1024
+	t.Cleanup(func() { maxHeaderBytes = 1 << 20 })
+
+	logger := createTestLogger()
+	mux := newServeMux(okHandler, "test-service", newDrainer("test-service", 0, logger), logger)
+
+	server := httptest.NewUnstartedServer(nil)
+	server.Config = newHTTPServer("", mux)
+	server.Start()
+	defer server.Close()
+
+	t.Run("headers within limit are accepted", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/health", nil)
+		require.NoError(t, err)
+		req.Header.Set("X-Small", "value")
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("oversized headers are rejected with 431", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/health", nil)
+		require.NoError(t, err)
+		// net/http allows some slack beyond MaxHeaderBytes, so go well past it
+		req.Header.Set("X-Large", strings.Repeat("a", 64*1024))
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+		assert.Equal(t, http.StatusRequestHeaderFieldsTooLarge, resp.StatusCode)
+	})
+}
+
+func TestDisableKeepalive(t *testing.T) {
+	logger := createTestLogger()
+	mux := newServeMux(okHandler, "test-service", newDrainer("test-service", 0, logger), logger)
+
+	// rawHeaders returns the response head as sent, since http.Client folds Connection into resp.Close
+	rawHeaders := func(t *testing.T) string {
+		t.Helper()
+		server := httptest.NewUnstartedServer(nil)
+		server.Config = newHTTPServer("", mux)
+		server.Start()
+		t.Cleanup(server.Close)
+
+		conn, err := net.Dial("tcp", server.Listener.Addr().String())
+		require.NoError(t, err)
+		defer func() { _ = conn.Close() }()
+
+		_, err = fmt.Fprint(conn, "GET /health HTTP/1.1\r\nHost: test\r\n\r\n")
+		require.NoError(t, err)
+		var raw bytes.Buffer
+		resp, err := http.ReadResponse(bufio.NewReader(io.TeeReader(conn, &raw)), nil)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		head, _, _ := strings.Cut(raw.String(), "\r\n\r\n")
+		return head
+	}
+
+	t.Run("connections are kept alive by default", func(t *testing.T) {
+		assert.NotContains(t, rawHeaders(t), "Connection: close")
+	})
+
+	t.Run("disabled keepalive closes each connection", func(t *testing.T) {
+		disableKeepalive = true
+		t.Cleanup(func() { disableKeepalive = false })
+
+		assert.Contains(t, rawHeaders(t), "Connection: close")
+	})
+}
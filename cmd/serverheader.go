@@ -0,0 +1,45 @@
+package cmd
+
+import "net/http"
+
+// serverHeaderMiddleware makes every response carry the given Server header, replacing any
+// propagated from an upstream hop. An empty value removes the header instead.
+func serverHeaderMiddleware(value string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&serverHeaderWriter{ResponseWriter: w, value: value}, r)
+	})
+}
+
+// serverHeaderWriter applies the Server header as the response status is written, after the
+// handler has finished setting its own headers
+type serverHeaderWriter struct {
+	http.ResponseWriter
+	value       string
+	wroteHeader bool
+}
+
+// WriteHeader applies the Server header to every status written until the final one. An
+// informational 1xx status such as 100 Continue may precede it, so it does not count as final.
+func (s *serverHeaderWriter) WriteHeader(statusCode int) {
+	if !s.wroteHeader {
+		s.wroteHeader = statusCode >= http.StatusOK
+		if s.value == "" {
+			s.Header().Del("Server")
+		} else {
+			s.Header().Set("Server", s.value)
+		}
+	}
+	s.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (s *serverHeaderWriter) Write(b []byte) (int, error) {
+	if !s.wroteHeader {
+		s.WriteHeader(http.StatusOK)
+	}
+	return s.ResponseWriter.Write(b)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (s *serverHeaderWriter) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
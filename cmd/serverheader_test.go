@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerHeaderMiddleware(t *testing.T) {
+	// upstream mimics the proxy handler forwarding a Server header from the next hop
+	upstream := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Server", "upstream/1.0")
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	tests := []struct {
+		name    string
+		value   string
+		handler http.Handler
+		want    []string
+	}{
+		{name: "sets header", value: "edge/2.0", handler: okHandler, want: []string{"edge/2.0"}},
+		{name: "replaces propagated header", value: "edge/2.0", handler: upstream, want: []string{"edge/2.0"}},
+		{name: "empty suppresses header", value: "", handler: upstream, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			serverHeaderMiddleware(tt.value, tt.handler).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, tt.want, rr.Header().Values("Server"))
+		})
+	}
+
+	t.Run("replaces header set after 100 Continue", func(t *testing.T) {
+		// continued sends 100 Continue before the upstream's response arrives
+		continued := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusContinue)
+			upstream.ServeHTTP(w, r)
+		})
+		server := httptest.NewServer(serverHeaderMiddleware("edge/2.0", continued))
+		defer server.Close()
+
+		resp, err := http.Get(server.URL)
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, []string{"edge/2.0"}, resp.Header.Values("Server"))
+	})
+
+	t.Run("applies to health endpoint", func(t *testing.T) {
+		logger := createTestLogger()
+		mux := newServeMux(okHandler, "test-service", newDrainer("test-service", 0, logger), logger)
+
+		rr := httptest.NewRecorder()
+		serverHeaderMiddleware("edge/2.0", mux).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/health", nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "edge/2.0", rr.Header().Get("Server"))
+	})
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// parseCertFor parses a --tls-cert-for value of the form "host=cert.pem,key.pem"
+func parseCertFor(spec string) (host, certFile, keyFile string, err error) {
+	host, files, ok := strings.Cut(spec, "=")
+	host = strings.ToLower(strings.TrimSpace(host))
+	if !ok || host == "" {
+		return "", "", "", fmt.Errorf("invalid --tls-cert-for %q: must be <host>=<cert>,<key>", spec)
+	}
+	certFile, keyFile, ok = strings.Cut(files, ",")
+	certFile, keyFile = strings.TrimSpace(certFile), strings.TrimSpace(keyFile)
+	if !ok || certFile == "" || keyFile == "" {
+		return "", "", "", fmt.Errorf("invalid --tls-cert-for %q: must be <host>=<cert>,<key>", spec)
+	}
+	return host, certFile, keyFile, nil
+}
+
+// loadSNICertificates loads the certificate for every --tls-cert-for value, keyed by lowercased hostname
+func loadSNICertificates(specs []string) (map[string]*tls.Certificate, error) {
+	certs := make(map[string]*tls.Certificate, len(specs))
+	for _, spec := range specs {
+		host, certFile, keyFile, err := parseCertFor(spec)
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := certs[host]; dup {
+			return nil, fmt.Errorf("invalid --tls-cert-for %q: %s already has a certificate", spec, host)
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS certificate/key pair for %s: %w", host, err)
+		}
+		certs[host] = &cert
+	}
+	return certs, nil
+}
+
+// newTLSConfig builds the server TLS configuration from --tls-cert/--tls-key and any --tls-cert-for
+// pairs. The certificate is chosen by the SNI hostname, falling back to the default certificate
+// for clients that send no server name or one without its own certificate. Completed handshakes
+// are logged at debug level.
+func newTLSConfig(logger *slog.Logger) (*tls.Config, error) {
+	defaultCert, err := tls.LoadX509KeyPair(tlsCertFile, tlsKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate/key pair: %w", err)
+	}
+	sniCerts, err := loadSNICertificates(tlsCertsFor)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			if cert, ok := sniCerts[strings.ToLower(hello.ServerName)]; ok {
+				return cert, nil
+			}
+			return &defaultCert, nil
+		},
+		VerifyConnection: logHandshake(logger),
+	}, nil
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCertFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		wantHost string
+		wantCert string
+		wantKey  string
+		wantErr  bool
+	}{
+		{name: "valid", spec: "a.example.com=a.pem,a-key.pem", wantHost: "a.example.com", wantCert: "a.pem", wantKey: "a-key.pem"},
+		{name: "host is lowercased", spec: "A.Example.com=a.pem,a-key.pem", wantHost: "a.example.com", wantCert: "a.pem", wantKey: "a-key.pem"},
+		{name: "missing host", spec: "=a.pem,a-key.pem", wantErr: true},
+		{name: "missing key", spec: "a.example.com=a.pem", wantErr: true},
+		{name: "empty key", spec: "a.example.com=a.pem,", wantErr: true},
+		{name: "missing separator", spec: "a.pem,a-key.pem", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, cert, key, err := parseCertFor(tt.spec)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantHost, host)
+			assert.Equal(t, tt.wantCert, cert)
+			assert.Equal(t, tt.wantKey, key)
+		})
+	}
+}
+
+func TestSNICertificates(t *testing.T) {
+	defaultCert, defaultKey := generateTestCertificates(t)
+	tenantCert, tenantKey := generateTestCertificates(t)
+	tlsCertFile, tlsKeyFile = defaultCert, defaultKey
+	tlsCertsFor = []string{"tenant.example.com=" + tenantCert + "," + tenantKey}
+	t.Cleanup(func() { tlsCertFile, tlsKeyFile, tlsCertsFor = "", "", nil })
+
+	logger := createTestLogger()
+	mux := newServeMux(okHandler, "test-service", newDrainer("test-service", 0, logger), logger)
+	listeners, err := bindListeners([]listenSpec{{addr: "127.0.0.1:0", tls: true}})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- serveListeners(ctx, listeners, mux, logger) }()
+	addr := listeners[0].listener.Addr().String()
+
+	// presented returns the leaf certificate the server offers for the given SNI hostname
+	presented := func(t *testing.T, serverName string) []byte {
+		var conn *tls.Conn
+		require.Eventually(t, func() bool {
+			conn, err = tls.Dial("tcp", addr, &tls.Config{
+				ServerName:         serverName,
+				InsecureSkipVerify: true, // #nosec G402 -- self-signed test certificates
+			})
+			return err == nil
+		}, 5*time.Second, 10*time.Millisecond)
+		defer func() { _ = conn.Close() }()
+		return conn.ConnectionState().PeerCertificates[0].Raw
+	}
+	leaf := func(t *testing.T, certFile, keyFile string) []byte {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		require.NoError(t, err)
+		return cert.Certificate[0]
+	}
+
+	tests := []struct {
+		name       string
+		serverName string
+		want       []byte
+	}{
+		{name: "matching hostname", serverName: "tenant.example.com", want: leaf(t, tenantCert, tenantKey)},
+		{name: "hostname matches case-insensitively", serverName: "Tenant.Example.com", want: leaf(t, tenantCert, tenantKey)},
+		{name: "unknown hostname falls back", serverName: "other.example.com", want: leaf(t, defaultCert, defaultKey)},
+		{name: "no SNI falls back", serverName: "", want: leaf(t, defaultCert, defaultKey)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.True(t, bytes.Equal(tt.want, presented(t, tt.serverName)))
+		})
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("servers did not shut down")
+	}
+}
+
+func TestValidateFlagsCertFor(t *testing.T) {
+	certPath, keyPath := generateTestCertificates(t)
+	resetFlags := func() {
+		port = 8080
+		timeout = 30 * time.Second
+		serviceName = "proxy"
+		logLevel = "info"
+		logFormat = "json"
+		tlsCertFile = certPath
+		tlsKeyFile = keyPath
+		tlsCertsFor = nil
+		upstreamCACerts = nil
+	}
+	t.Cleanup(func() { tlsCertFile, tlsKeyFile, tlsCertsFor = "", "", nil })
+
+	t.Run("valid", func(t *testing.T) {
+		resetFlags()
+		tlsCertsFor = []string{"a.example.com=" + certPath + "," + keyPath}
+		assert.NoError(t, validateFlags(nil, nil))
+	})
+
+	t.Run("requires a default certificate", func(t *testing.T) {
+		resetFlags()
+		tlsCertFile, tlsKeyFile = "", ""
+		tlsCertsFor = []string{"a.example.com=" + certPath + "," + keyPath}
+		assert.Error(t, validateFlags(nil, nil))
+	})
+
+	t.Run("unloadable certificate", func(t *testing.T) {
+		resetFlags()
+		tlsCertsFor = []string{"a.example.com=/nonexistent/cert.pem,/nonexistent/key.pem"}
+		assert.Error(t, validateFlags(nil, nil))
+	})
+
+	t.Run("duplicate hostname", func(t *testing.T) {
+		resetFlags()
+		tlsCertsFor = []string{"a.example.com=" + certPath + "," + keyPath, "A.example.com=" + certPath + "," + keyPath}
+		assert.Error(t, validateFlags(nil, nil))
+	})
+}
@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// staticPrefix is the route static files are served under
+const staticPrefix = "/static/"
+
+// newStaticHandler serves files from dir under staticPrefix. The directory is opened as an
+// os.Root so neither ".." segments nor symlinks can reach files outside it.
+func newStaticHandler(dir string) (http.Handler, error) {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, fmt.Errorf("opening static directory %q: %w", dir, err)
+	}
+	return http.StripPrefix(staticPrefix, http.FileServerFS(root.FS())), nil
+}
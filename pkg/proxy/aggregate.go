@@ -0,0 +1,66 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// aggregateParam is the query parameter that asks every hop to wrap the response from the hop
+// after it, so the client receives one nested JSON object for the whole chain
+const aggregateParam = "aggregate"
+
+// aggregateResponse wraps the response a hop received from the next hop
+type aggregateResponse struct {
+	Service    string          `json:"service"`
+	Status     int             `json:"status"`
+	Downstream json.RawMessage `json:"downstream"`
+}
+
+// aggregateRequested reports whether the request asked for an aggregated chain response
+func aggregateRequested(r *http.Request) bool {
+	return r.URL.Query().Get(aggregateParam) == "true"
+}
+
+// withAggregate carries the aggregate parameter onto an upstream URL when the request asked for it
+func withAggregate(r *http.Request, url string) string {
+	if !aggregateRequested(r) {
+		return url
+	}
+	separator := "?"
+	if strings.Contains(url, "?") {
+		separator = "&"
+	}
+	return url + separator + aggregateParam + "=true"
+}
+
+// forwardAggregatedResponse wraps a JSON response from the next hop in an object naming this
+// service before forwarding it. Responses that are not JSON are forwarded as-is.
+func (h *Handler) forwardAggregatedResponse(w http.ResponseWriter, resp *http.Response, logger *slog.Logger) error {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logger.Error("Failed to read response body", slog.String("error", err.Error()))
+		return err
+	}
+
+	if isJSONContentType(resp.Header.Get("Content-Type")) && json.Valid(body) {
+		body, err = json.Marshal(aggregateResponse{Service: h.serviceName, Status: resp.StatusCode, Downstream: body})
+		if err != nil {
+			return err
+		}
+		resp.Header.Set("Content-Type", mediaTypeJSON)
+		if !h.propagateResponseHeaders {
+			w.Header().Set("Content-Type", mediaTypeJSON)
+		}
+	} else {
+		logger.Debug("Not aggregating non-JSON response", slog.String("content_type", resp.Header.Get("Content-Type")))
+	}
+
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return h.forwardResponse(w, resp, logger)
+}
@@ -0,0 +1,103 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregate(t *testing.T) {
+	logger := createTestLogger()
+
+	newService := func(t *testing.T, name string, opts ...HandlerOption) string {
+		t.Helper()
+		h, err := NewHandler(time.Second, name, logger, opts...)
+		require.NoError(t, err)
+		server := httptest.NewServer(h)
+		t.Cleanup(server.Close)
+		return strings.TrimPrefix(server.URL, "http://")
+	}
+
+	get := func(t *testing.T, url string) (*http.Response, []byte) {
+		t.Helper()
+		resp, err := http.Get(url)
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, body
+	}
+
+	t.Run("nests every hop in order", func(t *testing.T) {
+		svcc := newService(t, "svcc")
+		svcb := newService(t, "svcb")
+		svca := newService(t, "svca")
+
+		resp, body := get(t, "http://"+svca+"/proxy/"+svcb+"/proxy/"+svcc+"?aggregate=true")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, mediaTypeJSON, resp.Header.Get("Content-Type"))
+
+		var outer map[string]any
+		require.NoError(t, json.Unmarshal(body, &outer))
+		assert.Equal(t, "svca", outer["service"])
+		assert.Equal(t, float64(200), outer["status"])
+		middle, ok := outer["downstream"].(map[string]any)
+		require.True(t, ok, "svca should wrap svcb's response: %s", body)
+		assert.Equal(t, "svcb", middle["service"])
+		inner, ok := middle["downstream"].(map[string]any)
+		require.True(t, ok, "svcb should wrap svcc's response: %s", body)
+		assert.Equal(t, "svcc", inner["service"])
+		assert.NotContains(t, inner, "downstream")
+	})
+
+	t.Run("records the status each hop received", func(t *testing.T) {
+		svcb := newService(t, "svcb")
+		svca := newService(t, "svca", WithStatusRemap(map[int]int{503: 500}))
+
+		resp, body := get(t, "http://"+svca+"/proxy/"+svcb+"/fault/503?aggregate=true")
+		require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+		var outer aggregateResponse
+		require.NoError(t, json.Unmarshal(body, &outer))
+		assert.Equal(t, "svca", outer.Service)
+		assert.Equal(t, http.StatusServiceUnavailable, outer.Status)
+	})
+
+	t.Run("keeps the length accurate when propagating response headers", func(t *testing.T) {
+		svcb := newService(t, "svcb")
+		svca := newService(t, "svca", WithPropagateResponseHeaders(true))
+
+		resp, body := get(t, "http://"+svca+"/proxy/"+svcb+"?aggregate=true")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, int64(len(body)), resp.ContentLength)
+		assert.Equal(t, []string{mediaTypeJSON}, resp.Header.Values("Content-Type"))
+		assert.True(t, json.Valid(body))
+	})
+
+	t.Run("forwards non-JSON responses as-is", func(t *testing.T) {
+		svcb := newService(t, "svcb")
+		svca := newService(t, "svca")
+
+		resp, body := get(t, "http://"+svca+"/proxy/"+svcb+"/bytes/64?aggregate=true")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Len(t, body, 64)
+	})
+
+	t.Run("absent unless requested", func(t *testing.T) {
+		svcb := newService(t, "svcb")
+		svca := newService(t, "svca")
+
+		_, body := get(t, "http://"+svca+"/proxy/"+svcb)
+		var resp map[string]any
+		require.NoError(t, json.Unmarshal(body, &resp))
+		assert.Equal(t, "svcb", resp["service"])
+		assert.NotContains(t, resp, "downstream")
+	})
+}
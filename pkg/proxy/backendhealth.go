@@ -0,0 +1,203 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultHealthProbeInterval is how often an unhealthy backend is probed for recovery
+const defaultHealthProbeInterval = time.Second
+
+// backendStateTTL is how long a backend no request has named stays tracked. /try/ targets come
+// from clients, so forgotten ones are dropped, stopping their probe, to keep the map bounded.
+const backendStateTTL = 5 * time.Minute
+
+// maxTrackedBackends caps how many backends are tracked at once; beyond it, new backends go
+// untracked until older ones expire
+const maxTrackedBackends = 1000
+
+// WithPassiveHealthThreshold enables passive health tracking of /try/ targets: a target failing
+// this many consecutive attempts (connection errors or 5xx) is skipped until a probe of its
+// /health endpoint succeeds. 0 disables tracking.
+func WithPassiveHealthThreshold(threshold int) HandlerOption {
+	return func(h *Handler) {
+		if threshold > 0 {
+			h.backendHealth = newBackendHealth(threshold, defaultHealthProbeInterval)
+		}
+	}
+}
+
+// backendHealth tracks consecutive failures per backend and which backends are unhealthy
+type backendHealth struct {
+	threshold     int
+	probeInterval time.Duration
+	ttl           time.Duration
+	now           func() time.Time
+
+	mu       sync.Mutex
+	backends map[string]*backendState
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// backendState is the passive health of a single backend
+type backendState struct {
+	failures  int
+	unhealthy bool
+	lastSeen  time.Time
+}
+
+func newBackendHealth(threshold int, probeInterval time.Duration) *backendHealth {
+	return &backendHealth{
+		threshold:     threshold,
+		probeInterval: probeInterval,
+		ttl:           backendStateTTL,
+		now:           time.Now,
+		backends:      make(map[string]*backendState),
+		stop:          make(chan struct{}),
+	}
+}
+
+// state returns the state of a backend, creating it on first use and evicting backends not seen
+// within the TTL to make room. Once maxTrackedBackends are tracked, a new backend gets a state
+// that is not kept. The caller must hold mu.
+func (b *backendHealth) state(target string) *backendState {
+	now := b.now()
+	s, ok := b.backends[target]
+	if !ok {
+		for k, existing := range b.backends {
+			if now.Sub(existing.lastSeen) > b.ttl {
+				delete(b.backends, k)
+			}
+		}
+		s = &backendState{}
+		if len(b.backends) < maxTrackedBackends {
+			b.backends[target] = s
+		}
+	}
+	s.lastSeen = now
+	return s
+}
+
+// tracked reports whether s is still the tracked state of target, so a probe can stop once its
+// backend has been forgotten
+func (b *backendHealth) tracked(target string, s *backendState) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.backends[target] == s
+}
+
+// healthy returns the indices in order whose targets are healthy. When every target is unhealthy
+// the full order is returned, since trying a suspect backend beats failing outright.
+func (b *backendHealth) healthy(targets []string, order []int) []int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	healthy := make([]int, 0, len(order))
+	for _, idx := range order {
+		s, ok := b.backends[targets[idx]]
+		if ok {
+			s.lastSeen = now
+		}
+		if !ok || !s.unhealthy {
+			healthy = append(healthy, idx)
+		}
+	}
+	if len(healthy) == 0 {
+		return order
+	}
+	return healthy
+}
+
+// recordSuccess resets a backend's consecutive failure count
+func (b *backendHealth) recordSuccess(target string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state(target).failures = 0
+}
+
+// recordFailure counts a failed attempt, returning the backend's state if it just made the
+// backend unhealthy, or nil otherwise
+func (b *backendHealth) recordFailure(target string) *backendState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := b.state(target)
+	s.failures++
+	if s.unhealthy || s.failures < b.threshold {
+		return nil
+	}
+	s.unhealthy = true
+	return s
+}
+
+// recover marks a backend healthy again
+func (b *backendHealth) recover(s *backendState) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s.unhealthy = false
+	s.failures = 0
+}
+
+// close stops any running recovery probes
+func (b *backendHealth) close() {
+	b.stopOnce.Do(func() { close(b.stop) })
+}
+
+// observeBackend records the outcome of an attempt against a /try/ target, starting a recovery
+// probe when the failure makes the target unhealthy
+func (h *Handler) observeBackend(target string, failed bool, logger *slog.Logger) {
+	if h.backendHealth == nil {
+		return
+	}
+	if !failed {
+		h.backendHealth.recordSuccess(target)
+		return
+	}
+	if s := h.backendHealth.recordFailure(target); s != nil {
+		logger.Warn("Backend marked unhealthy", slog.String("target", target), slog.Int("consecutive_failures", h.backendHealth.threshold))
+		go h.probeBackend(target, s)
+	}
+}
+
+// probeBackend polls the target's /health endpoint until it answers 2xx, then marks it healthy.
+// It stops early when the handler is closed or the backend is no longer tracked.
+func (h *Handler) probeBackend(target string, s *backendState) {
+	scheme, host := parseScheme(target)
+	url := fmt.Sprintf("%s://%s/health", scheme, host)
+
+	ticker := time.NewTicker(h.backendHealth.probeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-h.backendHealth.stop:
+			return
+		case <-ticker.C:
+			if !h.backendHealth.tracked(target, s) {
+				h.logger.Debug("Stopped probing forgotten backend", slog.String("target", target))
+				return
+			}
+			resp, err := h.client.Get(url)
+			if err != nil {
+				h.logger.Debug("Backend health probe failed", slog.String("target", target), slog.String("error", err.Error()))
+				continue
+			}
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+
+			if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+				h.backendHealth.recover(s)
+				h.logger.Info("Backend recovered", slog.String("target", target))
+				return
+			}
+			h.logger.Debug("Backend health probe failed", slog.String("target", target), slog.Int("status_code", resp.StatusCode))
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPassiveHealth(t *testing.T) {
+	// backend counts proxied requests and fails everything, /health included, while failing is set
+	type backend struct {
+		addr    string
+		hits    atomic.Int32
+		failing atomic.Bool
+	}
+	newBackend := func(t *testing.T) *backend {
+		t.Helper()
+		b := &backend{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/health" {
+				b.hits.Add(1)
+			}
+			if b.failing.Load() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		t.Cleanup(server.Close)
+		b.addr = strings.TrimPrefix(server.URL, "http://")
+		return b
+	}
+
+	a, b := newBackend(t), newBackend(t)
+
+	handler, err := NewHandler(30*time.Second, "test-service", createTestLogger(), WithPassiveHealthThreshold(2))
+	require.NoError(t, err)
+	handler.backendHealth.probeInterval = 10 * time.Millisecond
+	t.Cleanup(func() { _ = handler.Close() })
+
+	try := func() int {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/try/"+a.addr+","+b.addr, nil))
+		return rr.Code
+	}
+
+	a.failing.Store(true)
+
+	// Requests succeed throughout, falling through a until it is marked unhealthy
+	for a.hits.Load() < 2 {
+		require.Equal(t, http.StatusOK, try())
+	}
+
+	// Once unhealthy, a is skipped entirely and all traffic goes to b
+	a.hits.Store(0)
+	b.hits.Store(0)
+	for range 20 {
+		require.Equal(t, http.StatusOK, try())
+	}
+	assert.Zero(t, a.hits.Load(), "unhealthy backend should receive no traffic")
+	assert.Equal(t, int32(20), b.hits.Load())
+
+	// A successful probe brings a back into rotation
+	a.failing.Store(false)
+	require.Eventually(t, func() bool {
+		handler.backendHealth.mu.Lock()
+		defer handler.backendHealth.mu.Unlock()
+		return !handler.backendHealth.backends[a.addr].unhealthy
+	}, time.Second, 10*time.Millisecond)
+
+	for range 20 {
+		require.Equal(t, http.StatusOK, try())
+	}
+	assert.NotZero(t, a.hits.Load(), "recovered backend should receive traffic again")
+}
+
+func TestBackendHealthAllUnhealthy(t *testing.T) {
+	health := newBackendHealth(1, time.Hour)
+	targets := []string{"a:8080", "b:8080"}
+
+	assert.NotNil(t, health.recordFailure("a:8080"))
+	assert.Nil(t, health.recordFailure("a:8080"), "already unhealthy")
+	assert.Equal(t, []int{1}, health.healthy(targets, []int{0, 1}))
+
+	assert.NotNil(t, health.recordFailure("b:8080"))
+	assert.Equal(t, []int{1, 0}, health.healthy(targets, []int{1, 0}), "every target unhealthy falls back to all")
+}
+
+func TestBackendHealthForgetsIdleBackends(t *testing.T) {
+	now := time.Now()
+	health := newBackendHealth(1, time.Hour)
+	health.now = func() time.Time { return now }
+
+	unhealthy := health.recordFailure("a:8080")
+	require.NotNil(t, unhealthy)
+	health.recordSuccess("b:8080")
+	assert.True(t, health.tracked("a:8080", unhealthy))
+
+	// Backends still named by requests are kept; the rest are dropped once a new one arrives
+	now = now.Add(backendStateTTL / 2)
+	health.healthy([]string{"b:8080"}, []int{0})
+	now = now.Add(backendStateTTL/2 + time.Second)
+	health.recordSuccess("c:8080")
+
+	assert.False(t, health.tracked("a:8080", unhealthy), "an idle unhealthy backend is forgotten, stopping its probe")
+	assert.Len(t, health.backends, 2)
+	assert.Contains(t, health.backends, "b:8080")
+	assert.Contains(t, health.backends, "c:8080")
+}
+
+func TestBackendHealthCap(t *testing.T) {
+	health := newBackendHealth(1, time.Hour)
+	for i := range maxTrackedBackends + 10 {
+		health.recordSuccess(fmt.Sprintf("backend-%d:8080", i))
+	}
+	assert.Len(t, health.backends, maxTrackedBackends, "backends beyond the cap are not tracked")
+}
@@ -0,0 +1,92 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// errBodyReadTimeout is returned by request body reads once --body-read-timeout has passed
+var errBodyReadTimeout = errors.New("request body not received within the body read timeout")
+
+// WithBodyReadTimeout limits how long reading the request body may take, independently of the
+// request timeout, so slow-body (slowloris-style) clients get 408. Zero disables the limit.
+func WithBodyReadTimeout(d time.Duration) HandlerOption {
+	return func(h *Handler) {
+		h.bodyReadTimeout = d
+	}
+}
+
+// deadlineBody fails reads with errBodyReadTimeout once its deadline has passed. The connection's
+// read deadline is set to match so a read blocked on a stalled client is interrupted too, and is
+// cleared once the body has been read in full so it cannot cut the request short afterwards.
+// timedOut records the failure itself, since http.Transport does not always keep errBodyReadTimeout
+// in the error it returns when a body read fails.
+type deadlineBody struct {
+	io.ReadCloser
+	deadline time.Time
+	rc       *http.ResponseController
+	timedOut atomic.Bool
+}
+
+// limitBodyRead wraps r's body so it must be read within the body read timeout
+func (h *Handler) limitBodyRead(w http.ResponseWriter, r *http.Request) {
+	if h.bodyReadTimeout <= 0 || r.Body == nil || r.Body == http.NoBody {
+		return
+	}
+
+	body := &deadlineBody{ReadCloser: r.Body, deadline: time.Now().Add(h.bodyReadTimeout)}
+	// Writers that cannot set deadlines, such as test recorders, rely on the check between reads
+	rc := http.NewResponseController(w)
+	if err := rc.SetReadDeadline(body.deadline); err == nil {
+		body.rc = rc
+	}
+	r.Body = body
+}
+
+func (b *deadlineBody) Read(p []byte) (int, error) {
+	if !time.Now().Before(b.deadline) {
+		b.timedOut.Store(true)
+		return 0, errBodyReadTimeout
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	switch {
+	case err == io.EOF:
+		b.clearDeadline()
+	case err != nil && !time.Now().Before(b.deadline):
+		b.timedOut.Store(true)
+		return n, errBodyReadTimeout
+	}
+	return n, err
+}
+
+// bodyReadTimedOut reports whether reading r's body failed because the body read timeout ran out
+func bodyReadTimedOut(r *http.Request) bool {
+	body, ok := r.Body.(*deadlineBody)
+	return ok && body.timedOut.Load()
+}
+
+// clearDeadline removes the connection read deadline set for the body
+func (b *deadlineBody) clearDeadline() {
+	if b.rc != nil {
+		_ = b.rc.SetReadDeadline(time.Time{})
+		b.rc = nil
+	}
+}
+
+// writeBodyReadError answers a failure to read the request body: 408 when the body read timeout
+// ran out, 400 otherwise
+func (h *Handler) writeBodyReadError(w http.ResponseWriter, r *http.Request, err error, logger *slog.Logger) {
+	if errors.Is(err, errBodyReadTimeout) {
+		logger.Info("Request body read timed out", slog.Duration("body_read_timeout", h.bodyReadTimeout))
+		h.writeError(w, r, http.StatusRequestTimeout, ErrCodeRequestTimeout, fmt.Sprintf("Request body not received within %s", h.bodyReadTimeout), logger)
+		return
+	}
+	logger.Error("Failed to read request body", slog.String("error", err.Error()))
+	h.writeError(w, r, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("Failed to read request body: %v", err), logger)
+}
@@ -0,0 +1,98 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowBody sends first, then stalls for stall (or until release is closed, when set) before
+// sending the rest
+type slowBody struct {
+	first, rest string
+	stall       time.Duration
+	release     <-chan struct{}
+	sent        int
+}
+
+func (s *slowBody) Read(p []byte) (int, error) {
+	switch s.sent {
+	case 0:
+		s.sent++
+		return copy(p, s.first), nil
+	case 1:
+		s.sent++
+		if s.release != nil {
+			<-s.release
+		} else {
+			time.Sleep(s.stall)
+		}
+		return copy(p, s.rest), nil
+	default:
+		return 0, io.EOF
+	}
+}
+
+func TestBodyReadTimeout(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// Answer slowly so the request outlasts the body read timeout
+		time.Sleep(300 * time.Millisecond)
+		_, _ = w.Write(body)
+	}))
+	defer upstream.Close()
+	target := strings.TrimPrefix(upstream.URL, "http://")
+
+	h, err := NewHandler(5*time.Second, "test-service", createTestLogger(), WithBodyReadTimeout(200*time.Millisecond))
+	require.NoError(t, err)
+	proxy := httptest.NewServer(h)
+	defer proxy.Close()
+
+	post := func(t *testing.T, body io.Reader) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, proxy.URL+"/proxy/"+target, body)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return resp
+	}
+
+	t.Run("slow body gets 408", func(t *testing.T) {
+		// The rest of the body is only sent once the test is over, so only the timeout can end the read
+		release := make(chan struct{})
+		defer close(release)
+		resp := post(t, &slowBody{first: `{"part":`, rest: `1}`, release: release})
+		assert.Equal(t, http.StatusRequestTimeout, resp.StatusCode)
+
+		var body ErrorResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, ErrCodeRequestTimeout, body.Code)
+	})
+
+	t.Run("prompt body is not limited afterwards", func(t *testing.T) {
+		resp := post(t, strings.NewReader(`{"part":1}`))
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		got, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, `{"part":1}`, string(got))
+	})
+
+	t.Run("enforced without connection deadlines", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/proxy/"+target, &slowBody{first: "a", rest: "b", stall: 300 * time.Millisecond})
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusRequestTimeout, rr.Code)
+	})
+}
@@ -0,0 +1,67 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log/slog"
+	mathrand "math/rand"
+	"net/http"
+	"strconv"
+)
+
+// DefaultMaxPayloadBytes is the default cap on generated response bodies
+const DefaultMaxPayloadBytes = 10 << 20
+
+// WithMaxPayloadBytes caps the size of bodies generated by directives such as /bytes, and of
+// request bodies decompressed by WithDecompressRequests
+func WithMaxPayloadBytes(n int64) HandlerOption {
+	return func(h *Handler) {
+		h.maxPayloadBytes = n
+	}
+}
+
+// sendBytes answers with n random bytes, reproducible across requests when a ?seed= is given.
+// Per-KiB latency is bounded by ctx's deadline.
+func (h *Handler) sendBytes(ctx context.Context, w http.ResponseWriter, r *http.Request, n int64, logger *slog.Logger) {
+	if n > h.maxPayloadBytes {
+		logger.Info("Requested payload too large", slog.Int64("bytes", n), slog.Int64("max_payload_bytes", h.maxPayloadBytes))
+		h.writeError(w, r, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("Requested %d bytes exceeds the maximum of %d", n, h.maxPayloadBytes), logger)
+		return
+	}
+
+	var source io.Reader = rand.Reader
+	if seed := r.URL.Query().Get("seed"); seed != "" {
+		hash := fnv.New64a()
+		_, _ = hash.Write([]byte(seed))
+		source = mathrand.New(mathrand.NewSource(int64(hash.Sum64()))) // #nosec G404 G115 -- deterministic test data, not secrets
+	}
+
+	if err := h.waitForSize(ctx, n, logger); err != nil {
+		h.writeSizeWaitError(w, r, err, logger)
+		return
+	}
+
+	// A checksum header must be sent before the body, so generate the body up front
+	if h.checksum != "" {
+		body := make([]byte, n)
+		if _, err := io.ReadFull(source, body); err != nil {
+			logger.Error("Failed to generate random bytes", slog.String("error", err.Error()))
+			h.writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, fmt.Sprintf("Failed to generate random bytes: %v", err), logger)
+			return
+		}
+		h.setChecksum(w, body)
+		source = bytes.NewReader(body)
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(n, 10))
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.CopyN(w, source, n); err != nil {
+		logger.Error("Failed to write random bytes", slog.String("error", err.Error()))
+	}
+}
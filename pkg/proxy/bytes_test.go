@@ -0,0 +1,57 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBytesDirective(t *testing.T) {
+	handler, err := NewHandler(30*time.Second, "test-service", createTestLogger(), WithMaxPayloadBytes(1<<20))
+	require.NoError(t, err)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr
+	}
+
+	t.Run("returns exactly n bytes", func(t *testing.T) {
+		for _, n := range []int{0, 1, 4096, 100000} {
+			rr := get("/bytes/" + strconv.Itoa(n))
+			require.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, "application/octet-stream", rr.Header().Get("Content-Type"))
+			assert.Equal(t, strconv.Itoa(n), rr.Header().Get("Content-Length"))
+			assert.Len(t, rr.Body.Bytes(), n)
+		}
+	})
+
+	t.Run("seed makes content deterministic", func(t *testing.T) {
+		first := get("/bytes/256?seed=fixture").Body.Bytes()
+		second := get("/bytes/256?seed=fixture").Body.Bytes()
+		other := get("/bytes/256?seed=other").Body.Bytes()
+
+		assert.Equal(t, first, second)
+		assert.NotEqual(t, first, other)
+	})
+
+	t.Run("unseeded content differs", func(t *testing.T) {
+		assert.NotEqual(t, get("/bytes/256").Body.Bytes(), get("/bytes/256").Body.Bytes())
+	})
+
+	t.Run("size above the cap is rejected", func(t *testing.T) {
+		rr := get("/bytes/" + strconv.Itoa(1<<20+1))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("applies after a delay", func(t *testing.T) {
+		rr := get("/delay/1/bytes/10")
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Len(t, rr.Body.Bytes(), 10)
+	})
+}
@@ -0,0 +1,54 @@
+package proxy
+
+import "fmt"
+
+// local reports whether a is applied by this hop before the request is answered or forwarded:
+// delays, faults, status sequences, flaky failures, response overrides and repeats
+func (a actions) local() bool {
+	return a.IsDelay || a.IsFault || a.ContentType != "" || a.TrailerName != "" || a.Cookie != nil || len(a.SeqStatuses) > 0 || a.FlakyEvery > 0 || a.RepeatCount > 0
+}
+
+// forwards reports whether a ends the hop by passing its Remaining path on to other services
+func (a actions) forwards() bool {
+	return a.NextHop != "" || len(a.FanoutTargets) > 0 || len(a.TryTargets) > 0
+}
+
+// parseHop parses the directives one hop applies, in the order they are applied: the local
+// directives in path order, then the step that ends the hop. That is a /proxy/, /fanout/ or /try/
+// whose Remaining path belongs to the services it forwards to, a terminal directive such as
+// /bytes/, or the final response once the path runs out. The whole hop is validated before any of
+// it runs; on error the steps parsed before the failing directive are returned with it. A /fanout/
+// or /try/ naming more than maxTargets targets is an error; a maxTargets of 0 allows any number.
+func parseHop(path string, maxTargets int) ([]actions, error) {
+	var steps []actions
+	for {
+		a, err := parsePath(path)
+		if err != nil {
+			return steps, err
+		}
+		steps = append(steps, a)
+		if !a.local() {
+			if a.NextHop == "" && repeats(steps) {
+				return steps, fmt.Errorf("invalid repeat path: /repeat/<n> must be followed by a /proxy/ hop")
+			}
+			if maxTargets > 0 && len(a.FanoutTargets) > maxTargets {
+				return steps, fmt.Errorf("fanout to %d targets exceeds the maximum of %d", len(a.FanoutTargets), maxTargets)
+			}
+			if maxTargets > 0 && len(a.TryTargets) > maxTargets {
+				return steps, fmt.Errorf("try of %d targets exceeds the maximum of %d", len(a.TryTargets), maxTargets)
+			}
+			return steps, nil
+		}
+		path = a.Remaining
+	}
+}
+
+// repeats reports whether any of a hop's steps is a /repeat/ directive
+func repeats(steps []actions) bool {
+	for _, step := range steps {
+		if step.RepeatCount > 0 {
+			return true
+		}
+	}
+	return false
+}
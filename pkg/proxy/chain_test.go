@@ -0,0 +1,166 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHop(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		want    []actions
+		wantErr bool
+	}{
+		{
+			name: "delay then fault then proxy",
+			path: "/delay/100/fault/500/proxy/svcb:8080/fault/503",
+			want: []actions{
+				{Remaining: "/fault/500/proxy/svcb:8080/fault/503", IsDelay: true, Delay: 100 * time.Millisecond},
+				{Remaining: "/proxy/svcb:8080/fault/503", IsFault: true, FaultCode: 500, FaultPercentage: 100},
+				{NextHop: "svcb:8080", Remaining: "/fault/503", Scheme: "http"},
+			},
+		},
+		{
+			name: "order is kept as written",
+			path: "/fault/500/10/delay/100",
+			want: []actions{
+				{Remaining: "/delay/100", IsFault: true, FaultCode: 500, FaultPercentage: 10},
+				{Remaining: "/", IsDelay: true, Delay: 100 * time.Millisecond},
+				{Remaining: "/", IsLastHop: true},
+			},
+		},
+		{
+			name: "response overrides before a terminal directive",
+			path: "/ctype/text/plain/trailer/x-sum/1/bytes/10",
+			want: []actions{
+				{Remaining: "/trailer/x-sum/1/bytes/10", ContentType: "text/plain"},
+				{Remaining: "/bytes/10", TrailerName: "X-Sum", TrailerValue: "1"},
+				{Remaining: "/", IsBytes: true, Bytes: 10},
+			},
+		},
+		{
+			name: "fanout ends the hop",
+			path: "/delay/5/fanout/svca:8080,svcb:8080/delay/10",
+			want: []actions{
+				{Remaining: "/fanout/svca:8080,svcb:8080/delay/10", IsDelay: true, Delay: 5 * time.Millisecond},
+				{Remaining: "/delay/10", FanoutTargets: []string{"svca:8080", "svcb:8080"}},
+			},
+		},
+		{
+			name: "repeat before a proxy hop",
+			path: "/repeat/3/delay/5/proxy/svcb:8080",
+			want: []actions{
+				{Remaining: "/delay/5/proxy/svcb:8080", RepeatCount: 3},
+				{Remaining: "/proxy/svcb:8080", IsDelay: true, Delay: 5 * time.Millisecond},
+				{NextHop: "svcb:8080", Remaining: "/", Scheme: "http"},
+			},
+		},
+		{
+			name: "repeat without a proxy hop",
+			path: "/repeat/3/bytes/10",
+			want: []actions{
+				{Remaining: "/bytes/10", RepeatCount: 3},
+				{Remaining: "/", IsBytes: true, Bytes: 10},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "malformed directive later in the hop",
+			path:    "/delay/100/fault/abc",
+			want:    []actions{{Remaining: "/fault/abc", IsDelay: true, Delay: 100 * time.Millisecond}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseHop(tt.path, 0)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseHopTargetLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		max     int
+		wantErr bool
+	}{
+		{name: "fanout at the limit", path: "/fanout/a:80,b:80", max: 2},
+		{name: "fanout over the limit", path: "/fanout/a:80,b:80,c:80", max: 2, wantErr: true},
+		{name: "try at the limit", path: "/try/a:80,b:80", max: 2},
+		{name: "try over the limit", path: "/try/a:80,b:80,c:80", max: 2, wantErr: true},
+		{name: "after local directives", path: "/delay/1/try/a:80,b:80,c:80", max: 2, wantErr: true},
+		{name: "no limit", path: "/fanout/a:80,b:80,c:80", max: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseHop(tt.path, tt.max)
+			if tt.wantErr {
+				assert.ErrorContains(t, err, "exceeds the maximum of 2")
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestDirectiveApplicationOrder(t *testing.T) {
+	h, err := NewHandler(5*time.Second, "test-service", createTestLogger())
+	require.NoError(t, err)
+
+	serve := func(path string) (*httptest.ResponseRecorder, time.Duration) {
+		start := time.Now()
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr, time.Since(start)
+	}
+
+	t.Run("delay before fault waits then fails", func(t *testing.T) {
+		rr, elapsed := serve("/delay/200/fault/503")
+		assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+		assert.GreaterOrEqual(t, elapsed, 200*time.Millisecond)
+	})
+
+	t.Run("fault before delay fails without waiting", func(t *testing.T) {
+		rr, elapsed := serve("/fault/503/delay/2000")
+		assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+		assert.Less(t, elapsed, time.Second)
+	})
+
+	t.Run("overrides before a fault apply to it", func(t *testing.T) {
+		rr, _ := serve("/setcookie/a/1/fault/503")
+		assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+		assert.Equal(t, "a=1", rr.Header().Get("Set-Cookie"))
+	})
+
+	t.Run("overrides after a triggered fault are not reached", func(t *testing.T) {
+		rr, _ := serve("/fault/503/setcookie/a/1")
+		assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+		assert.Empty(t, rr.Header().Get("Set-Cookie"))
+	})
+
+	t.Run("later content type wins", func(t *testing.T) {
+		rr, _ := serve("/ctype/text/plain/ctype/text/csv")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "text/csv", rr.Header().Get("Content-Type"))
+	})
+
+	t.Run("malformed directive is rejected before earlier delays run", func(t *testing.T) {
+		rr, elapsed := serve("/delay/2000/fault/abc")
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Less(t, elapsed, time.Second)
+	})
+}
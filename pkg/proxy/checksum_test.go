@@ -0,0 +1,67 @@
+package proxy
+
+import (
+	"crypto/md5" // #nosec G501 -- verifies Content-MD5
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecksum(t *testing.T) {
+	md5Of := func(body []byte) string {
+		sum := md5.Sum(body) // #nosec G401 -- see import
+		return base64.StdEncoding.EncodeToString(sum[:])
+	}
+	sha256Of := func(body []byte) string {
+		sum := sha256.Sum256(body)
+		return hex.EncodeToString(sum[:])
+	}
+
+	tests := []struct {
+		algorithm string
+		header    string
+		sum       func([]byte) string
+	}{
+		{algorithm: ChecksumMD5, header: "Content-MD5", sum: md5Of},
+		{algorithm: ChecksumSHA256, header: sha256Header, sum: sha256Of},
+	}
+
+	for _, tt := range tests {
+		h, err := NewHandler(5*time.Second, "test-service", createTestLogger(), WithChecksum(tt.algorithm))
+		require.NoError(t, err)
+
+		for _, path := range []string{"/", "/seq/201", "/bytes/4096", "/bytes/100?seed=abc", "/bytes/0"} {
+			t.Run(tt.algorithm+path, func(t *testing.T) {
+				rr := httptest.NewRecorder()
+				h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+
+				require.NotEmpty(t, rr.Header().Get(tt.header))
+				assert.Equal(t, tt.sum(rr.Body.Bytes()), rr.Header().Get(tt.header))
+			})
+		}
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		h, err := NewHandler(5*time.Second, "test-service", createTestLogger())
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/bytes/16", nil))
+		assert.Empty(t, rr.Header().Get("Content-MD5"))
+		assert.Empty(t, rr.Header().Get(sha256Header))
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		assert.NoError(t, ValidateChecksum(""))
+		assert.NoError(t, ValidateChecksum(ChecksumMD5))
+		assert.NoError(t, ValidateChecksum(ChecksumSHA256))
+		assert.Error(t, ValidateChecksum("crc32"))
+	})
+}
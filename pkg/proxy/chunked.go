@@ -0,0 +1,79 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// chunkSize is how many bytes of the final response /chunked writes and flushes at a time
+const chunkSize = 32
+
+// chunkedWriter forces chunked transfer encoding by dropping any Content-Length and flushing
+// each small slice of the body as it is written, optionally pausing between slices
+type chunkedWriter struct {
+	http.ResponseWriter
+	rc       *http.ResponseController
+	ctx      context.Context
+	size     int
+	interval time.Duration
+	written  bool
+}
+
+// newChunkedWriter writes the body in chunkSize slices as fast as possible, for /chunked
+func newChunkedWriter(w http.ResponseWriter) *chunkedWriter {
+	return &chunkedWriter{ResponseWriter: w, rc: http.NewResponseController(w), size: chunkSize}
+}
+
+// newSlowBodyWriter writes the body one byte at a time, waiting interval between bytes, for
+// /slowbody. Writing stops with the context's error if ctx is done first.
+func newSlowBodyWriter(ctx context.Context, w http.ResponseWriter, interval time.Duration) *chunkedWriter {
+	return &chunkedWriter{ResponseWriter: w, rc: http.NewResponseController(w), ctx: ctx, size: 1, interval: interval}
+}
+
+func (w *chunkedWriter) WriteHeader(statusCode int) {
+	w.Header().Del("Content-Length")
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *chunkedWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		if w.interval > 0 && w.written {
+			if err := w.wait(); err != nil {
+				return written, err
+			}
+		}
+
+		n := min(w.size, len(p))
+		m, err := w.ResponseWriter.Write(p[:n])
+		written += m
+		w.written = true
+		if err != nil {
+			return written, err
+		}
+		// Flushing before the handler returns stops the server from computing a Content-Length
+		if err := w.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// wait pauses for the interval between slices, returning early if the context is done
+func (w *chunkedWriter) wait() error {
+	timer := time.NewTimer(w.interval)
+	defer timer.Stop()
+	select {
+	case <-w.ctx.Done():
+		return w.ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (w *chunkedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
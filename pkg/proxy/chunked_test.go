@@ -0,0 +1,103 @@
+package proxy
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkedDirective(t *testing.T) {
+	handler, err := NewHandler(30*time.Second, "test-service", createTestLogger(), WithETag(true))
+	require.NoError(t, err)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	t.Run("final response uses chunked encoding", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/ctype/application/json/chunked")
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, []string{"chunked"}, resp.TransferEncoding)
+		assert.Equal(t, int64(-1), resp.ContentLength)
+
+		var body Response
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, "test-service", body.Service)
+	})
+
+	t.Run("body is split across several chunks", func(t *testing.T) {
+		conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+		require.NoError(t, err)
+		defer func() { _ = conn.Close() }()
+
+		_, err = fmt.Fprintf(conn, "GET /chunked HTTP/1.1\r\nHost: test\r\nConnection: close\r\n\r\n")
+		require.NoError(t, err)
+		raw, err := io.ReadAll(bufio.NewReader(conn))
+		require.NoError(t, err)
+
+		_, body, ok := strings.Cut(string(raw), "\r\n\r\n")
+		require.True(t, ok)
+
+		// Walk the chunk framing: a hex size line, that many bytes, CRLF, until the zero-size chunk
+		var chunks int
+		for {
+			sizeLine, rest, ok := strings.Cut(body, "\r\n")
+			require.True(t, ok, "malformed chunk framing in %q", body)
+			size, err := strconv.ParseInt(sizeLine, 16, 64)
+			require.NoError(t, err)
+			if size == 0 {
+				break
+			}
+			chunks++
+			body = rest[size+2:]
+		}
+		assert.GreaterOrEqual(t, chunks, 2)
+	})
+}
+
+func TestSlowBodyDirective(t *testing.T) {
+	handler, err := NewHandler(30*time.Second, "test-service", createTestLogger())
+	require.NoError(t, err)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	t.Run("drips the body one byte per interval", func(t *testing.T) {
+		interval := 5 * time.Millisecond
+		start := time.Now()
+		resp, err := http.Get(server.URL + "/slowbody/5")
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		elapsed := time.Since(start)
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, []string{"chunked"}, resp.TransferEncoding)
+		assert.True(t, json.Valid(body))
+		assert.GreaterOrEqual(t, elapsed, time.Duration(len(body)-1)*interval)
+	})
+
+	t.Run("stops when the request deadline passes", func(t *testing.T) {
+		start := time.Now()
+		resp, err := http.Get(server.URL + "/slowbody/50?timeout=200ms")
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+
+		body, _ := io.ReadAll(resp.Body)
+		assert.Less(t, len(body), 10, "only the first few bytes should be written")
+		assert.Less(t, time.Since(start), 2*time.Second)
+	})
+}
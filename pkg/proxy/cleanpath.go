@@ -0,0 +1,87 @@
+package proxy
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	pathpkg "path"
+	"strings"
+)
+
+// WithRejectSuspiciousPaths answers 400 to paths that try to traverse with ".." segments, hide
+// a slash as %2F, or use a backslash as a separator, instead of cleaning them
+func WithRejectSuspiciousPaths(enabled bool) HandlerOption {
+	return func(h *Handler) {
+		h.rejectSuspiciousPaths = enabled
+	}
+}
+
+// cleanPath resolves "." and ".." segments and collapses repeated slashes the way the server's
+// router does, keeping any trailing slash. "https://" in a hop becomes "https:/", which hop
+// parsing accepts.
+func cleanPath(p string) string {
+	cleaned := pathpkg.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// suspiciousPath reports why u's path looks like a traversal attempt, or "" if it does not
+func suspiciousPath(u *url.URL) string {
+	for _, segment := range strings.Split(u.Path, "/") {
+		if segment == ".." {
+			return `path contains a ".." segment`
+		}
+	}
+	if strings.Contains(strings.ToLower(u.RawPath), "%2f") {
+		return "path contains an encoded slash"
+	}
+	if strings.Contains(u.Path, `\`) {
+		return "path contains a backslash"
+	}
+	return ""
+}
+
+// normalizePath cleans the request path before it is parsed, or answers 400 and returns false
+// when suspicious paths are rejected and the path is one. The returned request is r itself
+// unless its path changed.
+func (h *Handler) normalizePath(w http.ResponseWriter, r *http.Request) (*http.Request, bool) {
+	if h.rejectSuspiciousPaths {
+		if reason := suspiciousPath(r.URL); reason != "" {
+			logger := h.logger.With(slog.String("method", r.Method), slog.String("path", r.URL.Path))
+			logger.Info("Rejected suspicious path", slog.String("reason", reason), slog.String("raw_path", r.URL.EscapedPath()))
+			h.writeError(w, r, http.StatusBadRequest, ErrCodeBadRequest, "Suspicious path rejected: "+reason, logger)
+			return r, false
+		}
+	}
+
+	cleaned := cleanPath(r.URL.Path)
+	if cleaned == r.URL.Path {
+		return r, true
+	}
+	h.logger.Debug("Cleaned request path", slog.String("path", r.URL.Path), slog.String("cleaned_path", cleaned))
+
+	// Copy the request so the caller's URL is left untouched. The escaped form no longer
+	// matches, so it is dropped and recomputed from the cleaned path.
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.URL = new(url.URL)
+	*r2.URL = *r.URL
+	r2.URL.Path = cleaned
+	r2.URL.RawPath = ""
+	return r2, true
+}
+
+// NormalizePaths cleans or rejects request paths as ServeHTTP does before passing them to next.
+// Mount it in front of a router such as http.ServeMux, which would otherwise redirect unclean
+// paths to their cleaned form before the handler sees them.
+func (h *Handler) NormalizePaths(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r, ok := h.normalizePath(w, r)
+		if !ok {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
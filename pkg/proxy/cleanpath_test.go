@@ -0,0 +1,102 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/proxy/svca:8080", want: "/proxy/svca:8080"},
+		{path: "/proxy//svca:8080", want: "/proxy/svca:8080"},
+		{path: "/proxy/../svca:8080", want: "/svca:8080"},
+		{path: "/fault/503/../proxy/svca:8080", want: "/fault/proxy/svca:8080"},
+		{path: "/proxy/./svca:8080/", want: "/proxy/svca:8080/"},
+		{path: "/proxy/https://svca:8443", want: "/proxy/https:/svca:8443"},
+		{path: "/../..", want: "/"},
+		{path: "", want: "/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.want, cleanPath(tt.path))
+		})
+	}
+}
+
+func TestPathNormalization(t *testing.T) {
+	var upstreamPath string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamPath = r.URL.Path
+	}))
+	defer upstream.Close()
+	host := strings.TrimPrefix(upstream.URL, "http://")
+
+	do := func(t *testing.T, handler http.Handler, target string) *httptest.ResponseRecorder {
+		t.Helper()
+		upstreamPath = ""
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+		return rr
+	}
+
+	t.Run("cleans paths by default", func(t *testing.T) {
+		handler, err := NewHandler(5*time.Second, "test-service", createTestLogger())
+		require.NoError(t, err)
+
+		rr := do(t, handler, "/proxy//"+host+"//api")
+		assert.Equal(t, http.StatusOK, rr.Code, "double slashes collapse: %s", rr.Body.String())
+		assert.Equal(t, "/api/", upstreamPath)
+
+		rr = do(t, handler, "/fault/503/../proxy/"+host)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, "the fault's code is resolved away, leaving /fault/proxy")
+
+		rr = do(t, handler, "/proxy/../proxy/"+host)
+		assert.Equal(t, http.StatusOK, rr.Code, ".. resolves to the hop after it")
+		assert.Equal(t, "/", upstreamPath)
+
+		rr = do(t, handler, "/proxy%2F"+host)
+		assert.Equal(t, http.StatusOK, rr.Code, "an encoded slash acts as a slash")
+	})
+
+	t.Run("rejects suspicious paths when enabled", func(t *testing.T) {
+		handler, err := NewHandler(5*time.Second, "test-service", createTestLogger(), WithRejectSuspiciousPaths(true))
+		require.NoError(t, err)
+
+		for _, target := range []string{
+			"/proxy/../proxy/" + host,
+			"/proxy/%2E%2E/proxy/" + host,
+			"/proxy%2F" + host,
+			"/proxy/" + host + "/a%5Cb",
+		} {
+			rr := do(t, handler, target)
+			assert.Equal(t, http.StatusBadRequest, rr.Code, target)
+			assert.Equal(t, ErrCodeBadRequest, decodeErrorResponse(t, rr).Code, target)
+			assert.Empty(t, upstreamPath, "%s should not be forwarded", target)
+		}
+
+		rr := do(t, handler, "/proxy//"+host)
+		assert.Equal(t, http.StatusOK, rr.Code, "double slashes are still only cleaned")
+	})
+
+	t.Run("routers in front see the cleaned path", func(t *testing.T) {
+		handler, err := NewHandler(5*time.Second, "test-service", createTestLogger())
+		require.NoError(t, err)
+		mux := http.NewServeMux()
+		mux.Handle("/", handler)
+
+		rr := do(t, mux, "/proxy//"+host)
+		assert.Equal(t, 3, rr.Code/100, "the mux alone redirects")
+		assert.Equal(t, "/proxy/"+host, rr.Header().Get("Location"))
+		assert.Equal(t, http.StatusOK, do(t, handler.NormalizePaths(mux), "/proxy//"+host).Code)
+	})
+}
@@ -0,0 +1,30 @@
+package proxy
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// clientIP returns the address of the client that originated the request. When trustHeaders
+// is set, X-Forwarded-For (leftmost entry) and then X-Real-IP take precedence over the
+// connection's remote address; otherwise those headers are ignored since any client can set them.
+func clientIP(r *http.Request, trustHeaders bool) string {
+	if trustHeaders {
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			first, _, _ := strings.Cut(xff, ",")
+			if ip := strings.TrimSpace(first); ip != "" {
+				return ip
+			}
+		}
+		if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); ip != "" {
+			return ip
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
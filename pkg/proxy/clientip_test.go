@@ -0,0 +1,71 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		trust   bool
+		headers map[string]string
+		want    string
+	}{
+		{name: "remote addr when untrusted", trust: false, headers: map[string]string{"X-Forwarded-For": "203.0.113.7"}, want: "192.0.2.1"},
+		{name: "remote addr without headers", trust: true, want: "192.0.2.1"},
+		{name: "leftmost forwarded-for entry", trust: true, headers: map[string]string{"X-Forwarded-For": "203.0.113.7, 10.0.0.1"}, want: "203.0.113.7"},
+		{name: "real ip fallback", trust: true, headers: map[string]string{"X-Real-IP": "198.51.100.4"}, want: "198.51.100.4"},
+		{name: "forwarded-for preferred over real ip", trust: true, headers: map[string]string{"X-Forwarded-For": "203.0.113.7", "X-Real-IP": "198.51.100.4"}, want: "203.0.113.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			assert.Equal(t, tt.want, clientIP(req, tt.trust))
+		})
+	}
+}
+
+func TestClientIPLogging(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		trust bool
+		want  string
+	}{
+		{name: "untrusted logs the connection address", trust: false, want: "192.0.2.1"},
+		{name: "trusted logs the forwarded address", trust: true, want: "203.0.113.7"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+			handler, err := NewHandler(30*time.Second, "test-service", logger, WithTrustProxyHeaders(tt.trust))
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			req.Header.Set("X-Forwarded-For", "203.0.113.7")
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			// The first log line is the incoming request
+			line, _, _ := bytes.Cut(buf.Bytes(), []byte("\n"))
+			var entry map[string]any
+			require.NoError(t, json.Unmarshal(line, &entry))
+			assert.Equal(t, "Incoming request", entry["msg"])
+			assert.Equal(t, tt.want, entry["client_ip"])
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// coalescedHeader marks responses shared from another identical request's upstream call
+const coalescedHeader = "X-Coalesced"
+
+// WithCoalescing configures whether concurrent identical GET and HEAD requests share a single
+// execution of the chain, with every caller receiving the same response. Requests carrying
+// credentials or caller-specific faults are always served on their own.
+func WithCoalescing(enabled bool) HandlerOption {
+	return func(h *Handler) {
+		h.coalescing = enabled
+	}
+}
+
+// coalesceKeyHeaders are the request headers, besides the method, path and query, that change
+// the response a request gets, so requests only share a response when they agree on all of them
+var coalesceKeyHeaders = []string{"Accept", "If-None-Match", "If-Modified-Since", deadlineHeader}
+
+// coalescible reports whether r may share its response with identical in-flight requests.
+// Requests carrying credentials are not, since the response may be specific to the client, and
+// neither are protocol upgrades or requests whose faults depend on the caller: an X-Inject-Fault
+// header, already moved into the context by serve, or a /match/ on a header anywhere in the chain.
+func coalescible(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead || r.ContentLength > 0 {
+		return false
+	}
+	if r.Header.Get("Authorization") != "" || r.Header.Get("Cookie") != "" || r.Header.Get("Upgrade") != "" {
+		return false
+	}
+	if _, ok := injectedFault(r.Context()); ok {
+		return false
+	}
+	return !strings.Contains(r.URL.Path+"/", "/match/")
+}
+
+// coalesceKey identifies requests that would get the same response: the method, path and query,
+// and the values of the coalesceKeyHeaders
+func coalesceKey(r *http.Request) string {
+	key := r.Method + " " + r.URL.RequestURI()
+	for _, name := range coalesceKeyHeaders {
+		key += "\n" + name + ": " + strings.Join(r.Header.Values(name), ", ")
+	}
+	return key
+}
+
+// bufferedResponse records a response in memory instead of sending it
+type bufferedResponse struct {
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponse) WriteHeader(statusCode int) {
+	if b.statusCode == 0 {
+		b.statusCode = statusCode
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	if b.statusCode == 0 {
+		b.statusCode = http.StatusOK
+	}
+	return b.body.Write(p)
+}
+
+// serveCoalesced serves r through the chain once for all identical requests in flight at the
+// same time. The first request runs the chain with its response buffered; the rest wait and
+// receive a copy marked with X-Coalesced. Responses are buffered in full, so streaming
+// directives such as /chunked arrive in one piece and /reset is not supported. The shared call is
+// detached from the first request's cancellation and bounded by the request timeout instead, so
+// one caller going away does not fail the others.
+func (h *Handler) serveCoalesced(w http.ResponseWriter, r *http.Request) {
+	key := coalesceKey(r)
+
+	// The shared call outlives any one caller, who can still stop waiting for it
+	result := h.inflight.DoChan(key, func() (any, error) {
+		timeout, err := h.requestTimeout(r)
+		if err != nil {
+			timeout = h.timeout
+		}
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), timeout)
+		defer cancel()
+
+		buffered := &bufferedResponse{header: http.Header{}}
+		h.serveProxy(buffered, r.WithContext(ctx))
+		if buffered.statusCode == 0 {
+			buffered.statusCode = http.StatusOK
+		}
+		return cachedResponse{statusCode: buffered.statusCode, header: buffered.header, body: buffered.body.Bytes()}, nil
+	})
+
+	var res singleflight.Result
+	select {
+	case res = <-result:
+	case <-r.Context().Done():
+		h.writeError(w, r, http.StatusGatewayTimeout, ErrCodeGatewayTimeout, "Request ended while waiting for a coalesced response", h.logger)
+		return
+	}
+	resp, shared := res.Val.(cachedResponse), res.Shared
+
+	if shared {
+		h.logger.Debug("Sharing coalesced response", slog.String("key", key), slog.Int("status_code", resp.statusCode))
+		w.Header().Set(coalescedHeader, "true")
+	}
+	for k, v := range resp.header {
+		w.Header()[k] = append([]string(nil), v...)
+	}
+	w.WriteHeader(resp.statusCode)
+	_, _ = w.Write(resp.body)
+}
@@ -0,0 +1,187 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoalescing(t *testing.T) {
+	var hits atomic.Int32
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"upstream":true}`))
+	}))
+	defer upstream.Close()
+	path := "/proxy/" + strings.TrimPrefix(upstream.URL, "http://")
+
+	h, err := NewHandler(5*time.Second, "test-service", createTestLogger(), WithCoalescing(true))
+	require.NoError(t, err)
+
+	const requests = 50
+	recorders := make([]*httptest.ResponseRecorder, requests)
+	var wg sync.WaitGroup
+	for i := range requests {
+		recorders[i] = httptest.NewRecorder()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.ServeHTTP(recorders[i], httptest.NewRequest(http.MethodGet, path, nil))
+		}()
+	}
+
+	// Hold the upstream call open until every request has had time to join it
+	require.Eventually(t, func() bool { return hits.Load() == 1 }, 5*time.Second, time.Millisecond)
+	time.Sleep(200 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), hits.Load(), "identical in-flight requests share one upstream call")
+	coalesced := 0
+	for _, rr := range recorders {
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"upstream":true}`, rr.Body.String())
+		if rr.Header().Get(coalescedHeader) == "true" {
+			coalesced++
+		}
+	}
+	assert.Equal(t, requests, coalesced, "every caller shares the single response")
+
+	t.Run("later requests make a new call", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, int32(2), hits.Load())
+		assert.Empty(t, rr.Header().Get(coalescedHeader))
+	})
+
+	t.Run("requests with bodies are not coalesced", func(t *testing.T) {
+		assert.False(t, coalescible(httptest.NewRequest(http.MethodPost, path, nil)))
+		assert.False(t, coalescible(httptest.NewRequest(http.MethodGet, path, strings.NewReader("body"))))
+		assert.True(t, coalescible(httptest.NewRequest(http.MethodHead, path, nil)))
+	})
+
+	t.Run("requests with credentials are not coalesced", func(t *testing.T) {
+		for _, header := range []string{"Authorization", "Cookie"} {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set(header, "secret")
+			assert.False(t, coalescible(req), header)
+		}
+	})
+}
+
+func TestCoalescingSurvivesFirstCallerCancel(t *testing.T) {
+	var hits atomic.Int32
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		<-release
+		_, _ = w.Write([]byte(`{"upstream":true}`))
+	}))
+	defer upstream.Close()
+	path := "/proxy/" + strings.TrimPrefix(upstream.URL, "http://")
+
+	h, err := NewHandler(5*time.Second, "test-service", createTestLogger(), WithCoalescing(true))
+	require.NoError(t, err)
+
+	// The first caller starts the shared call, then goes away
+	ctx, cancel := context.WithCancel(context.Background())
+	first := httptest.NewRecorder()
+	firstDone := make(chan struct{})
+	go func() {
+		defer close(firstDone)
+		h.ServeHTTP(first, httptest.NewRequest(http.MethodGet, path, nil).WithContext(ctx))
+	}()
+	require.Eventually(t, func() bool { return hits.Load() == 1 }, 5*time.Second, time.Millisecond)
+
+	second := httptest.NewRecorder()
+	secondDone := make(chan struct{})
+	go func() {
+		defer close(secondDone)
+		h.ServeHTTP(second, httptest.NewRequest(http.MethodGet, path, nil))
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	cancel()
+	<-firstDone
+	close(release)
+	<-secondDone
+
+	assert.Equal(t, int32(1), hits.Load())
+	assert.Equal(t, http.StatusOK, second.Code, "the waiting caller gets the upstream response")
+	assert.JSONEq(t, `{"upstream":true}`, second.Body.String())
+	assert.Equal(t, "true", second.Header().Get(coalescedHeader))
+}
+
+func TestCoalescingKeepsCallerSpecificResponsesApart(t *testing.T) {
+	h, err := NewHandler(5*time.Second, "test-service", createTestLogger(), WithCoalescing(true))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		prefix string
+		header string
+		value  string
+	}{
+		{name: "injected fault", header: injectFaultHeader, value: "503"},
+		{name: "fault matching a header", prefix: "/fault/503/100/match/x-canary=true", header: "X-Canary", value: "true"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits atomic.Int32
+			release := make(chan struct{})
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				hits.Add(1)
+				<-release
+				_, _ = w.Write([]byte(`{"upstream":true}`))
+			}))
+			defer upstream.Close()
+			path := tt.prefix + "/proxy/" + strings.TrimPrefix(upstream.URL, "http://")
+
+			// A clean request holds the upstream call open...
+			clean := httptest.NewRecorder()
+			cleanDone := make(chan struct{})
+			go func() {
+				defer close(cleanDone)
+				h.ServeHTTP(clean, httptest.NewRequest(http.MethodGet, path, nil))
+			}()
+			require.Eventually(t, func() bool { return hits.Load() == 1 }, 5*time.Second, time.Millisecond)
+
+			// ...while an identical request that should be faulted arrives
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Header.Set(tt.header, tt.value)
+			faulted := httptest.NewRecorder()
+			h.ServeHTTP(faulted, req)
+
+			close(release)
+			<-cleanDone
+
+			assert.Equal(t, http.StatusServiceUnavailable, faulted.Code, "the faulted request gets its own fault")
+			assert.Empty(t, faulted.Header().Get(coalescedHeader))
+			assert.Equal(t, http.StatusOK, clean.Code, "the clean request is not faulted")
+			assert.Empty(t, clean.Header().Get(coalescedHeader))
+		})
+	}
+
+	t.Run("response-changing headers are part of the key", func(t *testing.T) {
+		plain := httptest.NewRequest(http.MethodGet, "/proxy/svc:8080", nil)
+		conditional := httptest.NewRequest(http.MethodGet, "/proxy/svc:8080", nil)
+		conditional.Header.Set("If-None-Match", `"abc"`)
+		assert.NotEqual(t, coalesceKey(plain), coalesceKey(conditional))
+
+		withDeadline := httptest.NewRequest(http.MethodGet, "/proxy/svc:8080", nil)
+		withDeadline.Header.Set(deadlineHeader, time.Now().Add(time.Second).Format(time.RFC3339Nano))
+		assert.NotEqual(t, coalesceKey(plain), coalesceKey(withDeadline))
+	})
+}
@@ -0,0 +1,129 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// TopologySpec describes a proxy chain as JSON, for POST /compose. It is translated into the
+// equivalent chain path, so every directive behaves exactly as it does in a URL.
+type TopologySpec struct {
+	Method     string    `json:"method,omitempty"`     // Method to send along the chain (default GET)
+	Directives []string  `json:"directives,omitempty"` // Directives this service applies before the first hop, e.g. "delay/100"
+	Hops       []HopSpec `json:"hops,omitempty"`       // Upstream hops in order; the last one answers the request
+}
+
+// HopSpec is a single upstream hop in a TopologySpec
+type HopSpec struct {
+	Target     string   `json:"target"`               // Hop address, e.g. "service-b:8080" or "https://service-b:8443"
+	Directives []string `json:"directives,omitempty"` // Directives this hop applies before forwarding onwards, e.g. "fault/500/50"
+}
+
+// Path translates the spec into the chain path the proxy handler parses, e.g.
+// {"directives":["delay/100"],"hops":[{"target":"service-b:8080"}]} becomes /delay/100/proxy/service-b:8080
+func (s TopologySpec) Path() (string, error) {
+	var segments []string
+	addDirectives := func(directives []string) error {
+		for _, d := range directives {
+			d = strings.Trim(d, "/")
+			if d == "" {
+				return fmt.Errorf("empty directive")
+			}
+			if d == "proxy" || strings.HasPrefix(d, "proxy/") {
+				return fmt.Errorf("directive %q: use hops for proxy targets", d)
+			}
+			segments = append(segments, d)
+		}
+		return nil
+	}
+
+	if err := addDirectives(s.Directives); err != nil {
+		return "", err
+	}
+	for i, hop := range s.Hops {
+		target := strings.Trim(hop.Target, "/")
+		if target == "" {
+			return "", fmt.Errorf("hop %d: missing target", i)
+		}
+		// Match the form the router leaves schemes in once it has cleaned the path
+		target = strings.Replace(target, "://", ":/", 1)
+		segments = append(segments, "proxy", target)
+		if err := addDirectives(hop.Directives); err != nil {
+			return "", fmt.Errorf("hop %d: %w", i, err)
+		}
+	}
+	return "/" + strings.Join(segments, "/"), nil
+}
+
+// maxComposeBodyBytes caps the size of a topology spec posted to /compose
+const maxComposeBodyBytes = 1 << 20
+
+// ServeCompose answers POST /compose, whose body is a TopologySpec, by running the chain it
+// describes and returning its response. The chain request carries the compose request's headers
+// but no body. Specs over maxComposeBodyBytes get 413.
+func (h *Handler) ServeCompose(w http.ResponseWriter, r *http.Request) {
+	logger := h.logger.With(slog.String("method", r.Method), slog.String("path", r.URL.Path))
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		h.writeError(w, r, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Compose requires POST with a topology spec as the body", logger)
+		return
+	}
+
+	var spec TopologySpec
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxComposeBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&spec); err != nil {
+		h.writeDecodeError(w, r, err, "topology spec", logger)
+		return
+	}
+
+	path, err := spec.Path()
+	if err == nil {
+		// Reject specs the hops would reject, before any of them is called
+		if result := inspectPath(path); result.Error != "" {
+			err = errors.New(result.Error)
+		}
+	}
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("Invalid topology spec: %v", err), logger)
+		return
+	}
+
+	req, err := composeRequest(r, spec.Method, path)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("Invalid topology spec: %v", err), logger)
+		return
+	}
+
+	logger.Info("Running composed topology", slog.String("compose_method", req.Method), slog.String("compose_path", path), slog.Int("hops", len(spec.Hops)))
+	h.serve(w, req)
+}
+
+// composeRequest builds the chain request for path on the context and connection of the compose
+// request r, without the headers describing r's body
+func composeRequest(r *http.Request, method, path string) (*http.Request, error) {
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	target := &url.URL{Path: path}
+	req, err := http.NewRequestWithContext(r.Context(), method, target.String(), http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Host = r.Host
+	req.RemoteAddr = r.RemoteAddr
+	req.RequestURI = target.RequestURI()
+
+	req.Header = r.Header.Clone()
+	req.Header.Del("Content-Type")
+	req.Header.Del("Content-Length")
+	req.Header.Del("Content-Encoding")
+	return req, nil
+}
@@ -0,0 +1,119 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopologySpecPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    TopologySpec
+		want    string
+		wantErr bool
+	}{
+		{name: "empty", spec: TopologySpec{}, want: "/"},
+		{
+			name: "directives and hops",
+			spec: TopologySpec{
+				Directives: []string{"delay/100"},
+				Hops: []HopSpec{
+					{Target: "service-b:8080", Directives: []string{"/fault/500/50/"}},
+					{Target: "https://service-c:8443"},
+				},
+			},
+			want: "/delay/100/proxy/service-b:8080/fault/500/50/proxy/https:/service-c:8443",
+		},
+		{name: "missing target", spec: TopologySpec{Hops: []HopSpec{{Directives: []string{"delay/10"}}}}, wantErr: true},
+		{name: "empty directive", spec: TopologySpec{Directives: []string{"/"}}, wantErr: true},
+		{name: "proxy directive", spec: TopologySpec{Directives: []string{"proxy/service-b:8080"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.spec.Path()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCompose(t *testing.T) {
+	logger := createTestLogger()
+
+	// Two real hops: compose -> service-b -> service-c
+	serviceC, err := NewHandler(5*time.Second, "service-c", logger)
+	require.NoError(t, err)
+	upstreamC := httptest.NewServer(serviceC)
+	defer upstreamC.Close()
+
+	serviceB, err := NewHandler(5*time.Second, "service-b", logger)
+	require.NoError(t, err)
+	upstreamB := httptest.NewServer(serviceB)
+	defer upstreamB.Close()
+
+	h, err := NewHandler(5*time.Second, "service-a", logger)
+	require.NoError(t, err)
+
+	compose := func(t *testing.T, method, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		h.ServeCompose(rr, httptest.NewRequest(method, "/compose", strings.NewReader(body)))
+		return rr
+	}
+	hostB := strings.TrimPrefix(upstreamB.URL, "http://")
+	hostC := strings.TrimPrefix(upstreamC.URL, "http://")
+
+	t.Run("executes the chain", func(t *testing.T) {
+		rr := compose(t, http.MethodPost, `{"hops":[{"target":"`+hostB+`"},{"target":"`+hostC+`"}]}`)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, "service-c", body["service"], "the last hop answers")
+	})
+
+	t.Run("hop directives run on that hop", func(t *testing.T) {
+		rr := compose(t, http.MethodPost, `{"hops":[{"target":"`+hostB+`","directives":["fault/503"]},{"target":"`+hostC+`"}]}`)
+		assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	})
+
+	t.Run("local directives run before the first hop", func(t *testing.T) {
+		rr := compose(t, http.MethodPost, `{"directives":["fault/418"],"hops":[{"target":"`+hostB+`"}]}`)
+		assert.Equal(t, http.StatusTeapot, rr.Code)
+	})
+
+	t.Run("invalid directive is rejected before any hop", func(t *testing.T) {
+		rr := compose(t, http.MethodPost, `{"hops":[{"target":"`+hostB+`","directives":["fault/999"]}]}`)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("malformed spec", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, compose(t, http.MethodPost, `{"hops":`).Code)
+		assert.Equal(t, http.StatusBadRequest, compose(t, http.MethodPost, `{"chain":[]}`).Code)
+	})
+
+	t.Run("oversized spec gets 413", func(t *testing.T) {
+		padding := strings.Repeat(" ", maxComposeBodyBytes)
+		rr := compose(t, http.MethodPost, `{"hops":[{"target":"`+hostB+`"}]`+padding+`, "method":"GET"}`)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		assert.Equal(t, ErrCodePayloadTooLarge, decodeErrorResponse(t, rr).Code)
+	})
+
+	t.Run("requires POST", func(t *testing.T) {
+		rr := compose(t, http.MethodGet, "")
+		assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+		assert.Equal(t, http.MethodPost, rr.Header().Get("Allow"))
+	})
+}
@@ -0,0 +1,58 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// WithMaxConcurrent limits how many requests are processed at once; 0 allows any number.
+// Requests over the limit wait up to the WithMaxQueueWait duration for a slot, then get 503.
+func WithMaxConcurrent(n int) HandlerOption {
+	return func(h *Handler) {
+		h.concurrency = nil
+		if n > 0 {
+			h.concurrency = semaphore.NewWeighted(int64(n))
+		}
+	}
+}
+
+// WithMaxQueueWait sets how long a request waits for a slot under WithMaxConcurrent before
+// being rejected; 0 rejects immediately
+func WithMaxQueueWait(d time.Duration) HandlerOption {
+	return func(h *Handler) {
+		h.maxQueueWait = d
+	}
+}
+
+// acquireSlot takes a concurrency slot for the request, queueing for up to maxQueueWait. When no
+// slot frees up in time it answers 503 and returns false; otherwise the caller must releaseSlot.
+func (h *Handler) acquireSlot(w http.ResponseWriter, r *http.Request) bool {
+	if h.concurrency.TryAcquire(1) {
+		return true
+	}
+
+	if h.maxQueueWait > 0 {
+		start := time.Now()
+		ctx, cancel := context.WithTimeout(r.Context(), h.maxQueueWait)
+		defer cancel()
+		if err := h.concurrency.Acquire(ctx, 1); err == nil {
+			h.logger.Debug("Acquired slot after queueing", slog.Duration("wait", time.Since(start)))
+			return true
+		}
+	}
+
+	logger := h.logger.With(slog.String("method", r.Method), slog.String("path", r.URL.Path))
+	logger.Warn("Rejecting request, concurrency limit reached", slog.Duration("max_queue_wait", h.maxQueueWait))
+	h.writeError(w, r, http.StatusServiceUnavailable, ErrCodeOverloaded, fmt.Sprintf("Too many concurrent requests, no slot freed within %s", h.maxQueueWait), logger)
+	return false
+}
+
+// releaseSlot returns a slot taken by acquireSlot
+func (h *Handler) releaseSlot() {
+	h.concurrency.Release(1)
+}
@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConcurrencyLimit(t *testing.T) {
+	// serveConcurrently sends n requests for path at once, returning how many got each status
+	serveConcurrently := func(handler *Handler, path string, n int) map[int]int {
+		var mu sync.Mutex
+		codes := map[int]int{}
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+				mu.Lock()
+				codes[rr.Code]++
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+		return codes
+	}
+
+	t.Run("queued requests wait for a slot or time out with 503", func(t *testing.T) {
+		// One slot and 200ms requests: the second queues for ~200ms and succeeds, the third
+		// would need ~400ms and gives up after 300ms
+		handler, err := NewHandler(30*time.Second, "test-service", createTestLogger(), WithMaxConcurrent(1), WithMaxQueueWait(300*time.Millisecond))
+		require.NoError(t, err)
+
+		codes := serveConcurrently(handler, "/delay/200", 3)
+		assert.Equal(t, map[int]int{http.StatusOK: 2, http.StatusServiceUnavailable: 1}, codes)
+	})
+
+	t.Run("without a queue wait excess requests are rejected immediately", func(t *testing.T) {
+		handler, err := NewHandler(30*time.Second, "test-service", createTestLogger(), WithMaxConcurrent(2))
+		require.NoError(t, err)
+
+		start := time.Now()
+		codes := serveConcurrently(handler, "/delay/200", 4)
+		assert.Equal(t, map[int]int{http.StatusOK: 2, http.StatusServiceUnavailable: 2}, codes)
+		assert.Less(t, time.Since(start), 400*time.Millisecond)
+	})
+
+	t.Run("slots are released after each request", func(t *testing.T) {
+		handler, err := NewHandler(30*time.Second, "test-service", createTestLogger(), WithMaxConcurrent(1))
+		require.NoError(t, err)
+
+		for i := 0; i < 3; i++ {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+			assert.Equal(t, http.StatusOK, rr.Code)
+		}
+	})
+}
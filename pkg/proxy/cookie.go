@@ -0,0 +1,72 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// parseSetCookie validates a /setcookie/<name>/<value> directive
+func parseSetCookie(name, value string) (*http.Cookie, error) {
+	cookie := &http.Cookie{Name: name, Value: value}
+	if err := cookie.Valid(); err != nil {
+		return nil, fmt.Errorf("invalid setcookie path: %w", err)
+	}
+	return cookie, nil
+}
+
+// withCookieAttributes returns a copy of cookie with the attributes given as query parameters:
+// path, max-age in seconds (0 expires the cookie), httponly and secure (true when given without a
+// value), and samesite (lax, strict or none)
+func withCookieAttributes(cookie *http.Cookie, query url.Values) (*http.Cookie, error) {
+	c := *cookie
+	c.Path = query.Get("path")
+
+	if v := query.Get("max-age"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid cookie max-age %q: must be a number of seconds", v)
+		}
+		// http.Cookie uses a negative MaxAge for "Max-Age=0" and 0 for no Max-Age at all
+		c.MaxAge = n
+		if n == 0 {
+			c.MaxAge = -1
+		}
+	}
+
+	for name, flag := range map[string]*bool{"httponly": &c.HttpOnly, "secure": &c.Secure} {
+		if !query.Has(name) {
+			continue
+		}
+		v := query.Get(name)
+		if v == "" {
+			*flag = true
+			continue
+		}
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cookie %s %q: must be true or false", name, v)
+		}
+		*flag = enabled
+	}
+
+	if v := query.Get("samesite"); v != "" {
+		switch strings.ToLower(v) {
+		case "lax":
+			c.SameSite = http.SameSiteLaxMode
+		case "strict":
+			c.SameSite = http.SameSiteStrictMode
+		case "none":
+			c.SameSite = http.SameSiteNoneMode
+		default:
+			return nil, fmt.Errorf("invalid cookie samesite %q: must be lax, strict or none", v)
+		}
+	}
+
+	if err := c.Valid(); err != nil {
+		return nil, fmt.Errorf("invalid cookie: %w", err)
+	}
+	return &c, nil
+}
@@ -0,0 +1,82 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetCookie(t *testing.T) {
+	h, err := NewHandler(5*time.Second, "test-service", createTestLogger())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		path string
+		want []string
+	}{
+		{
+			name: "name and value only",
+			path: "/setcookie/session/abc123",
+			want: []string{"session=abc123"},
+		},
+		{
+			name: "all attributes",
+			path: "/setcookie/session/abc123?path=/app&max-age=3600&httponly&secure=true&samesite=strict",
+			want: []string{"session=abc123; Path=/app; Max-Age=3600; HttpOnly; Secure; SameSite=Strict"},
+		},
+		{
+			name: "zero max-age expires the cookie",
+			path: "/setcookie/session/gone?max-age=0",
+			want: []string{"session=gone; Max-Age=0"},
+		},
+		{
+			name: "flags can be turned off",
+			path: "/setcookie/session/abc123?httponly=false&samesite=lax",
+			want: []string{"session=abc123; SameSite=Lax"},
+		},
+		{
+			name: "repeated directives set several cookies",
+			path: "/setcookie/a/1/setcookie/b/2?samesite=none&secure",
+			want: []string{"a=1; Secure; SameSite=None", "b=2; Secure; SameSite=None"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, tt.want, rr.Header().Values("Set-Cookie"))
+		})
+	}
+
+	t.Run("cookie is set on the forwarded response", func(t *testing.T) {
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.SetCookie(w, &http.Cookie{Name: "upstream", Value: "1"})
+		}))
+		defer upstream.Close()
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/setcookie/session/abc123/proxy/"+strings.TrimPrefix(upstream.URL, "http://"), nil))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.ElementsMatch(t, []string{"session=abc123", "upstream=1"}, rr.Header().Values("Set-Cookie"))
+	})
+
+	for _, query := range []string{"max-age=soon", "max-age=-5", "secure=maybe", "samesite=sometimes"} {
+		t.Run("invalid attribute "+query, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/setcookie/session/abc123?"+query, nil))
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			assert.Empty(t, rr.Header().Values("Set-Cookie"))
+		})
+	}
+}
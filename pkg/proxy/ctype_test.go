@@ -0,0 +1,60 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseContentType(t *testing.T) {
+	get := func(t *testing.T, handler *Handler, path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept", "application/xml")
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	tests := []struct {
+		name     string
+		flag     string
+		path     string
+		expected string
+	}{
+		{name: "negotiated without override", path: "/", expected: "application/xml"},
+		{name: "flag overrides negotiation", flag: "text/html", path: "/", expected: "text/html"},
+		{name: "directive overrides negotiation", path: "/ctype/text/plain", expected: "text/plain"},
+		{name: "directive wins over flag", flag: "text/html", path: "/ctype/application/vnd.test+json", expected: "application/vnd.test+json"},
+		{name: "directive after delay", path: "/delay/1/ctype/text/csv", expected: "text/csv"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, err := NewHandler(30*time.Second, "test-service", createTestLogger(), WithResponseContentType(tt.flag))
+			require.NoError(t, err)
+
+			rr := get(t, handler, tt.path)
+			require.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, tt.expected, rr.Header().Get("Content-Type"))
+
+			if tt.expected != "application/xml" {
+				var body Response
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body), "body should remain the JSON envelope")
+				assert.Equal(t, "test-service", body.Service)
+			}
+		})
+	}
+
+	t.Run("invalid directive", func(t *testing.T) {
+		handler, err := NewHandler(30*time.Second, "test-service", createTestLogger())
+		require.NoError(t, err)
+
+		rr := get(t, handler, "/ctype/text")
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
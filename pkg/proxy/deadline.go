@@ -0,0 +1,89 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// deadlineHeader carries the absolute time by which the whole chain must respond, as RFC 3339
+const deadlineHeader = "X-Deadline"
+
+// WithDeadlinePropagation enables end-to-end deadlines: the first hop sets X-Deadline from the
+// configured timeout, later hops honour it, and a hop that would exceed it answers 504 early
+func WithDeadlinePropagation(enabled bool) HandlerOption {
+	return func(h *Handler) {
+		h.propagateDeadline = enabled
+	}
+}
+
+// WithMaxTotalDuration bounds the time the whole chain may take, independent of each hop's
+// timeout. The first hop sets X-Deadline to when it received the request plus d, and every hop
+// configured with a maximum honours and forwards it, answering 504 once it would be exceeded.
+func WithMaxTotalDuration(d time.Duration) HandlerOption {
+	return func(h *Handler) {
+		h.maxTotalDuration = d
+	}
+}
+
+// usesDeadlineHeader reports whether incoming X-Deadline headers are honoured and forwarded
+func (h *Handler) usesDeadlineHeader() bool {
+	return h.propagateDeadline || h.maxTotalDuration > 0
+}
+
+// requestDeadline returns when the request must be answered by: now plus the request's timeout,
+// or sooner if the maximum total duration or an incoming X-Deadline (when propagation or a
+// maximum total duration is enabled) ends earlier
+func (h *Handler) requestDeadline(r *http.Request, now time.Time, timeout time.Duration) time.Time {
+	deadline := now.Add(timeout)
+	if h.maxTotalDuration > 0 && h.maxTotalDuration < timeout {
+		deadline = now.Add(h.maxTotalDuration)
+	}
+	if !h.usesDeadlineHeader() {
+		return deadline
+	}
+
+	value := r.Header.Get(deadlineHeader)
+	if value == "" {
+		return deadline
+	}
+	incoming, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		h.logger.Warn("Ignoring invalid deadline header", slog.String("value", value), slog.String("error", err.Error()))
+		return deadline
+	}
+	if incoming.Before(deadline) {
+		return incoming
+	}
+	return deadline
+}
+
+// formatDeadline renders a deadline for the X-Deadline header
+func formatDeadline(deadline time.Time) string {
+	return deadline.UTC().Format(time.RFC3339Nano)
+}
+
+// delay waits for d before the remaining path is processed. If the wait would overrun the
+// request deadline it answers 504 immediately instead and returns false.
+func (h *Handler) delay(ctx context.Context, w http.ResponseWriter, r *http.Request, d time.Duration, logger *slog.Logger) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		logger.Info("Delay would exceed deadline", slog.Duration("delay", d), slog.Time("deadline", deadline))
+		h.writeError(w, r, http.StatusGatewayTimeout, ErrCodeGatewayTimeout, fmt.Sprintf("Delay of %s would exceed the request deadline", d), logger)
+		return false
+	}
+
+	logger.Info("Delaying request", slog.Duration("delay", d))
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		logger.Info("Request ended during delay", slog.String("error", ctx.Err().Error()))
+		h.writeError(w, r, http.StatusGatewayTimeout, ErrCodeGatewayTimeout, "Request deadline exceeded during delay", logger)
+		return false
+	}
+}
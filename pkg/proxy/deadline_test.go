@@ -0,0 +1,156 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestDeadline(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		propagate bool
+		maxTotal  time.Duration
+		header    string
+		want      time.Time
+	}{
+		{
+			name:      "no header uses timeout",
+			propagate: true,
+			want:      now.Add(time.Second),
+		},
+		{
+			name:      "earlier header wins",
+			propagate: true,
+			header:    formatDeadline(now.Add(200 * time.Millisecond)),
+			want:      now.Add(200 * time.Millisecond),
+		},
+		{
+			name:      "later header is capped by timeout",
+			propagate: true,
+			header:    formatDeadline(now.Add(time.Minute)),
+			want:      now.Add(time.Second),
+		},
+		{
+			name:      "invalid header is ignored",
+			propagate: true,
+			header:    "tomorrow",
+			want:      now.Add(time.Second),
+		},
+		{
+			name:   "header ignored when propagation disabled",
+			header: formatDeadline(now.Add(200 * time.Millisecond)),
+			want:   now.Add(time.Second),
+		},
+		{
+			name:     "max total shorter than timeout",
+			maxTotal: 300 * time.Millisecond,
+			want:     now.Add(300 * time.Millisecond),
+		},
+		{
+			name:     "max total longer than timeout",
+			maxTotal: time.Minute,
+			want:     now.Add(time.Second),
+		},
+		{
+			name:     "max total honours an earlier header",
+			maxTotal: 300 * time.Millisecond,
+			header:   formatDeadline(now.Add(100 * time.Millisecond)),
+			want:     now.Add(100 * time.Millisecond),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := NewHandler(time.Second, "test-service", createTestLogger(), WithDeadlinePropagation(tt.propagate), WithMaxTotalDuration(tt.maxTotal))
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(deadlineHeader, tt.header)
+			}
+			assert.True(t, tt.want.Equal(h.requestDeadline(req, now, time.Second)))
+		})
+	}
+}
+
+func TestDeadlinePropagation(t *testing.T) {
+	logger := createTestLogger()
+
+	newService := func(t *testing.T, name string) string {
+		t.Helper()
+		h, err := NewHandler(time.Second, name, logger, WithDeadlinePropagation(true))
+		require.NoError(t, err)
+		server := httptest.NewServer(h)
+		t.Cleanup(server.Close)
+		return strings.TrimPrefix(server.URL, "http://")
+	}
+
+	t.Run("deadline header is forwarded", func(t *testing.T) {
+		var received string
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r.Header.Get(deadlineHeader)
+		}))
+		defer upstream.Close()
+
+		h, err := NewHandler(time.Second, "entry", logger, WithDeadlinePropagation(true))
+		require.NoError(t, err)
+
+		start := time.Now()
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/proxy/"+strings.TrimPrefix(upstream.URL, "http://"), nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		deadline, err := time.Parse(time.RFC3339Nano, received)
+		require.NoError(t, err)
+		assert.WithinDuration(t, start.Add(time.Second), deadline, 100*time.Millisecond)
+	})
+
+	t.Run("delays within the budget succeed", func(t *testing.T) {
+		svcb := newService(t, "svcb")
+		svca := newService(t, "svca")
+
+		resp, err := http.Get("http://" + svca + "/delay/100/proxy/" + svcb + "/delay/100")
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("hop that would exceed the deadline returns 504 early", func(t *testing.T) {
+		svcb := newService(t, "svcb")
+		svca := newService(t, "svca")
+
+		start := time.Now()
+		resp, err := http.Get("http://" + svca + "/delay/400/proxy/" + svcb + "/delay/400/delay/400")
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+
+		assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+		assert.Less(t, time.Since(start), time.Second, "should fail before the deadline elapses")
+
+		var body ErrorResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, ErrCodeGatewayTimeout, body.Code)
+		assert.Equal(t, "svcb", body.Service)
+	})
+
+	t.Run("delay past the timeout returns 504 without waiting", func(t *testing.T) {
+		h, err := NewHandler(time.Second, "test-service", logger)
+		require.NoError(t, err)
+
+		start := time.Now()
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/delay/5000", nil))
+
+		assert.Equal(t, http.StatusGatewayTimeout, rr.Code)
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+	})
+}
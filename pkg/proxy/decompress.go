@@ -0,0 +1,79 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// WithDecompressRequests enables transparent decompression of gzip request bodies, so hops that
+// cannot handle Content-Encoding: gzip receive the plain body
+func WithDecompressRequests(enabled bool) HandlerOption {
+	return func(h *Handler) {
+		h.decompressRequests = enabled
+	}
+}
+
+// isGzipEncoded reports whether the request body is gzip encoded and nothing else. Stacked
+// encodings such as "gzip, br" are left alone since only the gzip layer could be removed.
+func isGzipEncoded(r *http.Request) bool {
+	encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+	return encoding == "gzip" || encoding == "x-gzip"
+}
+
+// errDecompressedTooLarge is returned by gunzip when the decompressed body runs past its limit
+var errDecompressedTooLarge = errors.New("decompressed body too large")
+
+// decompressBody replaces a gzip request body with its decompressed content, updating the
+// Content-Length and dropping Content-Encoding so the request is forwarded as if sent uncompressed.
+// A body that is not valid gzip gets a 400 (408 if it arrives too slowly), one that decompresses to
+// more than the max payload bytes gets a 413, and false is returned.
+func (h *Handler) decompressBody(w http.ResponseWriter, r *http.Request) bool {
+	body, err := gunzip(r.Body, h.maxPayloadBytes)
+	_ = r.Body.Close()
+	if errors.Is(err, errBodyReadTimeout) {
+		h.writeBodyReadError(w, r, err, h.logger)
+		return false
+	}
+	if errors.Is(err, errDecompressedTooLarge) {
+		h.logger.Info("Decompressed request body too large", slog.Int64("compressed_bytes", r.ContentLength), slog.Int64("max_payload_bytes", h.maxPayloadBytes))
+		h.writeError(w, r, http.StatusRequestEntityTooLarge, ErrCodePayloadTooLarge, fmt.Sprintf("Decompressed request body exceeds the maximum of %d bytes", h.maxPayloadBytes), h.logger)
+		return false
+	}
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("Failed to decompress gzip request body: %v", err), h.logger)
+		return false
+	}
+
+	h.logger.Debug("Decompressed gzip request body", slog.Int64("compressed_bytes", r.ContentLength), slog.Int("bytes", len(body)))
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	r.Header.Del("Content-Encoding")
+	return true
+}
+
+// gunzip reads a gzip stream in full, including any concatenated members, failing with
+// errDecompressedTooLarge rather than holding more than limit decompressed bytes
+func gunzip(body io.Reader, limit int64) ([]byte, error) {
+	zr, err := gzip.NewReader(body)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = zr.Close() }()
+
+	decompressed, err := io.ReadAll(io.LimitReader(zr, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(decompressed)) > limit {
+		return nil, errDecompressedTooLarge
+	}
+	return decompressed, nil
+}
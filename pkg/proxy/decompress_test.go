@@ -0,0 +1,120 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecompressRequests(t *testing.T) {
+	var received *http.Request
+	var receivedBody string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received, receivedBody = r, string(body)
+	}))
+	defer upstream.Close()
+	target := strings.TrimPrefix(upstream.URL, "http://")
+
+	gzipped := func(t *testing.T, s string) []byte {
+		t.Helper()
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, err := zw.Write([]byte(s))
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+		return buf.Bytes()
+	}
+
+	post := func(t *testing.T, handler *Handler, body []byte, encoding string) *httptest.ResponseRecorder {
+		t.Helper()
+		received, receivedBody = nil, ""
+		req := httptest.NewRequest(http.MethodPost, "/proxy/"+target, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", encoding)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	payload := `{"message":"hello","items":[1,2,3]}`
+
+	handler, err := NewHandler(30*time.Second, "test-service", createTestLogger(),
+		WithDecompressRequests(true), WithPropagateRequestHeaders(true))
+	require.NoError(t, err)
+
+	t.Run("upstream receives decompressed body", func(t *testing.T) {
+		rr := post(t, handler, gzipped(t, payload), "gzip")
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.NotNil(t, received)
+		assert.Equal(t, payload, receivedBody)
+		assert.Equal(t, int64(len(payload)), received.ContentLength)
+		assert.Empty(t, received.Header.Get("Content-Encoding"))
+	})
+
+	t.Run("other encodings pass through", func(t *testing.T) {
+		rr := post(t, handler, []byte("opaque"), "br")
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.NotNil(t, received)
+		assert.Equal(t, "opaque", receivedBody)
+		assert.Equal(t, "br", received.Header.Get("Content-Encoding"))
+	})
+
+	t.Run("invalid gzip returns 400", func(t *testing.T) {
+		rr := post(t, handler, []byte("not gzip"), "gzip")
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Nil(t, received, "request should not be forwarded")
+	})
+
+	t.Run("gzip bomb returns 413", func(t *testing.T) {
+		handler, err := NewHandler(30*time.Second, "test-service", createTestLogger(),
+			WithDecompressRequests(true), WithMaxPayloadBytes(1<<20))
+		require.NoError(t, err)
+
+		// About 128 KiB of gzip that expands to 64 MiB of zeros
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zeros := make([]byte, 1<<20)
+		for range 64 {
+			_, err := zw.Write(zeros)
+			require.NoError(t, err)
+		}
+		require.NoError(t, zw.Close())
+		require.Less(t, buf.Len(), 1<<18)
+
+		rr := post(t, handler, buf.Bytes(), "gzip")
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		assert.Equal(t, ErrCodePayloadTooLarge, decodeErrorResponse(t, rr).Code)
+		assert.Nil(t, received, "request should not be forwarded")
+	})
+
+	t.Run("at the limit is decompressed", func(t *testing.T) {
+		handler, err := NewHandler(30*time.Second, "test-service", createTestLogger(),
+			WithDecompressRequests(true), WithMaxPayloadBytes(int64(len(payload))))
+		require.NoError(t, err)
+
+		rr := post(t, handler, gzipped(t, payload), "gzip")
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, payload, receivedBody)
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		handler, err := NewHandler(30*time.Second, "test-service", createTestLogger(), WithPropagateRequestHeaders(true))
+		require.NoError(t, err)
+
+		body := gzipped(t, payload)
+		rr := post(t, handler, body, "gzip")
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.NotNil(t, received)
+		assert.Equal(t, string(body), receivedBody)
+		assert.Equal(t, "gzip", received.Header.Get("Content-Encoding"))
+	})
+}
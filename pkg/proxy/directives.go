@@ -0,0 +1,59 @@
+package proxy
+
+// Directive documents a path directive understood by the proxy handler, for generated API docs
+type Directive struct {
+	Prefix   string // Path prefix that starts the directive, e.g. "/fault/"
+	Format   string // Path template with {parameters}, e.g. "/fault/{code}"
+	Summary  string // One-line description
+	Example  string // Example path using the directive
+	Terminal bool   // Whether the directive must be the last one in the path
+}
+
+// directives lists every directive in directivePrefixes, in the same order
+var directives = []Directive{
+	{Prefix: "/proxy/", Format: "/proxy/{target}", Example: "/proxy/service-b:8080",
+		Summary: "Forward the rest of the path to target (host:port, optionally prefixed with http:// or https://)"},
+	{Prefix: "/fault/", Format: "/fault/{code}", Example: "/fault/503/30/match/x-canary=true",
+		Summary: "Answer with status code (400-599) or badjson, optionally followed by /{percentage} and /match/{header}={value}"},
+	{Prefix: "/fanout/", Format: "/fanout/{targets}", Example: "/fanout/service-b:8080,service-c:8080",
+		Summary: "Forward the rest of the path to every comma-separated target concurrently and merge the responses"},
+	{Prefix: "/try/", Format: "/try/{targets}", Example: "/try/service-b:8080,service-c:8080",
+		Summary: "Forward the rest of the path to comma-separated targets in random order until one answers below 500"},
+	{Prefix: "/delay/", Format: "/delay/{ms}", Example: "/delay/250",
+		Summary: "Wait ms milliseconds before processing the rest of the path"},
+	{Prefix: "/slowstart/", Format: "/slowstart/{seconds}/{ms}", Example: "/slowstart/60/500",
+		Summary: "Wait up to ms milliseconds, decreasing to zero over the first seconds after server start"},
+	{Prefix: "/latency/", Format: "/latency/{percentiles}", Example: "/latency/20,80,200",
+		Summary: "Wait for a delay sampled per request from a distribution with the given p50,p95,p99 in milliseconds"},
+	{Prefix: "/bytes/", Format: "/bytes/{n}", Example: "/bytes/1024", Terminal: true,
+		Summary: "Answer with n random bytes"},
+	{Prefix: "/ctype/", Format: "/ctype/{type}/{subtype}", Example: "/ctype/text/html",
+		Summary: "Declare the final response with the given media type"},
+	{Prefix: "/reset/", Format: "/reset/{n}", Example: "/reset/100", Terminal: true,
+		Summary: "Write n bytes of the response, then reset the connection"},
+	{Prefix: "/multipart/", Format: "/multipart/{parts}", Example: "/multipart/3", Terminal: true,
+		Summary: "Answer with a multipart/mixed response of JSON parts"},
+	{Prefix: "/trailer/", Format: "/trailer/{name}/{value}", Example: "/trailer/x-checksum/abc",
+		Summary: "Send a trailer after this hop's response body"},
+	{Prefix: "/setcookie/", Format: "/setcookie/{name}/{value}", Example: "/setcookie/session/abc123",
+		Summary: "Set a cookie on this hop's response, with path, max-age, httponly, secure and samesite taken from the query"},
+	{Prefix: "/seq/", Format: "/seq/{statuses}", Example: "/seq/503,503,200",
+		Summary: "Answer successive requests for the same path with each comma-separated status in turn, repeating"},
+	{Prefix: "/chunked", Format: "/chunked", Example: "/chunked", Terminal: true,
+		Summary: "Stream the final response in small flushed chunks with chunked transfer encoding"},
+	{Prefix: "/grpc-status/", Format: "/grpc-status/{code}", Example: "/grpc-status/14", Terminal: true,
+		Summary: "Answer 200 application/grpc with the gRPC status code (0-16) in the trailers"},
+	{Prefix: "/slowbody/", Format: "/slowbody/{ms}", Example: "/slowbody/100", Terminal: true,
+		Summary: "Write the final response one byte at a time, ms milliseconds apart"},
+	{Prefix: "/flaky", Format: "/flaky/{n}", Example: "/flaky/3",
+		Summary: "Fail the first of every n requests for the same path with 500 and let the rest continue; n defaults to 2"},
+	{Prefix: "/proxys/", Format: "/proxys/{target}", Example: "/proxys/service-b:8443",
+		Summary: "Forward the rest of the path to target (host:port) over HTTPS"},
+	{Prefix: "/repeat/", Format: "/repeat/{n}", Example: "/repeat/5/proxy/service-b:8080",
+		Summary: "Send the request to the next /proxy/ hop n times in turn and answer with the last response"},
+}
+
+// Directives returns the path directives supported by this build
+func Directives() []Directive {
+	return append([]Directive(nil), directives...)
+}
@@ -0,0 +1,26 @@
+package proxy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirectivesMatchParser(t *testing.T) {
+	require.Len(t, directives, len(directivePrefixes), "every directive prefix needs a Directive entry")
+
+	for i, d := range Directives() {
+		t.Run(d.Prefix, func(t *testing.T) {
+			assert.Equal(t, directivePrefixes[i], d.Prefix)
+			assert.NotEmpty(t, d.Summary)
+
+			result := inspectPath(d.Example)
+			require.Empty(t, result.Error, "example %s must parse", d.Example)
+			require.NotEmpty(t, result.Steps)
+			last := result.Steps[len(result.Steps)-1]
+			isTerminal := last.IsBytes || last.IsReset || last.IsChunked || last.IsSlowBody || last.IsGRPCStatus || last.MultipartParts > 0
+			assert.Equal(t, d.Terminal, isTerminal)
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// dnsNegativeCacheTTL caps how long a hostname that does not exist is remembered, so a service
+// that comes up shortly after a failed lookup is not hidden for the whole TTL
+const dnsNegativeCacheTTL = 5 * time.Second
+
+// WithDNSCacheTTL caches the IPs upstream hostnames resolve to for ttl, so repeated hops to the
+// same service skip the DNS lookup. Zero disables the cache.
+func WithDNSCacheTTL(ttl time.Duration) HandlerOption {
+	return func(h *Handler) {
+		h.dnsCacheTTL = ttl
+	}
+}
+
+// dialFunc dials an upstream address, as http.Transport.DialContext does
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// hostResolver looks up a hostname's IPs; *net.Resolver satisfies it
+type hostResolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// dnsEntry is a cached lookup: the IPs a hostname resolved to, or the error for a hostname that
+// does not exist
+type dnsEntry struct {
+	ips     []string
+	err     error
+	expires time.Time
+}
+
+// dnsCache resolves hostnames through resolver, remembering each answer until its TTL passes.
+// Concurrent lookups of the same hostname share a single query.
+type dnsCache struct {
+	resolver hostResolver
+	ttl      time.Duration
+	now      func() time.Time
+	lookups  singleflight.Group
+
+	mu      sync.Mutex
+	entries map[string]dnsEntry
+}
+
+// newDNSCache creates a cache holding answers from resolver for ttl
+func newDNSCache(resolver hostResolver, ttl time.Duration) *dnsCache {
+	return &dnsCache{
+		resolver: resolver,
+		ttl:      ttl,
+		now:      time.Now,
+		entries:  make(map[string]dnsEntry),
+	}
+}
+
+// lookup returns the IPs for host, resolving it only when there is no unexpired cached answer
+func (c *dnsCache) lookup(ctx context.Context, host string) ([]string, error) {
+	host = strings.ToLower(host)
+	c.mu.Lock()
+	entry, ok := c.entries[host]
+	c.mu.Unlock()
+	if ok && c.now().Before(entry.expires) {
+		return entry.ips, entry.err
+	}
+
+	// The shared query outlives any one caller, who can still stop waiting for it
+	result := c.lookups.DoChan(host, func() (any, error) {
+		return c.refresh(context.WithoutCancel(ctx), host)
+	})
+	select {
+	case res := <-result:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.([]string), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// refresh resolves host and caches the answer. A hostname that does not exist is cached for at
+// most dnsNegativeCacheTTL. Other failures, such as an unreachable DNS server, are not cached;
+// when the hostname has resolved before, its last IPs are served for a while longer instead.
+func (c *dnsCache) refresh(ctx context.Context, host string) ([]string, error) {
+	addrs, err := c.resolver.LookupIPAddr(ctx, host)
+	now := c.now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var dnsErr *net.DNSError
+	switch {
+	case err == nil && len(addrs) > 0:
+		ips := make([]string, len(addrs))
+		for i, addr := range addrs {
+			ips[i] = addr.IP.String()
+		}
+		c.entries[host] = dnsEntry{ips: ips, expires: now.Add(c.ttl)}
+		return ips, nil
+	case err == nil:
+		err = &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+		c.entries[host] = dnsEntry{err: err, expires: now.Add(min(c.ttl, dnsNegativeCacheTTL))}
+		return nil, err
+	case errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+		c.entries[host] = dnsEntry{err: err, expires: now.Add(min(c.ttl, dnsNegativeCacheTTL))}
+		return nil, err
+	}
+
+	if stale, ok := c.entries[host]; ok && len(stale.ips) > 0 {
+		stale.expires = now.Add(min(c.ttl, dnsNegativeCacheTTL))
+		c.entries[host] = stale
+		return stale.ips, nil
+	}
+	return nil, err
+}
+
+// dialContext returns a dial function that resolves hostnames through the cache, then dials
+// their IPs in turn until one connects. IP addresses are dialed directly.
+func (c *dnsCache) dialContext(dial dialFunc) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || net.ParseIP(host) != nil {
+			return dial(ctx, network, addr)
+		}
+
+		ips, err := c.lookup(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		var firstErr error
+		for _, ip := range ips {
+			conn, err := dial(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		return nil, firstErr
+	}
+}
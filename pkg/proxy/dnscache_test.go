@@ -0,0 +1,159 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingResolver answers every lookup with its current result and counts the lookups per host
+type countingResolver struct {
+	mu      sync.Mutex
+	ips     []string
+	err     error
+	lookups map[string]int
+}
+
+func (r *countingResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.lookups == nil {
+		r.lookups = make(map[string]int)
+	}
+	r.lookups[host]++
+	if r.err != nil {
+		return nil, r.err
+	}
+	addrs := make([]net.IPAddr, len(r.ips))
+	for i, ip := range r.ips {
+		addrs[i] = net.IPAddr{IP: net.ParseIP(ip)}
+	}
+	return addrs, nil
+}
+
+func (r *countingResolver) set(ips []string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ips, r.err = ips, err
+}
+
+func (r *countingResolver) count(host string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lookups[host]
+}
+
+func TestDNSCache(t *testing.T) {
+	ctx := context.Background()
+	newCache := func(resolver hostResolver, ttl time.Duration) (*dnsCache, *time.Time) {
+		now := time.Unix(1700000000, 0)
+		cache := newDNSCache(resolver, ttl)
+		cache.now = func() time.Time { return now }
+		return cache, &now
+	}
+
+	t.Run("second lookup within the TTL is cached", func(t *testing.T) {
+		resolver := &countingResolver{ips: []string{"10.0.0.1"}}
+		cache, now := newCache(resolver, time.Minute)
+
+		ips, err := cache.lookup(ctx, "svc.test")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"10.0.0.1"}, ips)
+
+		*now = now.Add(59 * time.Second)
+		ips, err = cache.lookup(ctx, "SVC.test")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"10.0.0.1"}, ips)
+		assert.Equal(t, 1, resolver.count("svc.test"))
+	})
+
+	t.Run("refreshes once the TTL passes", func(t *testing.T) {
+		resolver := &countingResolver{ips: []string{"10.0.0.1"}}
+		cache, now := newCache(resolver, time.Minute)
+
+		_, err := cache.lookup(ctx, "svc.test")
+		require.NoError(t, err)
+		resolver.set([]string{"10.0.0.2"}, nil)
+		*now = now.Add(time.Minute)
+
+		ips, err := cache.lookup(ctx, "svc.test")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"10.0.0.2"}, ips)
+		assert.Equal(t, 2, resolver.count("svc.test"))
+	})
+
+	t.Run("missing hosts are cached briefly", func(t *testing.T) {
+		resolver := &countingResolver{err: &net.DNSError{Err: "no such host", Name: "gone.test", IsNotFound: true}}
+		cache, now := newCache(resolver, time.Minute)
+
+		_, err := cache.lookup(ctx, "gone.test")
+		require.Error(t, err)
+		_, err = cache.lookup(ctx, "gone.test")
+		require.Error(t, err)
+		assert.Equal(t, 1, resolver.count("gone.test"))
+
+		// The host appears once the negative entry expires, well within the TTL
+		resolver.set([]string{"10.0.0.3"}, nil)
+		*now = now.Add(dnsNegativeCacheTTL)
+		ips, err := cache.lookup(ctx, "gone.test")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"10.0.0.3"}, ips)
+	})
+
+	t.Run("transient failures serve the last answer", func(t *testing.T) {
+		resolver := &countingResolver{ips: []string{"10.0.0.1"}}
+		cache, now := newCache(resolver, time.Minute)
+
+		_, err := cache.lookup(ctx, "svc.test")
+		require.NoError(t, err)
+		resolver.set(nil, &net.DNSError{Err: "server misbehaving", Name: "svc.test", IsTemporary: true})
+		*now = now.Add(time.Minute)
+
+		ips, err := cache.lookup(ctx, "svc.test")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"10.0.0.1"}, ips)
+	})
+
+	t.Run("transient failures are not cached", func(t *testing.T) {
+		resolver := &countingResolver{err: errors.New("connection refused")}
+		cache, _ := newCache(resolver, time.Minute)
+
+		_, err := cache.lookup(ctx, "new.test")
+		require.Error(t, err)
+		resolver.set([]string{"10.0.0.4"}, nil)
+		ips, err := cache.lookup(ctx, "new.test")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"10.0.0.4"}, ips)
+	})
+}
+
+func TestDNSCacheDial(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer upstream.Close()
+	_, port, err := net.SplitHostPort(upstream.Listener.Addr().String())
+	require.NoError(t, err)
+
+	resolver := &countingResolver{ips: []string{"127.0.0.1"}}
+	handler, err := NewHandler(5*time.Second, "test-service", createTestLogger(), WithDNSCacheTTL(time.Minute))
+	require.NoError(t, err)
+	dialer := &net.Dialer{Timeout: time.Second}
+	transport := handler.client.Transport.(*http.Transport)
+	require.NotNil(t, transport.DialContext, "the cache should be installed on the transport")
+	transport.DialContext = newDNSCache(resolver, time.Minute).dialContext(dialer.DialContext)
+	transport.DisableKeepAlives = true
+
+	for range 3 {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/proxy/cached.test:"+port, nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+	}
+	assert.Equal(t, 1, resolver.count("cached.test"), "each new connection should reuse the cached lookup")
+}
@@ -0,0 +1,112 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Stable, machine-readable error codes returned in ErrorResponse.Code
+const (
+	ErrCodeBadPath              = "PROXY_BAD_PATH"
+	ErrCodeNotFound             = "PROXY_NOT_FOUND"
+	ErrCodeBadRequest           = "PROXY_BAD_REQUEST"
+	ErrCodeBadGateway           = "PROXY_BAD_GATEWAY"
+	ErrCodeGatewayTimeout       = "PROXY_GATEWAY_TIMEOUT"
+	ErrCodeInternal             = "PROXY_INTERNAL_ERROR"
+	ErrCodeNotAcceptable        = "PROXY_NOT_ACCEPTABLE"
+	ErrCodeMethodNotAllowed     = "PROXY_METHOD_NOT_ALLOWED"
+	ErrCodeRateLimited          = "PROXY_RATE_LIMITED"
+	ErrCodeOverloaded           = "PROXY_OVERLOADED"
+	ErrCodeRequestTimeout       = "PROXY_REQUEST_TIMEOUT"
+	ErrCodeUnauthorized         = "PROXY_UNAUTHORIZED"
+	ErrCodeDraining             = "PROXY_DRAINING"
+	ErrCodeHeadersTooLarge      = "PROXY_HEADERS_TOO_LARGE"
+	ErrCodeIdempotencyKeyReused = "PROXY_IDEMPOTENCY_KEY_REUSED"
+	ErrCodePayloadTooLarge      = "PROXY_PAYLOAD_TOO_LARGE"
+)
+
+// ErrorResponse represents the error response format
+type ErrorResponse struct {
+	Error   string `json:"error"`
+	Code    string `json:"code"`
+	Service string `json:"service"`
+	// Directives lists the valid directive prefixes when the path matched none of them
+	Directives []string `json:"directives,omitempty"`
+}
+
+// writeError sends an ErrorResponse with the given status and error code, formatted for the
+// request's Accept header
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, statusCode int, code, message string, logger *slog.Logger) {
+	h.writeErrorResponse(w, r, statusCode, ErrorResponse{Error: message, Code: code}, logger)
+}
+
+// writePathError answers a path that failed to parse: 404 listing the directive prefixes when
+// the path names no directive, 400 when a known directive is malformed
+func (h *Handler) writePathError(w http.ResponseWriter, r *http.Request, err error, logger *slog.Logger) {
+	if !errors.Is(err, errUnknownRoute) {
+		h.writeError(w, r, http.StatusBadRequest, ErrCodeBadPath, err.Error(), logger)
+		return
+	}
+	h.writeErrorResponse(w, r, http.StatusNotFound, ErrorResponse{
+		Error:      err.Error(),
+		Code:       ErrCodeNotFound,
+		Directives: directivePrefixes,
+	}, logger)
+}
+
+// writeDecodeError answers a JSON request body that could not be decoded: 413 when it ran past
+// the limit set with http.MaxBytesReader, 400 otherwise. what names the document, e.g. "topology spec".
+func (h *Handler) writeDecodeError(w http.ResponseWriter, r *http.Request, err error, what string, logger *slog.Logger) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		h.writeError(w, r, http.StatusRequestEntityTooLarge, ErrCodePayloadTooLarge, fmt.Sprintf("Invalid %s: body exceeds the maximum of %d bytes", what, tooLarge.Limit), logger)
+		return
+	}
+	h.writeError(w, r, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("Invalid %s: %v", what, err), logger)
+}
+
+// writeErrorResponse sends response with the given status, filling in the service name
+func (h *Handler) writeErrorResponse(w http.ResponseWriter, r *http.Request, statusCode int, response ErrorResponse, logger *slog.Logger) {
+	response.Service = h.serviceName
+	if err := WriteError(w, r, statusCode, response); err != nil {
+		logger.Error("Failed to write error response", slog.String("error", err.Error()))
+	}
+}
+
+// WriteError sends response with the given status as JSON, or as a line of plain text to clients
+// that prefer text/plain. Clients accepting neither, or only XML, get JSON. The built-in
+// endpoints served beside the proxy handler use it so every error has the same shape.
+func WriteError(w http.ResponseWriter, r *http.Request, statusCode int, response ErrorResponse) error {
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if mediaType, ok := negotiateMediaType(r.Header.Get("Accept")); ok && mediaType == mediaTypeText {
+		w.Header().Set("Content-Type", mediaTypeText+"; charset=utf-8")
+		w.WriteHeader(statusCode)
+		line := fmt.Sprintf("%s: %s (service %s)\n", response.Code, response.Error, response.Service)
+		if len(response.Directives) > 0 {
+			line += "Valid directives: " + strings.Join(response.Directives, " ") + "\n"
+		}
+		_, err := io.WriteString(w, line)
+		return err
+	}
+
+	w.Header().Set("Content-Type", mediaTypeJSON)
+	w.WriteHeader(statusCode)
+	return json.NewEncoder(w).Encode(response)
+}
+
+// isTimeout reports whether err was caused by a deadline being exceeded
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,154 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decodeErrorResponse asserts the recorder holds a JSON ErrorResponse and returns it
+func decodeErrorResponse(t *testing.T, rr *httptest.ResponseRecorder) ErrorResponse {
+	t.Helper()
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var resp ErrorResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp), "body: %s", rr.Body.String())
+	return resp
+}
+
+func TestErrorResponses(t *testing.T) {
+	logger := createTestLogger()
+
+	t.Run("parse error", func(t *testing.T) {
+		handler, err := NewHandler(30*time.Second, "test-service", logger)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/fault/abc", nil))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		resp := decodeErrorResponse(t, rr)
+		assert.Equal(t, ErrCodeBadPath, resp.Code)
+		assert.Equal(t, "test-service", resp.Service)
+		assert.Contains(t, resp.Error, "invalid fault code")
+	})
+
+	t.Run("unknown routes are not found", func(t *testing.T) {
+		handler, err := NewHandler(30*time.Second, "test-service", logger)
+		require.NoError(t, err)
+
+		for _, path := range []string{"/api/users", "/favicon.ico", "/proxyx/svc:8080", "/delay/10/api"} {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+
+			assert.Equal(t, http.StatusNotFound, rr.Code, path)
+			resp := decodeErrorResponse(t, rr)
+			assert.Equal(t, ErrCodeNotFound, resp.Code, path)
+			assert.Equal(t, directivePrefixes, resp.Directives, path)
+		}
+	})
+
+	t.Run("malformed directives are bad requests", func(t *testing.T) {
+		handler, err := NewHandler(30*time.Second, "test-service", logger)
+		require.NoError(t, err)
+
+		for _, path := range []string{"/fault", "/proxy", "/proxy/", "/delay/soon", "/bytes/-1", "/chunked/proxy/svc:8080"} {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code, path)
+			resp := decodeErrorResponse(t, rr)
+			assert.Equal(t, ErrCodeBadPath, resp.Code, path)
+			assert.Empty(t, resp.Directives, path)
+		}
+	})
+
+	t.Run("next hop connection failure", func(t *testing.T) {
+		handler, err := NewHandler(30*time.Second, "test-service", logger)
+		require.NoError(t, err)
+
+		// Reserve a port and close it so nothing is listening there
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := ln.Addr().String()
+		require.NoError(t, ln.Close())
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/proxy/"+addr, nil))
+
+		assert.Equal(t, http.StatusBadGateway, rr.Code)
+		resp := decodeErrorResponse(t, rr)
+		assert.Equal(t, ErrCodeBadGateway, resp.Code)
+		assert.Equal(t, "test-service", resp.Service)
+		assert.NotEmpty(t, resp.Error)
+	})
+
+	t.Run("next hop timeout", func(t *testing.T) {
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+			}
+		}))
+		defer upstream.Close()
+
+		handler, err := NewHandler(50*time.Millisecond, "test-service", logger)
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/proxy/"+strings.TrimPrefix(upstream.URL, "http://"), nil))
+
+		assert.Equal(t, http.StatusGatewayTimeout, rr.Code)
+		resp := decodeErrorResponse(t, rr)
+		assert.Equal(t, ErrCodeGatewayTimeout, resp.Code)
+	})
+}
+
+func TestErrorFormat(t *testing.T) {
+	handler, err := NewHandler(30*time.Second, "test-service", createTestLogger())
+	require.NoError(t, err)
+
+	get := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("JSON for clients accepting JSON", func(t *testing.T) {
+		for _, accept := range []string{"application/json", "", "*/*", "application/xml", "image/png"} {
+			rr := get("/fault/abc", accept)
+			assert.Equal(t, http.StatusBadRequest, rr.Code, accept)
+			resp := decodeErrorResponse(t, rr)
+			assert.Equal(t, ErrCodeBadPath, resp.Code, accept)
+			assert.Equal(t, "test-service", resp.Service, accept)
+			assert.Equal(t, "nosniff", rr.Header().Get("X-Content-Type-Options"), accept)
+		}
+	})
+
+	t.Run("plain text for clients preferring text", func(t *testing.T) {
+		rr := get("/fault/abc", "text/plain, application/json;q=0.5")
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, "text/plain; charset=utf-8", rr.Header().Get("Content-Type"))
+		assert.Regexp(t, `^PROXY_BAD_PATH: invalid fault code.* \(service test-service\)\n$`, rr.Body.String())
+	})
+
+	t.Run("plain text lists directives for unknown routes", func(t *testing.T) {
+		rr := get("/api/users", "text/plain")
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		lines := strings.Split(strings.TrimSuffix(rr.Body.String(), "\n"), "\n")
+		require.Len(t, lines, 2, rr.Body.String())
+		assert.True(t, strings.HasPrefix(lines[0], ErrCodeNotFound+": "), lines[0])
+		assert.Equal(t, "Valid directives: "+strings.Join(directivePrefixes, " "), lines[1])
+	})
+}
@@ -0,0 +1,87 @@
+package proxy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// WithETag enables weak ETags on final responses, answering 304 Not Modified when the
+// request's If-None-Match already names the current response
+func WithETag(enabled bool) HandlerOption {
+	return func(h *Handler) {
+		h.etag = enabled
+	}
+}
+
+// weakETag returns a weak entity tag derived from body
+func weakETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match value matches etag using the weak comparison
+// RFC 9110 requires for If-None-Match
+func etagMatches(ifNoneMatch, etag string) bool {
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == want {
+			return true
+		}
+	}
+	return false
+}
+
+// writeFinalBody writes the status and body of a final response. With ETags or Last-Modified
+// enabled, a 200 carries the validators and is replaced by a bodiless 304 when the client's copy
+// is current. Per-KiB latency is applied, bounded by ctx's deadline, and any checksum header set
+// before the status is written. A response whose deadline passes while it waits gets a 504 instead.
+func (h *Handler) writeFinalBody(ctx context.Context, w http.ResponseWriter, r *http.Request, statusCode int, body []byte, logger *slog.Logger) error {
+	h.stampHop(w)
+
+	if statusCode == http.StatusOK && h.notModified(w, r, body, logger) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	if err := h.waitForSize(ctx, int64(len(body)), logger); err != nil {
+		h.writeSizeWaitError(w, r, err, logger)
+		return nil
+	}
+
+	h.setChecksum(w, body)
+
+	w.WriteHeader(statusCode)
+	_, err := w.Write(body)
+	return err
+}
+
+// notModified sets the enabled validators for body and reports whether the request's conditional
+// headers show the client's copy is current. If-Modified-Since is only consulted without an
+// If-None-Match, which takes precedence under RFC 9110.
+func (h *Handler) notModified(w http.ResponseWriter, r *http.Request, body []byte, logger *slog.Logger) bool {
+	if !h.lastModified.IsZero() {
+		w.Header().Set("Last-Modified", h.lastModified.Format(http.TimeFormat))
+	}
+
+	if h.etag {
+		etag := weakETag(body)
+		w.Header().Set("ETag", etag)
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			logger.Debug("ETag matched, responding not modified", slog.String("etag", etag))
+			return true
+		}
+	}
+
+	if !h.lastModified.IsZero() && r.Header.Get("If-None-Match") == "" && notModifiedSince(r, h.lastModified) {
+		logger.Debug("Not modified since If-Modified-Since, responding not modified", slog.String("if_modified_since", r.Header.Get("If-Modified-Since")))
+		return true
+	}
+	return false
+}
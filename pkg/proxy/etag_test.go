@@ -0,0 +1,67 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestETag(t *testing.T) {
+	get := func(t *testing.T, handler *Handler, path, ifNoneMatch string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	handler, err := NewHandler(30*time.Second, "test-service", createTestLogger(), WithETag(true))
+	require.NoError(t, err)
+
+	first := get(t, handler, "/", "")
+	require.Equal(t, http.StatusOK, first.Code)
+	etag := first.Header().Get("ETag")
+	require.Regexp(t, `^W/"[0-9a-f]+"$`, etag)
+	assert.NotEmpty(t, first.Body.String())
+
+	t.Run("matching If-None-Match returns 304 without a body", func(t *testing.T) {
+		rr := get(t, handler, "/", etag)
+		assert.Equal(t, http.StatusNotModified, rr.Code)
+		assert.Equal(t, etag, rr.Header().Get("ETag"))
+		assert.Empty(t, rr.Body.String())
+	})
+
+	t.Run("match within a list and with strong form", func(t *testing.T) {
+		strong := etag[len("W/"):]
+		assert.Equal(t, http.StatusNotModified, get(t, handler, "/", `"other", `+strong).Code)
+		assert.Equal(t, http.StatusNotModified, get(t, handler, "/", "*").Code)
+	})
+
+	t.Run("stale If-None-Match returns the full response", func(t *testing.T) {
+		rr := get(t, handler, "/", `W/"stale"`)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, first.Body.String(), rr.Body.String())
+	})
+
+	t.Run("faults are not cached", func(t *testing.T) {
+		rr := get(t, handler, "/fault/500", "*")
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		assert.Empty(t, rr.Header().Get("ETag"))
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		handler, err := NewHandler(30*time.Second, "test-service", createTestLogger())
+		require.NoError(t, err)
+
+		rr := get(t, handler, "/", etag)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Empty(t, rr.Header().Get("ETag"))
+	})
+}
@@ -0,0 +1,20 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+)
+
+// WithExpectContinue answers Expect: 100-continue with an interim 100 Continue as soon as the
+// request arrives, before any directives run. Without it the server only sends 100 Continue once
+// a hop starts reading the body, so clients wait out their timeout on hops that never read it.
+func WithExpectContinue(enabled bool) HandlerOption {
+	return func(h *Handler) {
+		h.expectContinue = enabled
+	}
+}
+
+// expectsContinue reports whether the client is waiting for 100 Continue before sending its body
+func expectsContinue(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Expect"), "100-continue") && r.ContentLength != 0
+}
@@ -0,0 +1,58 @@
+package proxy
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpectContinue(t *testing.T) {
+	const body = `{"name":"test"}`
+
+	// firstStatus sends only the headers of a request expecting 100-continue to a path that delays
+	// before reading the body, and returns the first status the server answers with
+	firstStatus := func(t *testing.T, opts ...HandlerOption) (*bufio.Reader, net.Conn, int) {
+		t.Helper()
+		h, err := NewHandler(5*time.Second, "test-service", createTestLogger(), opts...)
+		require.NoError(t, err)
+		server := httptest.NewServer(h)
+		t.Cleanup(server.Close)
+
+		conn, err := net.Dial("tcp", server.Listener.Addr().String())
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = conn.Close() })
+		_, err = fmt.Fprintf(conn, "POST /delay/300 HTTP/1.1\r\nHost: test\r\nContent-Type: application/json\r\nContent-Length: %d\r\nExpect: 100-continue\r\n\r\n", len(body))
+		require.NoError(t, err)
+
+		reader := bufio.NewReader(conn)
+		resp, err := http.ReadResponse(reader, nil)
+		require.NoError(t, err)
+		return reader, conn, resp.StatusCode
+	}
+
+	t.Run("interim response is sent before directives run", func(t *testing.T) {
+		start := time.Now()
+		reader, conn, status := firstStatus(t, WithExpectContinue(true))
+		assert.Equal(t, http.StatusContinue, status)
+		assert.Less(t, time.Since(start), 300*time.Millisecond, "100 Continue should not wait for the delay")
+
+		_, err := conn.Write([]byte(body))
+		require.NoError(t, err)
+		resp, err := http.ReadResponse(reader, nil)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		_, _, status := firstStatus(t)
+		assert.Equal(t, http.StatusOK, status, "the final hop answers without asking for the body")
+	})
+}
@@ -0,0 +1,101 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+)
+
+// DefaultMaxFanout is the default limit on targets in a single fanout
+const DefaultMaxFanout = 64
+
+// WithMaxFanout limits how many targets a single /fanout/ or /try/ may name; paths over the limit
+// are rejected as they are parsed, with 400. A limit of 0 allows any number.
+func WithMaxFanout(n int) HandlerOption {
+	return func(h *Handler) {
+		h.maxFanout = n
+	}
+}
+
+// FanoutResult is one target's entry in a fanout response. Body holds the upstream body
+// verbatim when it is JSON, or as a JSON string otherwise; Error is set when the target
+// could not be reached.
+type FanoutResult struct {
+	Target string          `json:"target"`
+	Status int             `json:"status,omitempty"`
+	Body   json.RawMessage `json:"body,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// handleFanout forwards the request to every fanout target concurrently and responds with
+// a JSON array of their results, in target order, once all complete or the timeout fires
+func (h *Handler) handleFanout(ctx context.Context, w http.ResponseWriter, r *http.Request, actions actions, logger *slog.Logger) {
+	logger.Info("Fanning out", slog.Any("targets", actions.FanoutTargets), slog.String("remaining", actions.Remaining))
+
+	// Buffer the body once so every target receives a copy
+	body, err := h.readBody(ctx, r)
+	if err != nil {
+		h.writeBodyReadError(w, r, err, logger)
+		return
+	}
+
+	results := make([]FanoutResult, len(actions.FanoutTargets))
+	var wg sync.WaitGroup
+	for i, target := range actions.FanoutTargets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = h.fanoutTo(ctx, r, target, actions.Remaining, body, logger)
+		}()
+	}
+	wg.Wait()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		logger.Error("Failed to encode fanout response", slog.String("error", err.Error()))
+	}
+}
+
+// fanoutTo sends a single fanout request and captures its outcome
+func (h *Handler) fanoutTo(ctx context.Context, r *http.Request, target, remaining string, body []byte, logger *slog.Logger) FanoutResult {
+	scheme, host := parseScheme(target)
+	result := FanoutResult{Target: host}
+	url := fmt.Sprintf("%s://%s%s", scheme, host, remaining)
+
+	req, err := h.newUpstreamRequest(ctx, r, url, bytes.NewReader(body))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		logger.Error("Fanout target failed", slog.String("target", url), slog.String("error", err.Error()))
+		result.Error = err.Error()
+		return result
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		result.Error = fmt.Sprintf("reading response body: %v", err)
+		return result
+	}
+
+	result.Status = resp.StatusCode
+	trimmed := bytes.TrimSpace(respBody)
+	if json.Valid(trimmed) {
+		result.Body = trimmed
+	} else if encoded, err := json.Marshal(string(respBody)); err == nil {
+		result.Body = encoded
+	}
+
+	logger.Debug("Fanout target responded", slog.String("target", url), slog.Int("status_code", resp.StatusCode))
+	return result
+}
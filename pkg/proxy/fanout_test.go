@@ -0,0 +1,113 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFanout(t *testing.T) {
+	logger := createTestLogger()
+
+	newBackend := func(t *testing.T, name string) string {
+		t.Helper()
+		backend, err := NewHandler(30*time.Second, name, logger)
+		require.NoError(t, err)
+		server := httptest.NewServer(backend)
+		t.Cleanup(server.Close)
+		return strings.TrimPrefix(server.URL, "http://")
+	}
+
+	svca := newBackend(t, "svca")
+	svcb := newBackend(t, "svcb")
+
+	handler, err := NewHandler(30*time.Second, "test-service", logger)
+	require.NoError(t, err)
+
+	t.Run("merges responses from all targets", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/fanout/"+svca+","+svcb, nil))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+		var results []FanoutResult
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &results))
+		require.Len(t, results, 2)
+
+		for i, name := range []string{"svca", "svcb"} {
+			assert.Equal(t, http.StatusOK, results[i].Status)
+			assert.Empty(t, results[i].Error)
+
+			var resp Response
+			require.NoError(t, json.Unmarshal(results[i].Body, &resp))
+			assert.Equal(t, name, resp.Service)
+		}
+	})
+
+	t.Run("remaining path is applied by each target", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/fanout/"+svca+","+svcb+"/fault/503", nil))
+
+		var results []FanoutResult
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &results))
+		require.Len(t, results, 2)
+		assert.Equal(t, http.StatusServiceUnavailable, results[0].Status)
+		assert.Equal(t, http.StatusServiceUnavailable, results[1].Status)
+	})
+
+	t.Run("partial failure includes an error entry", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		dead := ln.Addr().String()
+		require.NoError(t, ln.Close())
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/fanout/"+svca+","+dead, nil))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var results []FanoutResult
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &results))
+		require.Len(t, results, 2)
+		assert.Equal(t, http.StatusOK, results[0].Status)
+		assert.Equal(t, dead, results[1].Target)
+		assert.NotEmpty(t, results[1].Error)
+		assert.Zero(t, results[1].Status)
+	})
+
+	t.Run("target count is limited", func(t *testing.T) {
+		handler, err := NewHandler(30*time.Second, "test-service", logger, WithMaxFanout(2))
+		require.NoError(t, err)
+
+		tests := []struct {
+			name       string
+			targets    []string
+			wantStatus int
+		}{
+			{name: "below the limit", targets: []string{svca}, wantStatus: http.StatusOK},
+			{name: "at the limit", targets: []string{svca, svcb}, wantStatus: http.StatusOK},
+			{name: "above the limit", targets: []string{svca, svcb, svca}, wantStatus: http.StatusBadRequest},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/fanout/"+strings.Join(tt.targets, ","), nil))
+				assert.Equal(t, tt.wantStatus, rr.Code)
+
+				if tt.wantStatus == http.StatusBadRequest {
+					var body ErrorResponse
+					require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+					assert.Equal(t, ErrCodeBadPath, body.Code)
+				}
+			})
+		}
+	})
+}
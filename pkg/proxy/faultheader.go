@@ -0,0 +1,60 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// injectFaultHeader asks for a fault on a single request, e.g. "503,50" as /fault/503/50 would
+const injectFaultHeader = "X-Inject-Fault"
+
+// injectedFaultKey is the context key under which serve passes a header fault to serveProxy
+type injectedFaultKey struct{}
+
+// parseInjectFault parses an X-Inject-Fault value of the form <code>[,<percentage>] or
+// badjson[,<percentage>], validating it as the equivalent /fault/ directive
+func parseInjectFault(value string) (actions, error) {
+	fields := strings.Split(value, ",")
+	if len(fields) > 2 {
+		return actions{}, fmt.Errorf("invalid %s header: must be <code> or <code>,<percentage>", injectFaultHeader)
+	}
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+
+	a, err := parsePath("/fault/" + strings.Join(fields, "/"))
+	if err != nil {
+		return actions{}, fmt.Errorf("invalid %s header: %w", injectFaultHeader, err)
+	}
+	// A percentage that fails to parse is left as the remaining path by the /fault/ parser
+	if a.Remaining != "/" {
+		return actions{}, fmt.Errorf("invalid %s header: invalid fault percentage: must be 0-100", injectFaultHeader)
+	}
+	return a, nil
+}
+
+// withInjectedFault moves an X-Inject-Fault header into the request context so serveProxy applies
+// it ahead of the path. The header is removed so it is not propagated to the next hop.
+func withInjectedFault(r *http.Request) (*http.Request, error) {
+	value := r.Header.Get(injectFaultHeader)
+	if value == "" {
+		return r, nil
+	}
+	fault, err := parseInjectFault(value)
+	if err != nil {
+		return nil, err
+	}
+
+	r = r.WithContext(context.WithValue(r.Context(), injectedFaultKey{}, fault))
+	r.Header = r.Header.Clone()
+	r.Header.Del(injectFaultHeader)
+	return r, nil
+}
+
+// injectedFault returns the fault requested by header for this request, if any
+func injectedFault(ctx context.Context) (actions, bool) {
+	a, ok := ctx.Value(injectedFaultKey{}).(actions)
+	return a, ok
+}
@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseInjectFault(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    actions
+		wantErr bool
+	}{
+		{name: "code only", value: "503", want: actions{Remaining: "/", IsFault: true, FaultCode: 503, FaultPercentage: 100}},
+		{name: "code and percentage", value: "503,50", want: actions{Remaining: "/", IsFault: true, FaultCode: 503, FaultPercentage: 50}},
+		{name: "spaces around fields", value: " 500 , 25 ", want: actions{Remaining: "/", IsFault: true, FaultCode: 500, FaultPercentage: 25}},
+		{name: "badjson", value: "badjson,10", want: actions{Remaining: "/", IsFault: true, FaultCode: 200, FaultPercentage: 10, FaultBadJSON: true}},
+		{name: "non-numeric code", value: "abc", wantErr: true},
+		{name: "code out of range", value: "200,50", wantErr: true},
+		{name: "percentage out of range", value: "503,150", wantErr: true},
+		{name: "non-numeric percentage", value: "503,half", wantErr: true},
+		{name: "too many fields", value: "503,50,10", wantErr: true},
+		{name: "empty code", value: ",50", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseInjectFault(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestInjectFaultHeader(t *testing.T) {
+	var forwarded []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = append(forwarded, r.Header.Get(injectFaultHeader))
+	}))
+	defer upstream.Close()
+	target := strings.TrimPrefix(upstream.URL, "http://")
+
+	handler, err := NewHandler(30*time.Second, "test-service", createTestLogger())
+	require.NoError(t, err)
+
+	get := func(path, fault string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if fault != "" {
+			req.Header.Set(injectFaultHeader, fault)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("injects the fault", func(t *testing.T) {
+		forwarded = nil
+		rr := get("/proxy/"+target, "503")
+		assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+		assert.Contains(t, rr.Body.String(), "Fault injected: 503")
+		assert.Empty(t, forwarded, "faulted request should not be forwarded")
+	})
+
+	t.Run("zero percent continues down the path without the header", func(t *testing.T) {
+		forwarded = nil
+		rr := get("/proxy/"+target, "503,0")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, []string{""}, forwarded, "header should not reach the next hop")
+	})
+
+	t.Run("applies ahead of path faults", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadGateway, get("/fault/500", "502").Code)
+		assert.Equal(t, http.StatusInternalServerError, get("/fault/500", "502,0").Code)
+	})
+
+	t.Run("malformed header returns 400", func(t *testing.T) {
+		for _, value := range []string{"oops", "503,150", "503,50,1"} {
+			rr := get("/", value)
+			assert.Equal(t, http.StatusBadRequest, rr.Code, value)
+			assert.Contains(t, rr.Body.String(), injectFaultHeader)
+		}
+	})
+}
@@ -0,0 +1,27 @@
+package proxy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// defaultFlakyEvery makes a bare /flaky fail every other request, starting with the first
+const defaultFlakyEvery = 2
+
+// maxFlakyEvery caps the period of a /flaky/ directive
+const maxFlakyEvery = 1000
+
+// parseFlaky parses a /flaky directive from the path segments after "flaky". The period is
+// optional, so a segment that is not a number is the start of the remaining path.
+func parseFlaky(segments []string) (every int, remaining string, err error) {
+	every = defaultFlakyEvery
+	if len(segments) > 0 && segments[0] != "" && segments[0][0] >= '0' && segments[0][0] <= '9' {
+		every, err = strconv.Atoi(segments[0])
+		if err != nil || every < 1 || every > maxFlakyEvery {
+			return 0, "", fmt.Errorf("invalid flaky: period must be a number between 1 and %d", maxFlakyEvery)
+		}
+		segments = segments[1:]
+	}
+	return every, "/" + strings.Join(segments, "/"), nil
+}
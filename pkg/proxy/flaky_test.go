@@ -0,0 +1,102 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlakyDirective(t *testing.T) {
+	handler, err := NewHandler(30*time.Second, "test-service", createTestLogger())
+	require.NoError(t, err)
+
+	get := func(path string) int {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr.Code
+	}
+	calls := func(path string, n int) []int {
+		var got []int
+		for range n {
+			got = append(got, get(path))
+		}
+		return got
+	}
+
+	t.Run("alternates failure and success", func(t *testing.T) {
+		assert.Equal(t, []int{500, 200, 500, 200, 500, 200}, calls("/flaky", 6))
+	})
+
+	t.Run("fails the first of every n", func(t *testing.T) {
+		assert.Equal(t, []int{500, 200, 200, 500, 200, 200}, calls("/flaky/3", 6))
+	})
+
+	t.Run("a period of one always fails", func(t *testing.T) {
+		assert.Equal(t, []int{500, 500, 500}, calls("/flaky/1", 3))
+	})
+
+	t.Run("kept per path", func(t *testing.T) {
+		assert.Equal(t, http.StatusInternalServerError, get("/flaky/2/seq/201"))
+		assert.Equal(t, http.StatusInternalServerError, get("/flaky/2/seq/202"))
+		assert.Equal(t, http.StatusCreated, get("/flaky/2/seq/201"))
+		assert.Equal(t, http.StatusAccepted, get("/flaky/2/seq/202"))
+	})
+
+	t.Run("idle paths are forgotten and tracking is capped", func(t *testing.T) {
+		bounded, err := NewHandler(30*time.Second, "test-service", createTestLogger())
+		require.NoError(t, err)
+		now := time.Now()
+		bounded.flakiness.now = func() time.Time { return now }
+		get := func(path string) int {
+			rr := httptest.NewRecorder()
+			bounded.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+			return rr.Code
+		}
+
+		assert.Equal(t, http.StatusInternalServerError, get("/flaky/2/seq/299"))
+		assert.Equal(t, 299, get("/flaky/2/seq/299"))
+		now = now.Add(sequenceTTL + time.Second)
+		for i := range maxTrackedSequences + 10 {
+			get(fmt.Sprintf("/flaky/%d/seq/200", i%maxFlakyEvery+1))
+			get(fmt.Sprintf("/flaky/%d/seq/201", i%maxFlakyEvery+1))
+		}
+		assert.Len(t, bounded.flakiness.next, maxTrackedSequences, "paths beyond the cap are not tracked")
+		assert.NotContains(t, bounded.flakiness.next, "/flaky/2/seq/299", "an idle path is forgotten")
+	})
+
+	t.Run("success continues down the path", func(t *testing.T) {
+		var hits int
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits++
+			w.WriteHeader(http.StatusTeapot)
+		}))
+		defer upstream.Close()
+
+		path := "/flaky/proxy/" + strings.TrimPrefix(upstream.URL, "http://")
+		assert.Equal(t, http.StatusInternalServerError, get(path))
+		assert.Equal(t, 0, hits, "failure should answer without forwarding")
+		assert.Equal(t, http.StatusTeapot, get(path))
+		assert.Equal(t, 1, hits)
+	})
+
+	t.Run("retries ride out the failure", func(t *testing.T) {
+		upstream, err := NewHandler(30*time.Second, "upstream", createTestLogger())
+		require.NoError(t, err)
+		server := httptest.NewServer(upstream)
+		defer server.Close()
+
+		retrying, err := NewHandler(30*time.Second, "test-service", createTestLogger(), WithMaxRetries(1), WithRetryOnStatus([]int{http.StatusInternalServerError}))
+		require.NoError(t, err)
+		for range 3 {
+			rr := httptest.NewRecorder()
+			retrying.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/proxy/"+strings.TrimPrefix(server.URL, "http://")+"/flaky", nil))
+			assert.Equal(t, http.StatusOK, rr.Code)
+		}
+	})
+}
@@ -0,0 +1,43 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+)
+
+// WithForwardFullPath configures how a /proxy/ hop builds the downstream URL. By default the hop
+// is everything up to the next directive and the path is rebuilt from the parsed, cleaned path,
+// so /proxy/svc:8080/api/users is sent to http://svc:8080/api/users/ and the query is dropped.
+// With full-path forwarding the hop is only the host and port, and the rest of the original
+// escaped path and query are forwarded verbatim, for backends that don't understand directives.
+func WithForwardFullPath(enabled bool) HandlerOption {
+	return func(h *Handler) {
+		h.forwardFullPath = enabled
+	}
+}
+
+// fullPathURL builds the next hop URL from the request's original escaped path and query,
+// dropping only the first /proxy/<host:port> or /proxys/<host:port> segment, which this hop consumes
+func fullPathURL(r *http.Request, scheme string) string {
+	escaped := r.URL.EscapedPath()
+	first, afterProxy := -1, ""
+	for _, prefix := range []string{"/proxy/", "/proxys/"} {
+		if i := strings.Index(escaped, prefix); i >= 0 && (first < 0 || i < first) {
+			first, afterProxy = i, escaped[i+len(prefix):]
+		}
+	}
+	_, afterProxy = parseScheme(afterProxy)
+	afterProxy = strings.TrimPrefix(afterProxy, "/")
+
+	host, path := afterProxy, "/"
+	if i := strings.Index(afterProxy, "/"); i >= 0 {
+		host, path = afterProxy[:i], afterProxy[i:]
+	}
+
+	url := scheme + "://" + host + path
+	// The query carries the trace parameter when the request is traced
+	if r.URL.RawQuery != "" {
+		url += "?" + r.URL.RawQuery
+	}
+	return url
+}
@@ -0,0 +1,83 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForwardFullPath(t *testing.T) {
+	var got string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.RequestURI()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+	hop := strings.TrimPrefix(upstream.URL, "http://")
+
+	tests := []struct {
+		name        string
+		path        string
+		wantDefault string
+		wantFull    string
+	}{
+		{
+			name:        "bare hop",
+			path:        "/proxy/" + hop,
+			wantDefault: "/",
+			wantFull:    "/",
+		},
+		{
+			name:        "backend path",
+			path:        "/proxy/" + hop + "/api/users",
+			wantDefault: "/api/users/",
+			wantFull:    "/api/users",
+		},
+		{
+			name:        "escaped path and query",
+			path:        "/proxy/" + hop + "/files/a%2Fb?page=2&sort=name",
+			wantDefault: "/files/a/b/",
+			wantFull:    "/files/a%2Fb?page=2&sort=name",
+		},
+		{
+			name:        "later directives",
+			path:        "/proxy/" + hop + "/proxy/svc-c:8080",
+			wantDefault: "/proxy/svc-c:8080",
+			wantFull:    "/proxy/svc-c:8080",
+		},
+		{
+			name:        "after another directive",
+			path:        "/delay/1/proxy/" + hop + "/api",
+			wantDefault: "/api/",
+			wantFull:    "/api",
+		},
+	}
+
+	for _, mode := range []struct {
+		name     string
+		fullPath bool
+	}{{"default", false}, {"full path", true}} {
+		h, err := NewHandler(5*time.Second, "test-service", createTestLogger(), WithForwardFullPath(mode.fullPath))
+		require.NoError(t, err)
+
+		for _, tt := range tests {
+			t.Run(mode.name+"/"+tt.name, func(t *testing.T) {
+				got = ""
+				rr := httptest.NewRecorder()
+				h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+				require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+				want := tt.wantDefault
+				if mode.fullPath {
+					want = tt.wantFull
+				}
+				assert.Equal(t, want, got)
+			})
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package proxy
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// grpcStatusNames are the canonical names of the gRPC status codes, indexed by code
+var grpcStatusNames = []string{
+	"OK", "Canceled", "Unknown", "InvalidArgument", "DeadlineExceeded", "NotFound", "AlreadyExists",
+	"PermissionDenied", "ResourceExhausted", "FailedPrecondition", "Aborted", "OutOfRange",
+	"Unimplemented", "Internal", "Unavailable", "DataLoss", "Unauthenticated",
+}
+
+// parseGRPCStatus validates the code of a /grpc-status/ directive
+func parseGRPCStatus(s string) (int, error) {
+	code, err := strconv.Atoi(s)
+	if err != nil || code < 0 || code >= len(grpcStatusNames) {
+		return 0, fmt.Errorf("invalid grpc-status: code must be a number between 0 and %d", len(grpcStatusNames)-1)
+	}
+	return code, nil
+}
+
+// sendGRPCStatus answers the way a gRPC server reports a status over HTTP/1.1: 200 with an
+// application/grpc content type, an empty body, and grpc-status and grpc-message trailers
+func (h *Handler) sendGRPCStatus(w http.ResponseWriter, code int, logger *slog.Logger) {
+	message := fmt.Sprintf("Fault injected: %d %s", code, grpcStatusNames[code])
+	if code == 0 {
+		message = ""
+	}
+	logger.Debug("Sending gRPC status", slog.Int("grpc_status", code), slog.String("grpc_message", message))
+
+	w.Header().Set("Content-Type", "application/grpc")
+	w, setTrailers := declareTrailers(w, http.Header{
+		"Grpc-Status":  {strconv.Itoa(code)},
+		"Grpc-Message": {message},
+	})
+	w.WriteHeader(http.StatusOK)
+	setTrailers()
+}
@@ -0,0 +1,54 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGRPCStatusDirective(t *testing.T) {
+	handler, err := NewHandler(30*time.Second, "test-service", createTestLogger())
+	require.NoError(t, err)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	get := func(t *testing.T, path string) *http.Response {
+		t.Helper()
+		resp, err := http.Get(server.URL + path)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = resp.Body.Close() })
+
+		// Trailers are only populated once the body has been read
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Empty(t, body)
+		return resp
+	}
+
+	t.Run("error status in trailers", func(t *testing.T) {
+		resp := get(t, "/grpc-status/14")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/grpc", resp.Header.Get("Content-Type"))
+		assert.Equal(t, "14", resp.Trailer.Get("Grpc-Status"))
+		assert.Equal(t, "Fault injected: 14 Unavailable", resp.Trailer.Get("Grpc-Message"))
+	})
+
+	t.Run("ok status", func(t *testing.T) {
+		resp := get(t, "/grpc-status/0")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "0", resp.Trailer.Get("Grpc-Status"))
+		assert.Empty(t, resp.Trailer.Get("Grpc-Message"))
+	})
+
+	t.Run("rejects codes outside the gRPC range", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/grpc-status/99")
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
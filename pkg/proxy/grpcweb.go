@@ -0,0 +1,73 @@
+package proxy
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// WithGRPCWeb enables gRPC-Web passthrough: requests with an application/grpc-web* content type
+// have their responses streamed chunk by chunk with trailers preserved instead of copied in bulk
+func WithGRPCWeb(enabled bool) HandlerOption {
+	return func(h *Handler) {
+		h.grpcWeb = enabled
+	}
+}
+
+// isGRPCWeb reports whether a content type is one of the gRPC-Web variants
+// (application/grpc-web, application/grpc-web+proto, application/grpc-web-text, ...)
+func isGRPCWeb(contentType string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(contentType)), "application/grpc-web")
+}
+
+// forwardGRPCWebResponse streams a gRPC-Web response to the client without buffering so the
+// message framing reaches the client unchanged and as it arrives. The content type is always kept
+// because clients rely on it to decode the frames, and any HTTP trailers are forwarded after the body.
+func (h *Handler) forwardGRPCWebResponse(w http.ResponseWriter, resp *http.Response, logger *slog.Logger) error {
+	logger.Debug("Streaming gRPC-Web response", slog.Int("status_code", resp.StatusCode))
+
+	h.stampHop(w)
+	if h.propagateResponseHeaders {
+		for k, v := range resp.Header {
+			for _, val := range v {
+				w.Header().Add(k, val)
+			}
+		}
+	} else if ct := resp.Header.Get("Content-Type"); ct != "" {
+		w.Header().Set("Content-Type", ct)
+	}
+
+	w.WriteHeader(h.remapStatus(resp.StatusCode, logger))
+
+	rc := http.NewResponseController(w)
+	buf := make([]byte, 32*1024)
+	for {
+		n, readErr := resp.Body.Read(buf)
+		if n > 0 {
+			if _, err := w.Write(buf[:n]); err != nil {
+				logger.Error("Failed to write gRPC-Web response", slog.String("error", err.Error()))
+				return err
+			}
+			if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				return err
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			logger.Error("Failed to read gRPC-Web response", slog.String("error", readErr.Error()))
+			return readErr
+		}
+	}
+
+	// Trailers are only known once the body has been read in full
+	for k, v := range resp.Trailer {
+		for _, val := range v {
+			w.Header().Add(http.TrailerPrefix+k, val)
+		}
+	}
+	return nil
+}
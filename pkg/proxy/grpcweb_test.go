@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// grpcWebFrame builds a gRPC-Web frame with the given flag byte and payload
+func grpcWebFrame(flag byte, payload []byte) []byte {
+	frame := make([]byte, 5, 5+len(payload))
+	frame[0] = flag
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(payload)))
+	return append(frame, payload...)
+}
+
+func TestIsGRPCWeb(t *testing.T) {
+	assert.True(t, isGRPCWeb("application/grpc-web"))
+	assert.True(t, isGRPCWeb("application/grpc-web+proto"))
+	assert.True(t, isGRPCWeb("application/grpc-web-text; charset=utf-8"))
+	assert.False(t, isGRPCWeb("application/grpc"))
+	assert.False(t, isGRPCWeb("application/json"))
+	assert.False(t, isGRPCWeb(""))
+}
+
+func TestGRPCWebPassthrough(t *testing.T) {
+	message := grpcWebFrame(0x00, []byte{0x0a, 0x05, 'h', 'e', 'l', 'l', 'o', 0x00, 0xff})
+	trailer := grpcWebFrame(0x80, []byte("grpc-status:0\r\ngrpc-message:\r\n"))
+	release := make(chan struct{})
+
+	var receivedBody []byte
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedBody, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/grpc-web+proto")
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.WriteHeader(http.StatusOK)
+
+		// Hold the trailer frame back until the client has seen the message frame
+		_, _ = w.Write(message)
+		w.(http.Flusher).Flush()
+		<-release
+		_, _ = w.Write(trailer)
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	defer upstream.Close()
+
+	handler, err := NewHandler(30*time.Second, "test-service", createTestLogger(),
+		WithGRPCWeb(true), WithPropagateResponseHeaders(false))
+	require.NoError(t, err)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	request := grpcWebFrame(0x00, []byte{0x0a, 0x03, 'a', 'b', 'c'})
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/proxy/"+strings.TrimPrefix(upstream.URL, "http://"), bytes.NewReader(request))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/grpc-web+proto")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/grpc-web+proto", resp.Header.Get("Content-Type"))
+
+	// The first frame must arrive while the upstream is still holding back the rest
+	first := make(chan []byte)
+	go func() {
+		buf := make([]byte, len(message))
+		_, _ = io.ReadFull(resp.Body, buf)
+		first <- buf
+	}()
+	select {
+	case got := <-first:
+		assert.Equal(t, message, got)
+	case <-time.After(5 * time.Second):
+		close(release)
+		t.Fatal("message frame was buffered instead of streamed")
+	}
+	close(release)
+
+	rest, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, trailer, rest)
+	assert.Equal(t, "0", resp.Trailer.Get("Grpc-Status"))
+	assert.Equal(t, request, receivedBody)
+}
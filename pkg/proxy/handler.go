@@ -1,20 +1,29 @@
 package proxy
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
-	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"math/rand"
+	"mime"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"text/template"
 	"time"
+
+	"github.com/itchyny/gojq"
+	"golang.org/x/sync/semaphore"
+	"golang.org/x/sync/singleflight"
 )
 
 // Handler handles HTTP proxy requests
@@ -28,15 +37,73 @@ type Handler struct {
 	caCertFiles              []string
 	propagateRequestHeaders  bool
 	propagateResponseHeaders bool
+	strictAccept             bool
+	responseTemplateFile     string
+	responseTemplate         *template.Template
+	trustProxyHeaders        bool
+	idempotency              *idempotencyCache
+	propagateDeadline        bool
+	recordFile               string
+	recordHeaders            bool
+	recorder                 *recorder
+	started                  time.Time
+	grpcWeb                  bool
+	statusRemap              map[int]int
+	maxRetries               int
+	retryOnStatus            map[int]bool
+	maxRequestTimeout        time.Duration
+	maxPayloadBytes          int64
+	responseContentType      string
+	rateLimiter              *rateLimiter
+	transformExpr            string
+	transform                *gojq.Code
+	etag                     bool
+	lastModified             time.Time
+	rejectSuspiciousPaths    bool
+	tracePropagation         string
+	allowedMethods           []string
+	latencyHeader            bool
+	forwardQuery             bool
+	maxHeaderCount           int
+	maxFanout                int
+	maxRepeat                int
+	concurrency              *semaphore.Weighted
+	heapGuard                *heapGuard
+	maxQueueWait             time.Duration
+	upstreamUserAgent        string
+	responseFields           ResponseFields
+	decompressRequests       bool
+	sequences                sequences
+	flakiness                sequences
+	responseMessage          string
+	backendHealth            *backendHealth
+	followRedirects          bool
+	hostAliases              map[string]string
+	dnsCacheTTL              time.Duration
+	latencyPerKB             time.Duration
+	websocket                bool
+	bodyReadTimeout          time.Duration
+	coalescing               bool
+	inflight                 singleflight.Group
+	forwardFullPath          bool
+	hmacSecret               []byte
+	checksum                 string
+	maxTotalDuration         time.Duration
+	logSampleRate            float64
+	expectContinue           bool
 }
 
 // Response represents the standard response format
 type Response struct {
-	Status  int    `json:"status"`
-	Service string `json:"service"`
-	Message string `json:"message,omitempty"`
+	XMLName xml.Name `json:"-" xml:"response"`
+	Status  int      `json:"status" xml:"status"`
+	Service string   `json:"service" xml:"service"`
+	Message string   `json:"message,omitempty" xml:"message,omitempty"`
 }
 
+// DefaultResponseMessage is the message of the final response when none is configured
+const DefaultResponseMessage = "Request processed successfully"
+
 // HandlerOption configures a Handler
 type HandlerOption func(*Handler)
 
@@ -54,6 +121,14 @@ func WithTLSInsecure(insecure bool) HandlerOption {
 	}
 }
 
+// WithFollowRedirects configures whether upstream redirects are followed. When disabled, a 3xx
+// response from the next hop is returned to the client as-is.
+func WithFollowRedirects(follow bool) HandlerOption {
+	return func(h *Handler) {
+		h.followRedirects = follow
+	}
+}
+
 // WithCACertFiles appends the given PEM CA certificate files to the system trust pool
 // for upstream TLS verification. Returns an error from NewHandler if any file cannot
 // be read or contains no valid certificates.
@@ -77,6 +152,68 @@ func WithPropagateResponseHeaders(propagate bool) HandlerOption {
 	}
 }
 
+// WithStrictAccept configures whether requests whose Accept header matches none of the
+// supported media types are rejected with 406 instead of falling back to JSON
+func WithStrictAccept(strict bool) HandlerOption {
+	return func(h *Handler) {
+		h.strictAccept = strict
+	}
+}
+
+// WithResponseTemplate renders final responses from the given Go text/template file instead of the
+// standard JSON envelope. Returns an error from NewHandler if the template cannot be read or parsed.
+func WithResponseTemplate(path string) HandlerOption {
+	return func(h *Handler) {
+		h.responseTemplateFile = path
+	}
+}
+
+// WithResponseContentType declares final responses as the given Content-Type instead of negotiating
+// one from the Accept header. The body remains the JSON envelope (or rendered template).
+func WithResponseContentType(contentType string) HandlerOption {
+	return func(h *Handler) {
+		h.responseContentType = contentType
+	}
+}
+
+// WithResponseMessage sets the message of the final response. A "{service}" placeholder is
+// replaced with the service name.
+func WithResponseMessage(message string) HandlerOption {
+	return func(h *Handler) {
+		h.responseMessage = message
+	}
+}
+
+// WithUpstreamUserAgent sets the User-Agent of requests to upstream hops, replacing any propagated
+// from the incoming request. A "{service}" placeholder is replaced with the service name.
+func WithUpstreamUserAgent(userAgent string) HandlerOption {
+	return func(h *Handler) {
+		h.upstreamUserAgent = userAgent
+	}
+}
+
+// WithTrustProxyHeaders configures whether X-Forwarded-For and X-Real-IP are trusted to
+// identify the client. Only enable this when running behind a proxy that sets them.
+func WithTrustProxyHeaders(trust bool) HandlerOption {
+	return func(h *Handler) {
+		h.trustProxyHeaders = trust
+	}
+}
+
+// WithIdempotencyTTL enables Idempotency-Key handling: a request repeating a key seen within
+// the TTL receives the first request's response, marked with X-Idempotent-Replay, waiting for it
+// if the first request is still in flight. A key reused for a different method or path gets 422.
+// Zero disables it.
+func WithIdempotencyTTL(ttl time.Duration) HandlerOption {
+	return func(h *Handler) {
+		if ttl > 0 {
+			h.idempotency = newIdempotencyCache(ttl)
+		} else {
+			h.idempotency = nil
+		}
+	}
+}
+
 // NewHandler creates a new proxy handler with structured logging
 func NewHandler(timeout time.Duration, serviceName string, logger *slog.Logger, opts ...HandlerOption) (*Handler, error) {
 	h := &Handler{
@@ -90,12 +227,20 @@ func NewHandler(timeout time.Duration, serviceName string, logger *slog.Logger,
 			},
 		},
 		timeout:                  timeout,
+		started:                  time.Now(),
 		serviceName:              serviceName,
 		logger:                   logger,
 		logHeaders:               false,
 		tlsInsecure:              false,
 		propagateRequestHeaders:  true,
 		propagateResponseHeaders: true,
+		maxPayloadBytes:          DefaultMaxPayloadBytes,
+		maxFanout:                DefaultMaxFanout,
+		maxRepeat:                DefaultMaxRepeat,
+		responseFields:           DefaultResponseFields,
+		responseMessage:          DefaultResponseMessage,
+		followRedirects:          true,
+		logSampleRate:            1,
 	}
 
 	// Apply options
@@ -103,6 +248,32 @@ func NewHandler(timeout time.Duration, serviceName string, logger *slog.Logger,
 		opt(h)
 	}
 
+	// Let per-request timeouts above --timeout run to their own deadline
+	if h.client.Timeout > 0 && h.maxRequestTimeout > h.client.Timeout {
+		h.client.Timeout = h.maxRequestTimeout
+	}
+
+	// Hand redirects back to the client instead of following them
+	if !h.followRedirects {
+		h.client.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	}
+
+	// Resolve aliased hostnames to their fixed IPs, and cache lookups of the rest. The dialer
+	// matches the timeouts of http.DefaultTransport's.
+	if len(h.hostAliases) > 0 || h.dnsCacheTTL > 0 {
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+		var dial dialFunc = dialer.DialContext
+		if h.dnsCacheTTL > 0 {
+			dial = newDNSCache(net.DefaultResolver, h.dnsCacheTTL).dialContext(dial)
+		}
+		if len(h.hostAliases) > 0 {
+			dial = aliasDialContext(h.hostAliases, dial)
+		}
+		h.client.Transport.(*http.Transport).DialContext = dial
+	}
+
 	// Apply TLS insecure setting
 	if h.tlsInsecure {
 		h.client.Transport.(*http.Transport).TLSClientConfig.InsecureSkipVerify = true
@@ -127,18 +298,71 @@ func NewHandler(timeout time.Duration, serviceName string, logger *slog.Logger,
 		h.client.Transport.(*http.Transport).TLSClientConfig.RootCAs = pool
 	}
 
+	// Parse the response template up front so a bad template fails at startup
+	if h.responseTemplateFile != "" {
+		tmpl, err := loadResponseTemplate(h.responseTemplateFile)
+		if err != nil {
+			return nil, err
+		}
+		h.responseTemplate = tmpl
+	}
+
+	// Compile the transform up front so a bad expression fails at startup
+	if h.transformExpr != "" {
+		code, err := compileTransform(h.transformExpr)
+		if err != nil {
+			return nil, err
+		}
+		h.transform = code
+	}
+
+	// Open the record file up front so a bad path fails at startup
+	if h.recordFile != "" {
+		rec, err := newRecorder(h.recordFile)
+		if err != nil {
+			return nil, err
+		}
+		h.recorder = rec
+	}
+
 	return h, nil
 }
 
 // actions represents the parsed proxy path actions
 type actions struct {
-	NextHop         string // The next hop service and port to forward to
-	Remaining       string // The remaining path after next hop
-	IsLastHop       bool   // Whether this is the last hop in the chain
-	Scheme          string // The URL scheme to use (http or https), defaults to http
-	IsFault         bool   // Whether this is a fault injection
-	FaultCode       int    // HTTP status code to inject (400-599)
-	FaultPercentage int    // Percentage chance of fault triggering (0-100)
+	NextHop          string          // The next hop service and port to forward to
+	Remaining        string          // The remaining path after next hop
+	IsLastHop        bool            // Whether this is the last hop in the chain
+	Scheme           string          // The URL scheme to use (http or https), defaults to http
+	IsFault          bool            // Whether this is a fault injection
+	FaultCode        int             // HTTP status code to inject (400-599)
+	FaultPercentage  int             // Percentage chance of fault triggering (0-100)
+	FaultBadJSON     bool            // Whether the fault returns 200 with a malformed JSON body instead of an error code
+	FaultMatchHeader string          // Header the request must carry for the fault to trigger (empty matches all requests)
+	FaultMatchValue  string          // Value FaultMatchHeader must have
+	FanoutTargets    []string        // Targets to forward to concurrently, each optionally prefixed with a scheme
+	TryTargets       []string        // Targets to try in random order until one succeeds, each optionally prefixed with a scheme
+	IsDelay          bool            // Whether this is a delay directive
+	Delay            time.Duration   // How long to wait before processing the remaining path
+	SlowStart        time.Duration   // Window after server start over which Delay ramps down to zero (0 for a fixed delay)
+	LatencyProfile   []time.Duration // p50, p95 and p99 of a distribution to sample the delay from (nil for a fixed delay)
+	ContentType      string          // Content-Type to declare on this hop's final response
+	TrailerName      string          // Trailer to send after this hop's response body (empty for none)
+	TrailerValue     string          // Value of TrailerName
+	Cookie           *http.Cookie    // Cookie to set on this hop's response; attributes come from the query (nil for none)
+	IsBytes          bool            // Whether to answer with random bytes
+	Bytes            int64           // Number of random bytes to answer with
+	MultipartParts   int             // Number of JSON parts to answer with in a multipart/mixed response (0 for none)
+	IsReset          bool            // Whether to reset the connection mid-response
+	ResetBytes       int64           // Number of body bytes to write before the reset
+	SeqStatuses      []int           // Statuses to cycle through on successive requests for the same path
+	FlakyEvery       int             // Fail the first of every FlakyEvery requests for the same path with 500 (0 for never)
+	RepeatCount      int             // Number of times to send the request to the next hop (0 for once)
+	IsChunked        bool            // Whether to stream the final response in chunks without a Content-Length
+	IsSlowBody       bool            // Whether to write the final response one byte at a time
+	SlowBodyInterval time.Duration   // Pause between bytes of a slow body
+	IsGRPCStatus     bool            // Whether to answer with a gRPC status in trailers
+	GRPCStatus       int             // gRPC status code to answer with (0-16)
 }
 
 // sensitiveHeaders lists headers that should be redacted in logs for security
@@ -172,12 +396,114 @@ func (h *Handler) headersToLogAttrs(headers http.Header, prefix string) slog.Att
 	return slog.Group(prefix, attrs...)
 }
 
+// directivePrefixes lists the path segments that start a directive. A proxy hop or
+// fanout/try target list ends where the next directive begins.
+var directivePrefixes = []string{"/proxy/", "/fault/", "/fanout/", "/try/", "/delay/", "/slowstart/", "/latency/", "/bytes/", "/ctype/", "/reset/", "/multipart/", "/trailer/", "/setcookie/", "/seq/", "/chunked", "/grpc-status/", "/slowbody/", "/flaky", "/proxys/", "/repeat/"}
+
+// errUnknownRoute marks paths that start with no directive at all, as opposed to directives
+// with malformed arguments
+var errUnknownRoute = errors.New("unknown route")
+
+// directiveName returns the directive prefix, without its trailing slash, that the path's first
+// segment names, or "" if it names none
+func directiveName(path string) string {
+	segment, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	for _, prefix := range directivePrefixes {
+		if name := strings.TrimSuffix(prefix, "/"); name == "/"+segment {
+			return name
+		}
+	}
+	return ""
+}
+
+// faultMatches reports whether the request satisfies the fault's header match, if it has one
+func faultMatches(r *http.Request, a actions) bool {
+	if a.FaultMatchHeader == "" {
+		return true
+	}
+	for _, value := range r.Header.Values(a.FaultMatchHeader) {
+		if value == a.FaultMatchValue {
+			return true
+		}
+	}
+	return false
+}
+
+// splitAtNextDirective splits s at the earliest directive segment, returning the part
+// before it and the remaining path (or "/" if there are no further directives)
+func splitAtNextDirective(s string) (before, remaining string) {
+	next := -1
+	for _, prefix := range directivePrefixes {
+		if idx := strings.Index(s, prefix); idx >= 0 && (next < 0 || idx < next) {
+			next = idx
+		}
+	}
+	if next < 0 {
+		return s, "/"
+	}
+	return s[:next], s[next:]
+}
+
+// parseTargetList splits a comma-separated target list from the path that follows it
+func parseTargetList(s string) (targets []string, remaining string, err error) {
+	targetList, remaining := splitAtNextDirective(s)
+	for _, target := range strings.Split(targetList, ",") {
+		if target == "" {
+			return nil, "", fmt.Errorf("empty target")
+		}
+		targets = append(targets, target)
+	}
+	return targets, remaining, nil
+}
+
+// proxyPrefix returns the forwarding directive path starts with, /proxy/ or /proxys/, or "" if
+// it starts with neither
+func proxyPrefix(path string) string {
+	for _, prefix := range []string{"/proxy/", "/proxys/"} {
+		if strings.HasPrefix(path, prefix) {
+			return prefix
+		}
+	}
+	return ""
+}
+
+// parseScheme strips an optional scheme from a hop, defaulting to http.
+// Format can be: "service:port" or "https:/service:port" or "http:/service:port"
+// Note: http:// and https:// get normalized to http:/ and https:/ in URL paths
+func parseScheme(hop string) (scheme, host string) {
+	if strings.HasPrefix(hop, "https:/") {
+		return "https", strings.TrimPrefix(hop, "https:/")
+	}
+	return "http", strings.TrimPrefix(hop, "http:/")
+}
+
 // parsePath validates and parses the proxy path into actions
 // Returns the actions to take and any error
 // Supports both /proxy/ and /fault/ segments:
 // - /proxy/service:port - forward to next service
 // - /fault/500 - always inject 500 error
 // - /fault/500/30 - inject 500 error 30% of the time
+// - /fault/badjson/30 - return malformed JSON 30% of the time
+// - /fault/503/100/match/x-canary=true - inject 503 only for requests with X-Canary: true
+// - /fanout/svca:8080,svcb:8080 - forward to every target concurrently and merge the responses
+// - /try/svca:8080,svcb:8080 - forward to targets in random order until one answers below 500
+// - /delay/250 - wait 250ms before processing the remaining path
+// - /slowstart/60/500 - wait up to 500ms, decreasing to 0 over the first 60s after server start
+// - /latency/20,80,200 - wait for a delay sampled from a distribution with p50 20ms, p95 80ms, and p99 200ms
+// - /bytes/1024 - answer with 1024 random bytes (must be the last directive)
+// - /ctype/text/html - declare the final response as text/html
+// - /reset/100 - write 100 bytes of the response then reset the connection (must be the last directive)
+// - /multipart/3 - answer with a multipart/mixed response of 3 JSON parts (must be the last directive)
+// - /trailer/x-checksum/abc - send X-Checksum: abc as a trailer after this hop's response body
+// - /setcookie/session/abc - set the cookie session=abc on this hop's response, with attributes from the query
+// - /chunked - stream the final response in small flushed chunks with chunked transfer encoding (must be the last directive)
+// - /slowbody/100 - write the final response one byte every 100ms (must be the last directive)
+// - /grpc-status/14 - answer 200 application/grpc with grpc-status: 14 in the trailers (must be the last directive)
+// - /seq/503,503,200 - answer successive requests for the same path with 503, 503, then continue with 200, repeating
+// - /flaky - fail odd-numbered requests for the same path with 500 and let even-numbered ones continue
+// - /flaky/3 - fail the first of every 3 requests for the same path with 500
+// - /proxys/service:port - forward to next service over HTTPS, like /proxy/https://service:port
+// - /repeat/5/proxy/service:port - send the request to the next service 5 times in turn and answer with the last response
 func parsePath(path string) (actions, error) {
 	if path == "" || path == "/" {
 		return actions{
@@ -198,15 +524,21 @@ func parsePath(path string) (actions, error) {
 			return actions{}, fmt.Errorf("invalid fault path: must be /fault/<code> or /fault/<code>/<percentage>")
 		}
 
-		// Parse status code
-		statusCode, err := strconv.Atoi(parts[2])
-		if err != nil {
-			return actions{}, fmt.Errorf("invalid fault code: must be a number")
-		}
+		// A badjson fault succeeds with a corrupt body rather than failing with a status code
+		badJSON := parts[2] == "badjson"
+		statusCode := http.StatusOK
+		if !badJSON {
+			// Parse status code
+			code, err := strconv.Atoi(parts[2])
+			if err != nil {
+				return actions{}, fmt.Errorf("invalid fault code: must be a number")
+			}
 
-		// Validate status code is 400-599
-		if statusCode < 400 || statusCode > 599 {
-			return actions{}, fmt.Errorf("invalid fault code: must be 400-599")
+			// Validate status code is 400-599
+			if code < 400 || code > 599 {
+				return actions{}, fmt.Errorf("invalid fault code: must be 400-599")
+			}
+			statusCode = code
 		}
 
 		// Default percentage to 100
@@ -227,6 +559,21 @@ func parsePath(path string) (actions, error) {
 			return actions{}, fmt.Errorf("invalid fault percentage: must be 0-100")
 		}
 
+		// Optionally restrict the fault to requests carrying a header value
+		var matchHeader, matchValue string
+		if len(parts) > startIdx && parts[startIdx] == "match" {
+			if len(parts) <= startIdx+1 {
+				return actions{}, fmt.Errorf("invalid fault match: must be /match/<header>=<value>")
+			}
+			name, value, ok := strings.Cut(parts[startIdx+1], "=")
+			if !ok || name == "" {
+				return actions{}, fmt.Errorf("invalid fault match: must be /match/<header>=<value>")
+			}
+			matchHeader = http.CanonicalHeaderKey(name)
+			matchValue = value
+			startIdx += 2
+		}
+
 		// Get remaining path
 		var remaining string
 		if len(parts) > startIdx {
@@ -236,67 +583,346 @@ func parsePath(path string) (actions, error) {
 		}
 
 		return actions{
-			NextHop:         "",
-			Remaining:       remaining,
-			IsLastHop:       false,
-			IsFault:         true,
-			FaultCode:       statusCode,
-			FaultPercentage: percentage,
+			NextHop:          "",
+			Remaining:        remaining,
+			IsLastHop:        false,
+			IsFault:          true,
+			FaultCode:        statusCode,
+			FaultPercentage:  percentage,
+			FaultBadJSON:     badJSON,
+			FaultMatchHeader: matchHeader,
+			FaultMatchValue:  matchValue,
 		}, nil
 	}
 
-	// Path must start with /proxy/
-	if !strings.HasPrefix(path, "/proxy/") {
-		return actions{}, fmt.Errorf("invalid path: must start with /proxy/ or /fault/")
+	// Check if this is a delay path
+	if strings.HasPrefix(path, "/delay/") {
+		ms, err := strconv.Atoi(parts[2])
+		if err != nil || ms < 0 {
+			return actions{}, fmt.Errorf("invalid delay: must be a non-negative number of milliseconds")
+		}
+
+		remaining := "/"
+		if len(parts) > 3 {
+			remaining = "/" + strings.Join(parts[3:], "/")
+		}
+
+		return actions{
+			Remaining: remaining,
+			IsDelay:   true,
+			Delay:     time.Duration(ms) * time.Millisecond,
+		}, nil
 	}
 
-	// Extract everything after "/proxy/"
-	afterProxy := strings.TrimPrefix(path, "/proxy/")
-	if afterProxy == "" {
-		return actions{}, fmt.Errorf("invalid path: empty service name")
+	// Check if this is a slow start path
+	if strings.HasPrefix(path, "/slowstart/") {
+		if len(parts) < 4 {
+			return actions{}, fmt.Errorf("invalid slowstart path: must be /slowstart/<durationSec>/<maxMs>")
+		}
+		sec, err := strconv.Atoi(parts[2])
+		if err != nil || sec <= 0 {
+			return actions{}, fmt.Errorf("invalid slowstart duration: must be a positive number of seconds")
+		}
+		ms, err := strconv.Atoi(parts[3])
+		if err != nil || ms < 0 {
+			return actions{}, fmt.Errorf("invalid slowstart delay: must be a non-negative number of milliseconds")
+		}
+
+		remaining := "/"
+		if len(parts) > 4 {
+			remaining = "/" + strings.Join(parts[4:], "/")
+		}
+
+		return actions{
+			Remaining: remaining,
+			IsDelay:   true,
+			Delay:     time.Duration(ms) * time.Millisecond,
+			SlowStart: time.Duration(sec) * time.Second,
+		}, nil
 	}
 
-	// Find the next "/proxy/" or "/fault/" segment to determine where nextHop ends
-	var nextHop, remaining string
-	nextProxyIdx := strings.Index(afterProxy, "/proxy/")
-	nextFaultIdx := strings.Index(afterProxy, "/fault/")
+	// Check if this is a latency profile path
+	if strings.HasPrefix(path, "/latency/") {
+		profile, err := parseLatencyProfile(parts[2])
+		if err != nil {
+			return actions{}, err
+		}
 
-	var nextSegmentIdx int
-	if nextProxyIdx >= 0 && nextFaultIdx >= 0 {
-		// Both found, use the earlier one
-		if nextProxyIdx < nextFaultIdx {
-			nextSegmentIdx = nextProxyIdx
-		} else {
-			nextSegmentIdx = nextFaultIdx
+		remaining := "/"
+		if len(parts) > 3 {
+			remaining = "/" + strings.Join(parts[3:], "/")
+		}
+
+		return actions{
+			Remaining:      remaining,
+			IsDelay:        true,
+			LatencyProfile: profile,
+		}, nil
+	}
+
+	// Check if this is a content type override path
+	if strings.HasPrefix(path, "/ctype/") {
+		if len(parts) < 4 {
+			return actions{}, fmt.Errorf("invalid ctype path: must be /ctype/<type>/<subtype>")
+		}
+		contentType := parts[2] + "/" + parts[3]
+		if _, _, err := mime.ParseMediaType(contentType); err != nil || parts[2] == "" || parts[3] == "" {
+			return actions{}, fmt.Errorf("invalid ctype path: %q is not a valid media type", contentType)
+		}
+
+		remaining := "/"
+		if len(parts) > 4 {
+			remaining = "/" + strings.Join(parts[4:], "/")
+		}
+
+		return actions{
+			Remaining:   remaining,
+			ContentType: contentType,
+		}, nil
+	}
+
+	// Check if this is a random bytes path
+	if strings.HasPrefix(path, "/bytes/") {
+		n, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil || n < 0 {
+			return actions{}, fmt.Errorf("invalid bytes: must be a non-negative number")
+		}
+		if len(parts) > 3 && strings.Join(parts[3:], "") != "" {
+			return actions{}, fmt.Errorf("invalid bytes path: /bytes/<n> must be the last directive")
+		}
+
+		return actions{
+			Remaining: "/",
+			IsBytes:   true,
+			Bytes:     n,
+		}, nil
+	}
+
+	// Check if this is a response trailer path
+	if strings.HasPrefix(path, "/trailer/") {
+		if len(parts) < 4 {
+			return actions{}, fmt.Errorf("invalid trailer path: must be /trailer/<name>/<value>")
+		}
+		name, err := parseTrailer(parts[2], parts[3])
+		if err != nil {
+			return actions{}, err
+		}
+
+		remaining := "/"
+		if len(parts) > 4 {
+			remaining = "/" + strings.Join(parts[4:], "/")
+		}
+
+		return actions{
+			Remaining:    remaining,
+			TrailerName:  name,
+			TrailerValue: parts[3],
+		}, nil
+	}
+
+	// Check if this is a set-cookie path
+	if strings.HasPrefix(path, "/setcookie/") {
+		if len(parts) < 4 {
+			return actions{}, fmt.Errorf("invalid setcookie path: must be /setcookie/<name>/<value>")
+		}
+		cookie, err := parseSetCookie(parts[2], parts[3])
+		if err != nil {
+			return actions{}, err
+		}
+
+		remaining := "/"
+		if len(parts) > 4 {
+			remaining = "/" + strings.Join(parts[4:], "/")
+		}
+
+		return actions{
+			Remaining: remaining,
+			Cookie:    cookie,
+		}, nil
+	}
+
+	// Check if this is a multipart response path
+	if strings.HasPrefix(path, "/multipart/") {
+		n, err := parseMultipartParts(parts[2])
+		if err != nil {
+			return actions{}, err
+		}
+		if len(parts) > 3 && strings.Join(parts[3:], "") != "" {
+			return actions{}, fmt.Errorf("invalid multipart path: /multipart/<parts> must be the last directive")
+		}
+
+		return actions{
+			Remaining:      "/",
+			MultipartParts: n,
+		}, nil
+	}
+
+	// Check if this is a connection reset path
+	if strings.HasPrefix(path, "/reset/") {
+		n, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil || n < 0 {
+			return actions{}, fmt.Errorf("invalid reset: must be a non-negative number of bytes")
+		}
+		if len(parts) > 3 && strings.Join(parts[3:], "") != "" {
+			return actions{}, fmt.Errorf("invalid reset path: /reset/<bytes> must be the last directive")
+		}
+
+		return actions{
+			Remaining:  "/",
+			IsReset:    true,
+			ResetBytes: n,
+		}, nil
+	}
+
+	// Check if this is a chunked response path
+	if path == "/chunked" || strings.HasPrefix(path, "/chunked/") {
+		if len(parts) > 2 && strings.Join(parts[2:], "") != "" {
+			return actions{}, fmt.Errorf("invalid chunked path: /chunked must be the last directive")
+		}
+
+		return actions{
+			Remaining: "/",
+			IsChunked: true,
+		}, nil
+	}
+
+	// Check if this is a slow body path
+	if strings.HasPrefix(path, "/slowbody/") {
+		ms, err := strconv.Atoi(parts[2])
+		if err != nil || ms < 0 {
+			return actions{}, fmt.Errorf("invalid slowbody: interval must be a non-negative number of milliseconds")
+		}
+		if len(parts) > 3 && strings.Join(parts[3:], "") != "" {
+			return actions{}, fmt.Errorf("invalid slowbody path: /slowbody/<intervalMs> must be the last directive")
+		}
+
+		return actions{
+			Remaining:        "/",
+			IsSlowBody:       true,
+			SlowBodyInterval: time.Duration(ms) * time.Millisecond,
+		}, nil
+	}
+
+	// Check if this is a gRPC status path
+	if strings.HasPrefix(path, "/grpc-status/") {
+		code, err := parseGRPCStatus(parts[2])
+		if err != nil {
+			return actions{}, err
+		}
+		if len(parts) > 3 && strings.Join(parts[3:], "") != "" {
+			return actions{}, fmt.Errorf("invalid grpc-status path: /grpc-status/<code> must be the last directive")
+		}
+
+		return actions{
+			Remaining:    "/",
+			IsGRPCStatus: true,
+			GRPCStatus:   code,
+		}, nil
+	}
+
+	// Check if this is a status sequence path
+	if strings.HasPrefix(path, "/seq/") {
+		statuses, err := parseSeqStatuses(parts[2])
+		if err != nil {
+			return actions{}, err
+		}
+
+		remaining := "/"
+		if len(parts) > 3 {
+			remaining = "/" + strings.Join(parts[3:], "/")
+		}
+
+		return actions{
+			Remaining:   remaining,
+			SeqStatuses: statuses,
+		}, nil
+	}
+
+	// Check if this is a flaky path
+	if path == "/flaky" || strings.HasPrefix(path, "/flaky/") {
+		every, remaining, err := parseFlaky(parts[2:])
+		if err != nil {
+			return actions{}, err
+		}
+
+		return actions{
+			Remaining:  remaining,
+			FlakyEvery: every,
+		}, nil
+	}
+
+	// Check if this is a repeat path
+	if strings.HasPrefix(path, "/repeat/") {
+		n, err := parseRepeat(parts[2])
+		if err != nil {
+			return actions{}, err
+		}
+
+		remaining := "/"
+		if len(parts) > 3 {
+			remaining = "/" + strings.Join(parts[3:], "/")
+		}
+
+		return actions{
+			Remaining:   remaining,
+			RepeatCount: n,
+		}, nil
+	}
+
+	// Check if this is a fanout path
+	if strings.HasPrefix(path, "/fanout/") {
+		targets, remaining, err := parseTargetList(strings.TrimPrefix(path, "/fanout/"))
+		if err != nil {
+			return actions{}, fmt.Errorf("invalid fanout path: %w", err)
+		}
+
+		return actions{
+			Remaining:     remaining,
+			FanoutTargets: targets,
+		}, nil
+	}
+
+	// Check if this is a try path
+	if strings.HasPrefix(path, "/try/") {
+		targets, remaining, err := parseTargetList(strings.TrimPrefix(path, "/try/"))
+		if err != nil {
+			return actions{}, fmt.Errorf("invalid try path: %w", err)
 		}
-	} else if nextProxyIdx >= 0 {
-		nextSegmentIdx = nextProxyIdx
-	} else if nextFaultIdx >= 0 {
-		nextSegmentIdx = nextFaultIdx
-	} else {
-		// No more segments, entire afterProxy is the nextHop
-		nextSegmentIdx = -1
+
+		return actions{
+			Remaining:  remaining,
+			TryTargets: targets,
+		}, nil
 	}
 
-	if nextSegmentIdx >= 0 {
-		nextHop = afterProxy[:nextSegmentIdx]
-		remaining = afterProxy[nextSegmentIdx:]
-	} else {
-		nextHop = afterProxy
-		remaining = "/"
+	// Path must start with /proxy/ or its HTTPS shortcut /proxys/
+	prefix := proxyPrefix(path)
+	if prefix == "" {
+		if name := directiveName(path); name != "" {
+			return actions{}, fmt.Errorf("invalid path: %s is missing its arguments", name)
+		}
+		return actions{}, fmt.Errorf("%w: must start with one of %s", errUnknownRoute, strings.Join(directivePrefixes, ", "))
 	}
 
+	// Extract everything after the prefix
+	afterProxy := strings.TrimPrefix(path, prefix)
+	if afterProxy == "" {
+		return actions{}, fmt.Errorf("invalid path: empty service name")
+	}
+
+	// The next directive segment determines where nextHop ends
+	nextHop, remaining := splitAtNextDirective(afterProxy)
+
 	// Parse scheme from nextHop
-	// Format can be: "service:port" or "https:/service:port" or "http:/service:port"
-	// Note: http:// and https:// get normalized to http:/ and https:/ in URL paths
-	scheme := "http" // default to http
-	if strings.HasPrefix(nextHop, "https:/") {
+	scheme, host := parseScheme(nextHop)
+	if prefix == "/proxys/" {
+		// The shortcut always means HTTPS, so an inline scheme is a mistake rather than an override
+		if host != nextHop {
+			return actions{}, fmt.Errorf("invalid proxys path: /proxys/ always uses https, so the target must not include a scheme")
+		}
 		scheme = "https"
-		nextHop = strings.TrimPrefix(nextHop, "https:/")
-	} else if strings.HasPrefix(nextHop, "http:/") {
-		scheme = "http"
-		nextHop = strings.TrimPrefix(nextHop, "http:/")
 	}
+	nextHop = host
 
 	// Validate nextHop is not empty after parsing
 	if nextHop == "" || nextHop == "/" {
@@ -311,82 +937,317 @@ func parsePath(path string) (actions, error) {
 	}, nil
 }
 
-// ServeHTTP handles incoming HTTP requests with comprehensive logging
+// ServeHTTP handles incoming HTTP requests, recording them when a record file is configured
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Time the whole request, including any rejection below
+	if h.latencyHeader {
+		w = &responseTimeWriter{ResponseWriter: w, start: time.Now()}
+	}
+
+	// Clean the path before anything reads it, turning away traversal attempts when configured
+	r, ok := h.normalizePath(w, r)
+	if !ok {
+		return
+	}
+
+	// Send the interim response before any wrapper could mistake it for the final status
+	if h.expectContinue && expectsContinue(r) {
+		w.WriteHeader(http.StatusContinue)
+	}
+
+	if h.recorder != nil {
+		rec := &statusRecorder{ResponseWriter: w}
+		defer h.recordRequest(rec, r, captureBody(r), time.Now())
+		w = rec
+	}
+
+	h.serve(w, r)
+}
+
+// serve runs a request through the proxy chain, rejecting requests with too many headers or a
+// disallowed method, tracing it when asked with ?trace=true, applying any X-Inject-Fault header,
+// shedding load while the heap is over its limit, rejecting clients over the rate limit, queueing
+// requests over the concurrency limit, decompressing gzip bodies when enabled, and replaying
+// cached responses for repeated idempotency keys. It is shared by ServeHTTP, ServeCompose and
+// ServeReplay, so their requests are held to the same limits.
+func (h *Handler) serve(w http.ResponseWriter, r *http.Request) {
+	// Counting headers is the cheapest check, so it comes first
+	if !h.allowHeaderCount(w, r) {
+		return
+	}
+
+	// Turn away disallowed methods before any work is done for them
+	if !h.allowMethod(w, r) {
+		return
+	}
+
+	if traceRequested(r) {
+		w = &traceWriter{ResponseWriter: w, service: h.serviceName, start: time.Now()}
+	}
+
+	faulted, err := withInjectedFault(r)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, ErrCodeBadRequest, err.Error(), h.logger)
+		return
+	}
+	r = faulted
+
+	if h.heapGuard != nil && h.shedForHeap(w, r) {
+		return
+	}
+
+	if h.rateLimiter != nil && h.rateLimited(w, r) {
+		return
+	}
+
+	if h.concurrency != nil {
+		if !h.acquireSlot(w, r) {
+			return
+		}
+		defer h.releaseSlot()
+	}
+
+	h.limitBodyRead(w, r)
+
+	// Signatures cover the body as sent, so check them before it is decompressed
+	if len(h.hmacSecret) > 0 && !h.verifySignature(w, r) {
+		return
+	}
+
+	if h.decompressRequests && isGzipEncoded(r) && !h.decompressBody(w, r) {
+		return
+	}
+
+	if h.idempotency != nil {
+		if key := r.Header.Get(idempotencyKeyHeader); key != "" {
+			h.serveIdempotent(w, r, key)
+			return
+		}
+	}
+
+	if h.coalescing && coalescible(r) {
+		h.serveCoalesced(w, r)
+		return
+	}
+
+	h.serveProxy(w, r)
+}
+
+// serveProxy processes the request path with comprehensive logging
+func (h *Handler) serveProxy(w http.ResponseWriter, r *http.Request) {
 	startTime := time.Now()
-	requestID := fmt.Sprintf("%d", startTime.UnixNano())
 
-	// Create logger with request context
-	logger := h.logger.With(slog.String("request_id", requestID), slog.String("method", r.Method), slog.String("path", r.URL.Path), slog.String("service", h.serviceName), slog.String("remote_addr", r.RemoteAddr))
+	// Create logger with request context, sampled by request ID
+	id := requestID(r)
+	logger := h.requestLogger(id).With(slog.String("request_id", id), slog.Int64("request_timestamp", startTime.UnixNano()), slog.String("method", r.Method), slog.String("path", r.URL.Path), slog.String("service", h.serviceName), slog.String("remote_addr", r.RemoteAddr), slog.String("client_ip", clientIP(r, h.trustProxyHeaders)))
+	r, span, traced := h.startSpan(r)
+	if traced {
+		logger = logger.With(slog.String("trace_id", span.TraceID), slog.String("span_id", span.SpanID))
+	}
 	logger.Info("Incoming request",
 		slog.String("user_agent", r.UserAgent()),
 		slog.String("query", r.URL.RawQuery),
 		h.headersToLogAttrs(r.Header, "request_headers"))
 
-	// Parse the current hop from the path
-	actions, err := parsePath(r.URL.Path)
+	// CONNECT asks for a tunnel rather than a request to forward, so it is never proxied
+	if r.Method == http.MethodConnect {
+		logger.Info("Rejecting CONNECT request")
+		w.Header().Set("Allow", h.connectAllow())
+		h.writeError(w, r, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method CONNECT is not supported", logger)
+		return
+	}
+
+	// Parse this hop's directives from the path, starting with any fault requested by header
+	steps, err := parseHop(r.URL.Path, h.maxFanout)
 	if err != nil {
 		logger.Error("Path parsing failed", slog.String("error", err.Error()), slog.String("path", r.URL.Path))
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		h.writePathError(w, r, err, logger)
 		return
 	}
+	if fault, ok := injectedFault(r.Context()); ok {
+		fault.Remaining = r.URL.Path
+		steps = append([]actions{fault}, steps...)
+	}
+	actions := steps[len(steps)-1]
 
-	logger.Debug("Path parsed successfully", slog.String("next_hop", actions.NextHop), slog.String("remaining", actions.Remaining), slog.Bool("is_last_hop", actions.IsLastHop))
+	logger.Debug("Path parsed successfully", slog.Int("local_directives", len(steps)-1), slog.String("next_hop", actions.NextHop), slog.String("remaining", actions.Remaining), slog.Bool("is_last_hop", actions.IsLastHop))
 
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	// Create context bounded by the request deadline
+	timeout, err := h.requestTimeout(r)
+	if err != nil {
+		logger.Info("Invalid request timeout", slog.String("error", err.Error()))
+		h.writeError(w, r, http.StatusBadRequest, ErrCodeBadRequest, err.Error(), logger)
+		return
+	}
+	ctx, cancel := context.WithDeadline(r.Context(), h.requestDeadline(r, startTime, timeout))
 	defer cancel()
 
-	// Handle fault injection
-	if actions.IsFault {
-		logger.Info("Fault injection detected", slog.Int("fault_code", actions.FaultCode), slog.Int("percentage", actions.FaultPercentage))
-
-		// Determine if fault should trigger based on percentage
-		shouldTrigger := rand.Intn(100) < actions.FaultPercentage
-
-		if shouldTrigger {
-			logger.Info("Fault triggered", slog.Int("fault_code", actions.FaultCode))
-
-			if err := h.sendFaultResponse(w, actions.FaultCode, logger); err != nil {
-				logger.Error("Failed to send fault response", slog.String("error", err.Error()))
-				http.Error(w, fmt.Sprintf("Response error: %v", err), http.StatusInternalServerError)
+	// Apply local directives (delays, faults, response overrides) in path order before the step
+	// that answers or forwards the request
+	contentType := h.responseContentType
+	trailers := http.Header{}
+	finalStatus := http.StatusOK
+	repeat := 1
+	for _, step := range steps[:len(steps)-1] {
+		switch {
+		case step.RepeatCount > 0:
+			if h.maxRepeat > 0 && step.RepeatCount > h.maxRepeat {
+				logger.Info("Repeat count too high", slog.Int("repeat", step.RepeatCount), slog.Int("max_repeat", h.maxRepeat))
+				h.writeError(w, r, http.StatusBadRequest, ErrCodeBadPath, fmt.Sprintf("Repeat count %d exceeds the maximum of %d", step.RepeatCount, h.maxRepeat), logger)
 				return
 			}
+			repeat = step.RepeatCount
 
-			duration := time.Since(startTime)
-			logger.Info("Fault injection completed",
-				slog.Duration("duration", duration),
-				slog.Int("status_code", actions.FaultCode),
-				h.headersToLogAttrs(w.Header(), "response_headers"))
-			return
-		}
+		case step.ContentType != "":
+			logger.Debug("Overriding response content type", slog.String("content_type", step.ContentType))
+			contentType = step.ContentType
 
-		logger.Info("Fault not triggered, continuing to next segment", slog.String("remaining", actions.Remaining))
+		case step.TrailerName != "":
+			logger.Debug("Adding response trailer", slog.String("trailer", step.TrailerName))
+			trailers.Add(step.TrailerName, step.TrailerValue)
 
-		// Fault didn't trigger, continue processing remaining path
-		// If there's a remaining path, process it recursively
-		if actions.Remaining != "/" {
-			// Parse and process the remaining path
-			nextActions, err := parsePath(actions.Remaining)
+		case step.Cookie != nil:
+			cookie, err := withCookieAttributes(step.Cookie, r.URL.Query())
 			if err != nil {
-				logger.Error("Failed to parse remaining path", slog.String("error", err.Error()))
-				http.Error(w, err.Error(), http.StatusBadRequest)
+				logger.Info("Invalid cookie attributes", slog.String("error", err.Error()))
+				h.writeError(w, r, http.StatusBadRequest, ErrCodeBadRequest, err.Error(), logger)
 				return
 			}
-			actions = nextActions
-			logger.Debug("Continuing with remaining path", slog.String("next_hop", actions.NextHop), slog.String("remaining", actions.Remaining))
-		} else {
-			// No remaining path, return success
-			logger.Info("No remaining path, returning success")
-			if err := h.sendFinalResponse(w, http.StatusOK, logger); err != nil {
-				logger.Error("Failed to send final response", slog.String("error", err.Error()))
-				http.Error(w, fmt.Sprintf("Response error: %v", err), http.StatusInternalServerError)
+			logger.Debug("Setting response cookie", slog.String("cookie", cookie.Name))
+			http.SetCookie(w, cookie)
+
+		case len(step.SeqStatuses) > 0:
+			status := h.sequences.advance(r.URL.Path, step.SeqStatuses)
+			logger.Info("Status sequence advanced", slog.Int("status_code", status))
+			if isErrorStatus(status) {
+				if err := h.sendFaultResponse(w, status, logger); err != nil {
+					logger.Error("Failed to send sequence response", slog.String("error", err.Error()))
+				}
+				logger.Info("Request completed", slog.Duration("duration", time.Since(startTime)), slog.Int("status_code", status))
 				return
 			}
-			duration := time.Since(startTime)
-			logger.Info("Request completed", slog.Duration("duration", duration), slog.Int("status_code", http.StatusOK))
+			finalStatus = status
+
+		case step.FlakyEvery > 0:
+			if h.flakiness.position(r.URL.Path, step.FlakyEvery) == 0 {
+				logger.Info("Flaky request failed", slog.Int("every", step.FlakyEvery))
+				if err := h.sendFaultResponse(w, http.StatusInternalServerError, logger); err != nil {
+					logger.Error("Failed to send flaky response", slog.String("error", err.Error()))
+				}
+				logger.Info("Request completed", slog.Duration("duration", time.Since(startTime)), slog.Int("status_code", http.StatusInternalServerError))
+				return
+			}
+
+		case step.IsDelay:
+			delay := step.Delay
+			if step.SlowStart > 0 {
+				delay = slowStartDelay(time.Since(h.started), step.SlowStart, step.Delay)
+			}
+			if step.LatencyProfile != nil {
+				delay = sampleLatency(step.LatencyProfile, rand.Float64())
+			}
+			if !h.delay(ctx, w, r, delay, logger) {
+				return
+			}
+
+		default:
+			logger.Info("Fault injection detected", slog.Int("fault_code", step.FaultCode), slog.Int("percentage", step.FaultPercentage))
+
+			// Determine if fault should trigger based on the request match and percentage
+			shouldTrigger := faultMatches(r, step) && rand.Intn(100) < step.FaultPercentage
+
+			if shouldTrigger {
+				logger.Info("Fault triggered", slog.Int("fault_code", step.FaultCode), slog.Bool("bad_json", step.FaultBadJSON))
+
+				sendFault := h.sendFaultResponse
+				if step.FaultBadJSON {
+					sendFault = h.sendBadJSONResponse
+				}
+				if err := sendFault(w, step.FaultCode, logger); err != nil {
+					logger.Error("Failed to send fault response", slog.String("error", err.Error()))
+					h.writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, fmt.Sprintf("Response error: %v", err), logger)
+					return
+				}
+
+				duration := time.Since(startTime)
+				logger.Info("Fault injection completed",
+					slog.Duration("duration", duration),
+					slog.Int("status_code", step.FaultCode),
+					h.headersToLogAttrs(w.Header(), "response_headers"))
+				return
+			}
+
+			logger.Info("Fault not triggered, continuing to next segment", slog.String("remaining", step.Remaining))
+		}
+	}
+
+	// Send any requested trailers after whichever response follows
+	if len(trailers) > 0 {
+		var setTrailers func()
+		w, setTrailers = declareTrailers(w, trailers)
+		defer setTrailers()
+	}
+
+	// Answer with generated random bytes
+	if actions.IsBytes {
+		h.sendBytes(ctx, w, r, actions.Bytes, logger)
+		logger.Info("Request completed", slog.Duration("duration", time.Since(startTime)), slog.Int64("bytes", actions.Bytes))
+		return
+	}
+
+	// Answer with a multipart response
+	if actions.MultipartParts > 0 {
+		h.sendMultipart(w, actions.MultipartParts, logger)
+		logger.Info("Request completed", slog.Duration("duration", time.Since(startTime)), slog.Int("parts", actions.MultipartParts))
+		return
+	}
+
+	// Break the connection partway through the response
+	if actions.IsReset {
+		h.sendReset(w, r, actions.ResetBytes, logger)
+		logger.Info("Request completed", slog.Duration("duration", time.Since(startTime)), slog.Int64("bytes", actions.ResetBytes))
+		return
+	}
+
+	// Answer with a gRPC status
+	if actions.IsGRPCStatus {
+		h.sendGRPCStatus(w, actions.GRPCStatus, logger)
+		logger.Info("Request completed", slog.Duration("duration", time.Since(startTime)), slog.Int("grpc_status", actions.GRPCStatus))
+		return
+	}
+
+	// Answer with the final response streamed in chunks
+	if actions.IsChunked {
+		if err := h.sendFinalResponse(ctx, newChunkedWriter(w), r, finalStatus, contentType, logger); err != nil {
+			logger.Error("Failed to send chunked response", slog.String("error", err.Error()))
 			return
 		}
+		logger.Info("Request completed", slog.Duration("duration", time.Since(startTime)), slog.Int("status_code", finalStatus))
+		return
+	}
+
+	// Answer with the final response dripped out a byte at a time
+	if actions.IsSlowBody {
+		if err := h.sendFinalResponse(ctx, newSlowBodyWriter(ctx, w, actions.SlowBodyInterval), r, finalStatus, contentType, logger); err != nil {
+			logger.Warn("Slow body response interrupted", slog.String("error", err.Error()))
+			return
+		}
+		logger.Info("Request completed", slog.Duration("duration", time.Since(startTime)), slog.Int("status_code", finalStatus))
+		return
+	}
+
+	// Try several targets in turn until one succeeds
+	if len(actions.TryTargets) > 0 {
+		h.handleTry(ctx, w, r, actions, logger)
+		logger.Info("Request completed", slog.Duration("duration", time.Since(startTime)))
+		return
+	}
+
+	// Fan out to several targets and merge their responses
+	if len(actions.FanoutTargets) > 0 {
+		h.handleFanout(ctx, w, r, actions, logger)
+		logger.Info("Request completed", slog.Duration("duration", time.Since(startTime)))
+		return
 	}
 
 	// If this is the last hop, we're done
@@ -394,64 +1255,103 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		logger.Info("Processing as final hop")
 
 		// Create our own response since we're the final destination
-		if err := h.sendFinalResponse(w, http.StatusOK, logger); err != nil {
+		if err := h.sendFinalResponse(ctx, w, r, finalStatus, contentType, logger); err != nil {
 			logger.Error("Failed to send final response", slog.String("error", err.Error()))
-			http.Error(w, fmt.Sprintf("Response error: %v", err), http.StatusInternalServerError)
+			h.writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, fmt.Sprintf("Response error: %v", err), logger)
 			return
 		}
 
 		duration := time.Since(startTime)
 		logger.Info("Request completed",
 			slog.Duration("duration", duration),
-			slog.Int("status_code", http.StatusOK),
+			slog.Int("status_code", finalStatus),
 			h.headersToLogAttrs(w.Header(), "response_headers"))
 		return
 	}
 
 	// Construct the next hop URL with port, using only the remaining path
-	nextHopURL := fmt.Sprintf("%s://%s%s", actions.Scheme, actions.NextHop, actions.Remaining)
+	nextHopURL := h.nextHopQuery(r, fmt.Sprintf("%s://%s%s", actions.Scheme, actions.NextHop, actions.Remaining))
+	if h.forwardFullPath {
+		nextHopURL = fullPathURL(r, actions.Scheme)
+	}
 
 	logger.Info("Forwarding to next hop",
 		slog.String("next_hop_url", nextHopURL),
 		slog.String("scheme", actions.Scheme),
-		slog.String("next_service", actions.NextHop))
+		slog.String("next_service", actions.NextHop),
+		slog.Int("repeat", repeat))
 
-	// Forward to next hop
-	nextReq, err := http.NewRequestWithContext(ctx, r.Method, nextHopURL, r.Body)
-	if err != nil {
-		logger.Error("Failed to create next hop request", slog.String("error", err.Error()), slog.String("next_hop_url", nextHopURL))
-		http.Error(w, fmt.Sprintf("Failed to create next hop request: %v", err), http.StatusInternalServerError)
+	// Don't start a hop that can no longer finish in time
+	if ctx.Err() != nil {
+		logger.Info("Deadline exceeded before forwarding", slog.String("next_hop_url", nextHopURL))
+		h.writeError(w, r, http.StatusGatewayTimeout, ErrCodeGatewayTimeout, "Deadline exceeded before forwarding to next hop", logger)
 		return
 	}
 
-	// Propagate incoming request headers to the next hop
-	if h.propagateRequestHeaders {
-		for k, v := range r.Header {
-			for _, val := range v {
-				nextReq.Header.Add(k, val)
-			}
-		}
+	// Protocol upgrades become a byte pipe to the next hop instead of a forwarded response
+	if h.websocket && isUpgradeRequest(r) {
+		h.forwardUpgrade(w, r, nextHopURL, logger)
+		return
+	}
+
+	// Forward to next hop, noting which IP the hostname resolved to
+	ctx, upstream := traceUpstreamConn(ctx)
+	newNextReq, err := h.upstreamRequests(ctx, r, nextHopURL, repeat > 1)
+	var transformErr *transformError
+	if errors.As(err, &transformErr) {
+		logger.Info("Failed to transform request body", slog.String("error", err.Error()))
+		h.writeError(w, r, http.StatusBadRequest, ErrCodeBadRequest, err.Error(), logger)
+		return
+	}
+	if err != nil && bodyReadTimedOut(r) {
+		h.writeBodyReadError(w, r, errBodyReadTimeout, logger)
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to create next hop request", slog.String("error", err.Error()), slog.String("next_hop_url", nextHopURL))
+		h.writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, fmt.Sprintf("Failed to create next hop request: %v", err), logger)
+		return
 	}
 
 	forwardStartTime := time.Now()
 
-	// Forward to the next hop
-	nextResp, err := h.client.Do(nextReq)
+	// Forward to the next hop, repeating and retrying as configured
+	nextResp, err := h.doRepeated(ctx, newNextReq, repeat, logger)
 	if err != nil {
 		forwardDuration := time.Since(forwardStartTime)
 		logger.Error("Next hop request failed", slog.String("error", err.Error()), slog.String("next_hop_url", nextHopURL), slog.Duration("forward_duration", forwardDuration))
-		http.Error(w, fmt.Sprintf("Next hop error: %v", err), http.StatusBadGateway)
+		if bodyReadTimedOut(r) {
+			h.writeBodyReadError(w, r, errBodyReadTimeout, logger)
+			return
+		}
+		if isTimeout(err) {
+			h.writeError(w, r, http.StatusGatewayTimeout, ErrCodeGatewayTimeout, fmt.Sprintf("Next hop timed out: %v", err), logger)
+			return
+		}
+		h.writeError(w, r, http.StatusBadGateway, ErrCodeBadGateway, fmt.Sprintf("Next hop error: %v", err), logger)
 		return
 	}
 	defer func() { _ = nextResp.Body.Close() }()
 
 	forwardDuration := time.Since(forwardStartTime)
-	logger.Info("Next hop response received", slog.Int("status_code", nextResp.StatusCode), slog.Duration("forward_duration", forwardDuration), slog.String("next_hop_url", nextHopURL))
+	logger.Info("Next hop response received", slog.Int("status_code", nextResp.StatusCode), slog.Duration("forward_duration", forwardDuration), slog.String("next_hop_url", nextHopURL), slog.String("upstream_ip", upstream.ip()))
+
+	// Carry the later hops' trace back so this hop's entry is prepended to it
+	if downstream := nextResp.Header.Get(traceHeader); downstream != "" && traceRequested(r) {
+		nextResp.Header.Del(traceHeader)
+		w.Header().Set(traceHeader, downstream)
+	}
 
 	// Forward the downstream response as-is (don't modify the service field)
-	if err := h.forwardResponse(w, nextResp, logger); err != nil {
+	forward := h.forwardResponse
+	if h.grpcWeb && isGRPCWeb(r.Header.Get("Content-Type")) {
+		forward = h.forwardGRPCWebResponse
+	} else if aggregateRequested(r) {
+		forward = h.forwardAggregatedResponse
+	}
+	if err := forward(w, nextResp, logger); err != nil {
 		logger.Error("Failed to forward response", slog.String("error", err.Error()), slog.Int("upstream_status", nextResp.StatusCode))
-		http.Error(w, fmt.Sprintf("Response error: %v", err), http.StatusInternalServerError)
+		h.writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, fmt.Sprintf("Response error: %v", err), logger)
 		return
 	}
 
@@ -464,25 +1364,115 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		h.headersToLogAttrs(w.Header(), "response_headers"))
 }
 
-// sendFinalResponse creates and sends our own response when we're the final destination
-func (h *Handler) sendFinalResponse(w http.ResponseWriter, statusCode int, logger *slog.Logger) error {
+// newUpstreamRequest builds a request to an upstream hop mirroring the incoming request's method
+// and, when propagation is enabled, its headers
+func (h *Handler) newUpstreamRequest(ctx context.Context, r *http.Request, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, r.Method, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Propagate incoming request headers to the next hop
+	if h.propagateRequestHeaders {
+		for k, v := range r.Header {
+			for _, val := range v {
+				req.Header.Add(k, val)
+			}
+		}
+	}
+
+	if h.upstreamUserAgent != "" {
+		req.Header.Set("User-Agent", strings.ReplaceAll(h.upstreamUserAgent, "{service}", h.serviceName))
+	}
+
+	// Make this hop's span the parent of the next hop's
+	h.injectSpan(ctx, req.Header)
+
+	// Hand the remaining budget to the next hop as an absolute deadline
+	if h.usesDeadlineHeader() {
+		if deadline, ok := ctx.Deadline(); ok {
+			req.Header.Set(deadlineHeader, formatDeadline(deadline))
+		}
+	}
+	return req, nil
+}
+
+// sendFinalResponse creates and sends our own response when we're the final destination.
+// The body is serialized as JSON, XML, or plain text according to the request's Accept header,
+// unless contentType is set, in which case the JSON body is declared as that type instead.
+// ctx bounds any per-KiB latency.
+func (h *Handler) sendFinalResponse(ctx context.Context, w http.ResponseWriter, r *http.Request, statusCode int, contentType string, logger *slog.Logger) error {
 	logger.Debug("Sending final response", slog.Int("status_code", statusCode), slog.String("service", h.serviceName))
 
+	if h.responseTemplate != nil {
+		return h.sendTemplatedResponse(ctx, w, r, statusCode, contentType, logger)
+	}
+
 	response := Response{
 		Status:  statusCode,
 		Service: h.serviceName,
-		Message: "Request processed successfully",
+		Message: strings.ReplaceAll(h.responseMessage, "{service}", h.serviceName),
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
+	mediaType, ok := negotiateMediaType(r.Header.Get("Accept"))
+	if contentType != "" {
+		mediaType, ok = mediaTypeJSON, true
+	}
+	if !ok {
+		if h.strictAccept {
+			logger.Info("No acceptable media type", slog.String("accept", r.Header.Get("Accept")))
+			h.writeError(w, r, http.StatusNotAcceptable, ErrCodeNotAcceptable, "Not Acceptable: supported media types are application/json, application/xml, text/plain", logger)
+			return nil
+		}
+		mediaType = mediaTypeJSON
+	}
 
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		logger.Error("Failed to encode JSON response", slog.String("error", err.Error()))
+	if contentType == "" {
+		contentType = mediaType
+	}
+
+	var body bytes.Buffer
+	if err := h.responseFields.encode(&body, mediaType, response); err != nil {
+		logger.Error("Failed to encode response", slog.String("error", err.Error()), slog.String("content_type", mediaType))
 		return err
 	}
 
-	logger.Debug("Final response sent successfully")
+	w.Header().Set("Content-Type", contentType)
+	if err := h.writeFinalBody(ctx, w, r, statusCode, body.Bytes(), logger); err != nil {
+		logger.Error("Failed to write response", slog.String("error", err.Error()), slog.String("content_type", mediaType))
+		return err
+	}
+
+	logger.Debug("Final response sent successfully", slog.String("content_type", mediaType))
+	return nil
+}
+
+// sendTemplatedResponse renders the configured response template as the final response, declared
+// as contentType when set or as the detected type of the rendered body otherwise
+func (h *Handler) sendTemplatedResponse(ctx context.Context, w http.ResponseWriter, r *http.Request, statusCode int, contentType string, logger *slog.Logger) error {
+	body, detectedType, err := renderResponseTemplate(h.responseTemplate, TemplateData{
+		Service: h.serviceName,
+		Status:  statusCode,
+		Method:  r.Method,
+		Path:    r.URL.Path,
+		Query:   r.URL.RawQuery,
+		Headers: r.Header,
+	})
+	if err != nil {
+		logger.Error("Failed to render response template", slog.String("error", err.Error()))
+		return err
+	}
+
+	if contentType == "" {
+		contentType = detectedType
+	}
+	w.Header().Set("Content-Type", contentType)
+	if err := h.writeFinalBody(ctx, w, r, statusCode, body, logger); err != nil {
+		logger.Error("Failed to write templated response", slog.String("error", err.Error()))
+		return err
+	}
+
+	logger.Debug("Templated response sent successfully", slog.String("content_type", contentType))
 	return nil
 }
 
@@ -505,7 +1495,7 @@ func (h *Handler) sendFaultResponse(w http.ResponseWriter, statusCode int, logge
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 
-	if err := json.NewEncoder(w).Encode(response); err != nil {
+	if err := h.responseFields.encode(w, mediaTypeJSON, response); err != nil {
 		logger.Error("Failed to encode JSON fault response", slog.String("error", err.Error()))
 		return err
 	}
@@ -514,10 +1504,40 @@ func (h *Handler) sendFaultResponse(w http.ResponseWriter, statusCode int, logge
 	return nil
 }
 
+// sendBadJSONResponse sends a response that claims to be JSON but is truncated mid-document,
+// for exercising client JSON parsing error handling
+func (h *Handler) sendBadJSONResponse(w http.ResponseWriter, statusCode int, logger *slog.Logger) error {
+	logger.Debug("Sending malformed JSON response", slog.Int("status_code", statusCode), slog.String("service", h.serviceName))
+
+	body, err := h.responseFields.marshalJSON(Response{
+		Status:  statusCode,
+		Service: h.serviceName,
+		Message: "Fault injected: malformed JSON",
+	})
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	// Drop the second half of the document so it is never valid JSON
+	if _, err := w.Write(body[:len(body)/2]); err != nil {
+		logger.Error("Failed to write malformed JSON response", slog.String("error", err.Error()))
+		return err
+	}
+
+	logger.Debug("Malformed JSON response sent successfully")
+	return nil
+}
+
 // forwardResponse forwards the downstream response as-is without modification
 func (h *Handler) forwardResponse(w http.ResponseWriter, resp *http.Response, logger *slog.Logger) error {
 	logger.Debug("Forwarding response", slog.Int("status_code", resp.StatusCode), slog.Int("header_count", len(resp.Header)))
 
+	// Stamp this hop ahead of the hops after it
+	h.stampHop(w)
+
 	// Copy headers from downstream response
 	headerCount := 0
 	if h.propagateResponseHeaders {
@@ -527,9 +1547,14 @@ func (h *Handler) forwardResponse(w http.ResponseWriter, resp *http.Response, lo
 				headerCount++
 			}
 		}
+
+		// Announce the downstream trailers so they can follow the body
+		for k := range resp.Trailer {
+			w.Header().Add("Trailer", k)
+		}
 	}
 
-	w.WriteHeader(resp.StatusCode)
+	w.WriteHeader(h.remapStatus(resp.StatusCode, logger))
 
 	// Copy the response body as-is
 	_, err := io.Copy(w, resp.Body)
@@ -538,6 +1563,16 @@ func (h *Handler) forwardResponse(w http.ResponseWriter, resp *http.Response, lo
 		return err
 	}
 
+	// Trailer values are only known once the body has been read in full. The prefix also sends
+	// trailers the downstream did not announce.
+	if h.propagateResponseHeaders {
+		for k, v := range resp.Trailer {
+			for _, val := range v {
+				w.Header().Add(http.TrailerPrefix+k, val)
+			}
+		}
+	}
+
 	logger.Debug("Response forwarded successfully", slog.Int("headers_copied", headerCount))
 
 	return nil
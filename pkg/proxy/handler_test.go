@@ -5,8 +5,10 @@ import (
 	"crypto/rsa"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/json"
 	"encoding/pem"
 	"fmt"
+	"io"
 	"log/slog"
 	"math/big"
 	"net/http"
@@ -285,6 +287,522 @@ func TestParsePath(t *testing.T) {
 			want:    actions{},
 			wantErr: true,
 		},
+		{
+			name: "bad json fault",
+			path: "/fault/badjson",
+			want: actions{
+				Remaining:       "/",
+				IsFault:         true,
+				FaultCode:       200,
+				FaultPercentage: 100,
+				FaultBadJSON:    true,
+			},
+		},
+		{
+			name: "bad json fault with percentage chained with proxy",
+			path: "/fault/badjson/25/proxy/service-b:8080",
+			want: actions{
+				Remaining:       "/proxy/service-b:8080",
+				IsFault:         true,
+				FaultCode:       200,
+				FaultPercentage: 25,
+				FaultBadJSON:    true,
+			},
+		},
+		{
+			name:    "bad json fault - invalid percentage",
+			path:    "/fault/badjson/150",
+			want:    actions{},
+			wantErr: true,
+		},
+		{
+			name: "fanout to two targets",
+			path: "/fanout/svca:8080,svcb:8080",
+			want: actions{
+				Remaining:     "/",
+				FanoutTargets: []string{"svca:8080", "svcb:8080"},
+			},
+		},
+		{
+			name: "fanout with schemes followed by proxy",
+			path: "/fanout/https:/svca:8443,svcb:8080/proxy/svcc:80",
+			want: actions{
+				Remaining:     "/proxy/svcc:80",
+				FanoutTargets: []string{"https:/svca:8443", "svcb:8080"},
+			},
+		},
+		{
+			name: "proxy hop ends at fanout",
+			path: "/proxy/svca:8080/fanout/svcb:8080,svcc:8080",
+			want: actions{
+				NextHop:   "svca:8080",
+				Remaining: "/fanout/svcb:8080,svcc:8080",
+				Scheme:    "http",
+			},
+		},
+		{
+			name:    "fanout with empty target",
+			path:    "/fanout/svca:8080,",
+			want:    actions{},
+			wantErr: true,
+		},
+		{
+			name: "delay followed by proxy",
+			path: "/delay/250/proxy/svca:8080",
+			want: actions{
+				Remaining: "/proxy/svca:8080",
+				IsDelay:   true,
+				Delay:     250 * time.Millisecond,
+			},
+		},
+		{
+			name: "delay at end of path",
+			path: "/delay/0",
+			want: actions{
+				Remaining: "/",
+				IsDelay:   true,
+			},
+		},
+		{
+			name: "proxy hop ends at delay",
+			path: "/proxy/svca:8080/delay/100",
+			want: actions{
+				NextHop:   "svca:8080",
+				Remaining: "/delay/100",
+				Scheme:    "http",
+			},
+		},
+		{
+			name: "slowstart followed by proxy",
+			path: "/slowstart/60/500/proxy/svca:8080",
+			want: actions{
+				Remaining: "/proxy/svca:8080",
+				IsDelay:   true,
+				Delay:     500 * time.Millisecond,
+				SlowStart: time.Minute,
+			},
+		},
+		{
+			name:    "slowstart missing max delay",
+			path:    "/slowstart/60",
+			want:    actions{},
+			wantErr: true,
+		},
+		{
+			name:    "slowstart with zero duration",
+			path:    "/slowstart/0/500",
+			want:    actions{},
+			wantErr: true,
+		},
+		{
+			name: "fault with header match",
+			path: "/fault/503/match/x-canary=true/proxy/svca:8080",
+			want: actions{
+				Remaining:        "/proxy/svca:8080",
+				IsFault:          true,
+				FaultCode:        503,
+				FaultPercentage:  100,
+				FaultMatchHeader: "X-Canary",
+				FaultMatchValue:  "true",
+			},
+		},
+		{
+			name: "fault with percentage and header match",
+			path: "/fault/500/50/match/x-user=alice",
+			want: actions{
+				Remaining:        "/",
+				IsFault:          true,
+				FaultCode:        500,
+				FaultPercentage:  50,
+				FaultMatchHeader: "X-User",
+				FaultMatchValue:  "alice",
+			},
+		},
+		{
+			name:    "fault match without value",
+			path:    "/fault/503/match/x-canary",
+			want:    actions{},
+			wantErr: true,
+		},
+		{
+			name:    "fault match missing spec",
+			path:    "/fault/503/match",
+			want:    actions{},
+			wantErr: true,
+		},
+		{
+			name: "bytes",
+			path: "/bytes/1024",
+			want: actions{
+				Remaining: "/",
+				IsBytes:   true,
+				Bytes:     1024,
+			},
+		},
+		{
+			name: "proxy hop ends at bytes",
+			path: "/proxy/svca:8080/bytes/10",
+			want: actions{
+				NextHop:   "svca:8080",
+				Remaining: "/bytes/10",
+				Scheme:    "http",
+			},
+		},
+		{
+			name:    "bytes followed by another directive",
+			path:    "/bytes/10/proxy/svca:8080",
+			want:    actions{},
+			wantErr: true,
+		},
+		{
+			name:    "bytes with invalid size",
+			path:    "/bytes/lots",
+			want:    actions{},
+			wantErr: true,
+		},
+		{
+			name: "try with remaining path",
+			path: "/try/svca:8080,https:/svcb:8443/fault/503",
+			want: actions{
+				Remaining:  "/fault/503",
+				TryTargets: []string{"svca:8080", "https:/svcb:8443"},
+			},
+		},
+		{
+			name:    "try with empty target",
+			path:    "/try/,svcb:8080",
+			want:    actions{},
+			wantErr: true,
+		},
+		{
+			name: "ctype before last hop",
+			path: "/ctype/text/html",
+			want: actions{
+				Remaining:   "/",
+				ContentType: "text/html",
+			},
+		},
+		{
+			name: "ctype followed by proxy",
+			path: "/ctype/application/vnd.api+json/proxy/svca:8080",
+			want: actions{
+				Remaining:   "/proxy/svca:8080",
+				ContentType: "application/vnd.api+json",
+			},
+		},
+		{
+			name:    "ctype missing subtype",
+			path:    "/ctype/text",
+			want:    actions{},
+			wantErr: true,
+		},
+		{
+			name: "reset",
+			path: "/reset/100",
+			want: actions{
+				Remaining:  "/",
+				IsReset:    true,
+				ResetBytes: 100,
+			},
+		},
+		{
+			name:    "reset followed by another directive",
+			path:    "/reset/100/proxy/svca:8080",
+			want:    actions{},
+			wantErr: true,
+		},
+		{
+			name: "multipart",
+			path: "/multipart/3",
+			want: actions{
+				Remaining:      "/",
+				MultipartParts: 3,
+			},
+		},
+		{
+			name:    "multipart with zero parts",
+			path:    "/multipart/0",
+			want:    actions{},
+			wantErr: true,
+		},
+		{
+			name:    "multipart followed by another directive",
+			path:    "/multipart/3/proxy/svca:8080",
+			want:    actions{},
+			wantErr: true,
+		},
+		{
+			name: "trailer followed by proxy",
+			path: "/trailer/x-checksum/abc123/proxy/svca:8080",
+			want: actions{
+				Remaining:    "/proxy/svca:8080",
+				TrailerName:  "X-Checksum",
+				TrailerValue: "abc123",
+			},
+		},
+		{
+			name:    "trailer missing value",
+			path:    "/trailer/x-checksum",
+			want:    actions{},
+			wantErr: true,
+		},
+		{
+			name:    "trailer with forbidden name",
+			path:    "/trailer/content-length/10",
+			want:    actions{},
+			wantErr: true,
+		},
+		{
+			name: "setcookie followed by proxy",
+			path: "/setcookie/session/abc123/proxy/svca:8080",
+			want: actions{
+				Remaining: "/proxy/svca:8080",
+				Cookie:    &http.Cookie{Name: "session", Value: "abc123"},
+			},
+		},
+		{
+			name:    "setcookie missing value",
+			path:    "/setcookie/session",
+			want:    actions{},
+			wantErr: true,
+		},
+		{
+			name:    "setcookie with invalid value",
+			path:    "/setcookie/session/a;b",
+			want:    actions{},
+			wantErr: true,
+		},
+		{
+			name: "chunked",
+			path: "/chunked",
+			want: actions{
+				Remaining: "/",
+				IsChunked: true,
+			},
+		},
+		{
+			name: "proxy hop ends at chunked",
+			path: "/proxy/svca:8080/chunked",
+			want: actions{
+				NextHop:   "svca:8080",
+				Remaining: "/chunked",
+				Scheme:    "http",
+			},
+		},
+		{
+			name:    "chunked must be last",
+			path:    "/chunked/proxy/svca:8080",
+			want:    actions{},
+			wantErr: true,
+		},
+		{
+			name: "slowbody",
+			path: "/slowbody/100",
+			want: actions{
+				Remaining:        "/",
+				IsSlowBody:       true,
+				SlowBodyInterval: 100 * time.Millisecond,
+			},
+		},
+		{
+			name:    "slowbody with negative interval",
+			path:    "/slowbody/-1",
+			want:    actions{},
+			wantErr: true,
+		},
+		{
+			name:    "slowbody must be last",
+			path:    "/slowbody/10/proxy/svca:8080",
+			want:    actions{},
+			wantErr: true,
+		},
+		{
+			name: "grpc-status",
+			path: "/grpc-status/14",
+			want: actions{
+				Remaining:    "/",
+				IsGRPCStatus: true,
+				GRPCStatus:   14,
+			},
+		},
+		{
+			name:    "grpc-status out of range",
+			path:    "/grpc-status/17",
+			want:    actions{},
+			wantErr: true,
+		},
+		{
+			name:    "grpc-status must be last",
+			path:    "/grpc-status/0/proxy/svca:8080",
+			want:    actions{},
+			wantErr: true,
+		},
+		{
+			name: "latency profile followed by proxy",
+			path: "/latency/20,80,200/proxy/svca:8080",
+			want: actions{
+				Remaining:      "/proxy/svca:8080",
+				IsDelay:        true,
+				LatencyProfile: []time.Duration{20 * time.Millisecond, 80 * time.Millisecond, 200 * time.Millisecond},
+			},
+		},
+		{
+			name: "proxy hop ends at latency profile",
+			path: "/proxy/svca:8080/latency/20,80,200",
+			want: actions{
+				NextHop:   "svca:8080",
+				Remaining: "/latency/20,80,200",
+				Scheme:    "http",
+			},
+		},
+		{
+			name:    "latency profile with decreasing percentiles",
+			path:    "/latency/80,20,200",
+			want:    actions{},
+			wantErr: true,
+		},
+		{
+			name: "seq followed by proxy",
+			path: "/seq/503,503,200/proxy/svca:8080",
+			want: actions{
+				Remaining:   "/proxy/svca:8080",
+				SeqStatuses: []int{503, 503, 200},
+			},
+		},
+		{
+			name: "proxy hop ends at seq",
+			path: "/proxy/svca:8080/seq/500,200",
+			want: actions{
+				NextHop:   "svca:8080",
+				Remaining: "/seq/500,200",
+				Scheme:    "http",
+			},
+		},
+		{
+			name:    "seq with invalid status",
+			path:    "/seq/503,abc",
+			want:    actions{},
+			wantErr: true,
+		},
+		{
+			name:    "seq with status out of range",
+			path:    "/seq/503,700",
+			want:    actions{},
+			wantErr: true,
+		},
+		{
+			name:    "seq with bodiless status",
+			path:    "/seq/204",
+			want:    actions{},
+			wantErr: true,
+		},
+		{
+			name: "bare flaky",
+			path: "/flaky",
+			want: actions{
+				Remaining:  "/",
+				FlakyEvery: 2,
+			},
+		},
+		{
+			name: "flaky followed by proxy",
+			path: "/flaky/proxy/svca:8080",
+			want: actions{
+				Remaining:  "/proxy/svca:8080",
+				FlakyEvery: 2,
+			},
+		},
+		{
+			name: "flaky with period",
+			path: "/flaky/3/proxy/svca:8080",
+			want: actions{
+				Remaining:  "/proxy/svca:8080",
+				FlakyEvery: 3,
+			},
+		},
+		{
+			name:    "flaky with zero period",
+			path:    "/flaky/0",
+			want:    actions{},
+			wantErr: true,
+		},
+		{
+			name:    "flaky with malformed period",
+			path:    "/flaky/3x",
+			want:    actions{},
+			wantErr: true,
+		},
+		{
+			name: "repeat",
+			path: "/repeat/5/proxy/svca:8080",
+			want: actions{
+				Remaining:   "/proxy/svca:8080",
+				RepeatCount: 5,
+			},
+		},
+		{
+			name:    "repeat with zero count",
+			path:    "/repeat/0/proxy/svca:8080",
+			want:    actions{},
+			wantErr: true,
+		},
+		{
+			name:    "repeat with malformed count",
+			path:    "/repeat/many/proxy/svca:8080",
+			want:    actions{},
+			wantErr: true,
+		},
+		{
+			name: "proxys shortcut",
+			path: "/proxys/svca:8443",
+			want: actions{
+				NextHop:   "svca:8443",
+				Remaining: "/",
+				Scheme:    "https",
+			},
+		},
+		{
+			name: "proxys shortcut followed by plain proxy",
+			path: "/proxys/svca:8443/proxy/svcb:8080",
+			want: actions{
+				NextHop:   "svca:8443",
+				Remaining: "/proxy/svcb:8080",
+				Scheme:    "https",
+			},
+		},
+		{
+			name: "proxy followed by proxys shortcut",
+			path: "/proxy/svca:8080/proxys/svcb:8443",
+			want: actions{
+				NextHop:   "svca:8080",
+				Remaining: "/proxys/svcb:8443",
+				Scheme:    "http",
+			},
+		},
+		{
+			name:    "proxys shortcut with inline scheme",
+			path:    "/proxys/http:/svca:8080",
+			want:    actions{},
+			wantErr: true,
+		},
+		{
+			name:    "proxys shortcut with empty target",
+			path:    "/proxys/",
+			want:    actions{},
+			wantErr: true,
+		},
+		{
+			name:    "delay with invalid duration",
+			path:    "/delay/soon",
+			want:    actions{},
+			wantErr: true,
+		},
+		{
+			name:    "delay with negative duration",
+			path:    "/delay/-5",
+			want:    actions{},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -373,6 +891,64 @@ func TestSendFaultResponse(t *testing.T) {
 	}
 }
 
+func TestSendBadJSONResponse(t *testing.T) {
+	logger := createTestLogger()
+	handler, err := NewHandler(30*time.Second, "test-service", logger)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/fault/badjson", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	assert.NotEmpty(t, rr.Body.String())
+
+	var resp Response
+	assert.Error(t, json.Unmarshal(rr.Body.Bytes(), &resp), "body should not be valid JSON")
+}
+
+func TestTrailingDirectivesAppliedDownstream(t *testing.T) {
+	logger := createTestLogger()
+
+	downstream, err := NewHandler(30*time.Second, "downstream", logger)
+	require.NoError(t, err)
+	downstreamServer := httptest.NewServer(downstream)
+	defer downstreamServer.Close()
+	downstreamAddr := strings.TrimPrefix(downstreamServer.URL, "http://")
+
+	upstream, err := NewHandler(30*time.Second, "upstream", logger)
+	require.NoError(t, err)
+
+	t.Run("fault after proxy hop is applied by the downstream service", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/proxy/"+downstreamAddr+"/fault/503", nil)
+		rr := httptest.NewRecorder()
+		upstream.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"service":"downstream"`)
+		assert.Contains(t, rr.Body.String(), "Fault injected")
+	})
+
+	t.Run("untriggered fault after proxy hop returns downstream success", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/proxy/"+downstreamAddr+"/fault/500/0", nil)
+		rr := httptest.NewRecorder()
+		upstream.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"service":"downstream"`)
+	})
+
+	t.Run("consecutive local faults are each applied", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/fault/500/0/fault/503", nil)
+		rr := httptest.NewRecorder()
+		upstream.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+		assert.Contains(t, rr.Body.String(), `"service":"upstream"`)
+	})
+}
+
 // responseRecorder is a simple HTTP response writer for testing
 type responseRecorder struct {
 	statusCode int
@@ -620,6 +1196,45 @@ func TestTLSInsecureOption(t *testing.T) {
 	})
 }
 
+func TestProxysShortcut(t *testing.T) {
+	var got string
+	upstream := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.RequestURI()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+	hop := strings.TrimPrefix(upstream.URL, "https://")
+
+	t.Run("forwards over https", func(t *testing.T) {
+		h, err := NewHandler(5*time.Second, "test-service", createTestLogger(), WithTLSInsecure(true))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/proxys/"+hop, nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "/", got)
+	})
+
+	t.Run("verifies certificates unless told not to", func(t *testing.T) {
+		h, err := NewHandler(5*time.Second, "test-service", createTestLogger())
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/proxys/"+hop, nil))
+		assert.Equal(t, http.StatusBadGateway, rr.Code)
+	})
+
+	t.Run("forwards the full path", func(t *testing.T) {
+		h, err := NewHandler(5*time.Second, "test-service", createTestLogger(), WithTLSInsecure(true), WithForwardFullPath(true))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/proxys/"+hop+"/files/a%2Fb?page=2", nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "/files/a%2Fb?page=2", got)
+	})
+}
+
 func TestDefaultTLSInsecure(t *testing.T) {
 	logger := createTestLogger()
 
@@ -723,6 +1338,50 @@ func TestRequestHeaderPropagation(t *testing.T) {
 	}
 }
 
+func TestUpstreamUserAgent(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		userAgent string
+	)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		userAgent = r.UserAgent()
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	upstreamAddr := strings.TrimPrefix(upstream.URL, "http://")
+
+	tests := []struct {
+		name      string
+		userAgent string
+		want      string
+	}{
+		{name: "unset propagates the client user agent", want: "client/1.0"},
+		{name: "custom user agent replaces the client's", userAgent: "loadtest/2.0", want: "loadtest/2.0"},
+		{name: "service placeholder is expanded", userAgent: "loadtest/2.0 ({service})", want: "loadtest/2.0 (test-service)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, err := NewHandler(30*time.Second, "test-service", createTestLogger(), WithUpstreamUserAgent(tt.userAgent))
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "/proxy/"+upstreamAddr, nil)
+			req.Header.Set("User-Agent", "client/1.0")
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, tt.want, userAgent)
+		})
+	}
+}
+
 func TestResponseHeaderPropagation(t *testing.T) {
 	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("X-Upstream-Header", "upstream-value")
@@ -859,3 +1518,181 @@ func TestWithCACertFiles(t *testing.T) {
 		assert.NotNil(t, transport.TLSClientConfig.RootCAs)
 	})
 }
+
+func TestMethodForwarding(t *testing.T) {
+	type echoed struct {
+		Method           string   `json:"method"`
+		Body             string   `json:"body"`
+		ContentLength    int64    `json:"content_length"`
+		TransferEncoding []string `json:"transfer_encoding"`
+	}
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(echoed{
+			Method:           r.Method,
+			Body:             string(body),
+			ContentLength:    r.ContentLength,
+			TransferEncoding: r.TransferEncoding,
+		})
+	}))
+	defer upstream.Close()
+
+	logger := createTestLogger()
+
+	// A second hop so bodies are forwarded across two proxies
+	hop, err := NewHandler(30*time.Second, "hop", logger)
+	require.NoError(t, err)
+	hopServer := httptest.NewServer(hop)
+	defer hopServer.Close()
+
+	handler, err := NewHandler(30*time.Second, "test-service", logger)
+	require.NoError(t, err)
+
+	path := "/proxy/" + strings.TrimPrefix(hopServer.URL, "http://") + "/proxy/" + strings.TrimPrefix(upstream.URL, "http://")
+
+	tests := []struct {
+		method string
+		body   string
+	}{
+		{method: http.MethodPatch, body: `{"op":"replace","path":"/name","value":"new"}`},
+		{method: http.MethodPut, body: `{"name":"new"}`},
+		{method: http.MethodPost, body: "plain body"},
+		{method: http.MethodDelete},
+		{method: http.MethodOptions},
+		{method: http.MethodTrace},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, path, strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+			var got echoed
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+			assert.Equal(t, tt.method, got.Method)
+			assert.Equal(t, tt.body, got.Body)
+			assert.Equal(t, int64(len(tt.body)), got.ContentLength)
+			assert.Empty(t, got.TransferEncoding, "body should not be re-chunked")
+		})
+	}
+
+	t.Run("CONNECT is rejected", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodConnect, path, nil))
+
+		assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+		var resp ErrorResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.Equal(t, ErrCodeMethodNotAllowed, resp.Code)
+		assert.Equal(t, "GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS, TRACE", rr.Header().Get("Allow"))
+	})
+
+	t.Run("CONNECT rejection lists the allowed methods", func(t *testing.T) {
+		restricted, err := NewHandler(30*time.Second, "test-service", createTestLogger(), WithAllowedMethods([]string{"GET", "CONNECT", "POST"}))
+		require.NoError(t, err)
+
+		rr := httptest.NewRecorder()
+		restricted.ServeHTTP(rr, httptest.NewRequest(http.MethodConnect, path, nil))
+
+		assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+		assert.Equal(t, "GET, POST", rr.Header().Get("Allow"))
+	})
+}
+
+func TestFaultHeaderMatch(t *testing.T) {
+	handler, err := NewHandler(30*time.Second, "test-service", createTestLogger())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		wantStatus int
+	}{
+		{name: "matching request gets the fault", headers: map[string]string{"X-Canary": "true"}, wantStatus: http.StatusServiceUnavailable},
+		{name: "header name is case-insensitive", headers: map[string]string{"x-canary": "true"}, wantStatus: http.StatusServiceUnavailable},
+		{name: "different value is not faulted", headers: map[string]string{"X-Canary": "false"}, wantStatus: http.StatusOK},
+		{name: "missing header is not faulted", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/fault/503/match/x-canary=true", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			assert.Equal(t, tt.wantStatus, rr.Code)
+		})
+	}
+}
+
+func TestResponseMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []HandlerOption
+		want    string
+		present bool
+	}{
+		{name: "default message", want: DefaultResponseMessage, present: true},
+		{name: "custom message", opts: []HandlerOption{WithResponseMessage("hello from the stub")}, want: "hello from the stub", present: true},
+		{name: "service placeholder is expanded", opts: []HandlerOption{WithResponseMessage("served by {service}")}, want: "served by test-service", present: true},
+		{name: "empty message is omitted", opts: []HandlerOption{WithResponseMessage("")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, err := NewHandler(30*time.Second, "test-service", createTestLogger(), tt.opts...)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var body map[string]any
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			message, ok := body["message"]
+			assert.Equal(t, tt.present, ok)
+			if tt.present {
+				assert.Equal(t, tt.want, message)
+			}
+		})
+	}
+}
+
+func TestFollowRedirects(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			http.Redirect(w, r, "/new", http.StatusFound)
+			return
+		}
+		_, _ = w.Write([]byte("landed on " + r.URL.Path))
+	}))
+	defer upstream.Close()
+	upstreamAddr := strings.TrimPrefix(upstream.URL, "http://")
+
+	get := func(t *testing.T, opts ...HandlerOption) *httptest.ResponseRecorder {
+		t.Helper()
+		handler, err := NewHandler(30*time.Second, "test-service", createTestLogger(), opts...)
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/proxy/"+upstreamAddr, nil))
+		return rr
+	}
+
+	t.Run("follows redirects by default", func(t *testing.T) {
+		rr := get(t)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "landed on /new", rr.Body.String())
+	})
+
+	t.Run("returns the redirect when disabled", func(t *testing.T) {
+		rr := get(t, WithFollowRedirects(false))
+		assert.Equal(t, http.StatusFound, rr.Code)
+		assert.Equal(t, "/new", rr.Header().Get("Location"))
+	})
+}
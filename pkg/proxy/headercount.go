@@ -0,0 +1,42 @@
+package proxy
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// WithMaxHeaderCount rejects requests carrying more than n header fields with 431 Request Header
+// Fields Too Large. Each value of a repeated header counts as a field, as it would be a separate
+// line on the wire. A limit of 0 allows any number.
+func WithMaxHeaderCount(n int) HandlerOption {
+	return func(h *Handler) {
+		h.maxHeaderCount = n
+	}
+}
+
+// headerFieldCount counts the header fields in header, one per value
+func headerFieldCount(header http.Header) int {
+	n := 0
+	for _, values := range header {
+		n += len(values)
+	}
+	return n
+}
+
+// allowHeaderCount reports whether the request is within the header field limit, answering 431
+// if not
+func (h *Handler) allowHeaderCount(w http.ResponseWriter, r *http.Request) bool {
+	if h.maxHeaderCount <= 0 {
+		return true
+	}
+	count := headerFieldCount(r.Header)
+	if count <= h.maxHeaderCount {
+		return true
+	}
+
+	logger := h.logger.With(slog.String("method", r.Method), slog.String("path", r.URL.Path))
+	logger.Info("Rejected request with too many headers", slog.Int("header_count", count), slog.Int("max_header_count", h.maxHeaderCount))
+	h.writeError(w, r, http.StatusRequestHeaderFieldsTooLarge, ErrCodeHeadersTooLarge, fmt.Sprintf("Request has %d header fields, more than the maximum of %d", count, h.maxHeaderCount), logger)
+	return false
+}
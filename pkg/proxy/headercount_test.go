@@ -0,0 +1,93 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeaderFieldCount(t *testing.T) {
+	header := http.Header{}
+	assert.Equal(t, 0, headerFieldCount(header))
+
+	header.Set("Accept", "application/json")
+	header.Add("X-Forwarded-For", "10.0.0.1")
+	header.Add("X-Forwarded-For", "10.0.0.2")
+	assert.Equal(t, 3, headerFieldCount(header), "repeated headers count once per value")
+}
+
+func TestMaxHeaderCount(t *testing.T) {
+	logger := createTestLogger()
+
+	// withHeaders returns a request carrying n distinct header fields
+	withHeaders := func(n int) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		for i := range n {
+			req.Header.Set(fmt.Sprintf("X-Header-%d", i), "value")
+		}
+		return req
+	}
+
+	t.Run("any number allowed by default", func(t *testing.T) {
+		h, err := NewHandler(5*time.Second, "test-service", logger)
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, withHeaders(500))
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	h, err := NewHandler(5*time.Second, "test-service", logger, WithMaxHeaderCount(20))
+	require.NoError(t, err)
+
+	t.Run("at the limit", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, withHeaders(20))
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("over the limit", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, withHeaders(200))
+		assert.Equal(t, http.StatusRequestHeaderFieldsTooLarge, rr.Code)
+		assert.Equal(t, ErrCodeHeadersTooLarge, decodeErrorResponse(t, rr).Code)
+	})
+
+	t.Run("repeated values count", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		for range 21 {
+			req.Header.Add("X-Repeated", "value")
+		}
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusRequestHeaderFieldsTooLarge, rr.Code)
+	})
+	t.Run("composed requests are limited", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/compose", strings.NewReader(`{"hops":[{"target":"svc:8080"}]}`))
+		for i := range 200 {
+			req.Header.Set(fmt.Sprintf("X-Header-%d", i), "value")
+		}
+		rr := httptest.NewRecorder()
+		h.ServeCompose(rr, req)
+		assert.Equal(t, http.StatusRequestHeaderFieldsTooLarge, rr.Code)
+	})
+
+	t.Run("replayed requests are limited", func(t *testing.T) {
+		rec := Record{Method: http.MethodGet, Path: "/proxy/svc:8080", RequestHeaders: map[string][]string{}}
+		for i := range 200 {
+			rec.RequestHeaders[fmt.Sprintf("X-Header-%d", i)] = []string{"value"}
+		}
+		body, err := json.Marshal(rec)
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		h.ServeReplay(rr, httptest.NewRequest(http.MethodPost, "/replay", bytes.NewReader(body)))
+		assert.Equal(t, http.StatusRequestHeaderFieldsTooLarge, rr.Code)
+	})
+}
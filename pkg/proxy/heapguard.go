@@ -0,0 +1,69 @@
+package proxy
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// heapCheckInterval is how long a heap usage reading is reused before it is taken again, so
+// runtime.ReadMemStats, which briefly stops the world, runs at most once per interval
+const heapCheckInterval = time.Second
+
+// WithMaxHeapMB sheds load while the heap is over mb mebibytes: requests get 503 until garbage
+// collection brings usage back under the limit. Usage is sampled at most once a second. A limit
+// of 0 disables the check.
+func WithMaxHeapMB(mb int) HandlerOption {
+	return func(h *Handler) {
+		h.heapGuard = nil
+		if mb > 0 {
+			h.heapGuard = &heapGuard{limit: uint64(mb) << 20, interval: heapCheckInterval, read: readHeapAlloc}
+		}
+	}
+}
+
+// readHeapAlloc returns the bytes of allocated heap objects
+func readHeapAlloc() uint64 {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapAlloc
+}
+
+// heapGuard caches heap usage readings for the load shedding check
+type heapGuard struct {
+	limit    uint64
+	interval time.Duration
+	read     func() uint64
+
+	mu     sync.Mutex
+	readAt time.Time
+	heap   uint64
+}
+
+// usage returns the heap in use, taking a new reading once the last is interval old
+func (g *heapGuard) usage(now time.Time) uint64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.readAt.IsZero() || now.Sub(g.readAt) >= g.interval {
+		g.heap = g.read()
+		g.readAt = now
+	}
+	return g.heap
+}
+
+// shedForHeap answers 503 and returns true when heap usage is over the limit
+func (h *Handler) shedForHeap(w http.ResponseWriter, r *http.Request) bool {
+	heap := h.heapGuard.usage(time.Now())
+	if heap <= h.heapGuard.limit {
+		return false
+	}
+
+	logger := h.logger.With(slog.String("method", r.Method), slog.String("path", r.URL.Path))
+	logger.Warn("Shedding request, heap usage over limit", slog.Uint64("heap_mb", heap>>20), slog.Uint64("max_heap_mb", h.heapGuard.limit>>20))
+	w.Header().Set("Retry-After", "1")
+	h.writeError(w, r, http.StatusServiceUnavailable, ErrCodeOverloaded, fmt.Sprintf("Heap usage of %d MiB is over the limit of %d MiB", heap>>20, h.heapGuard.limit>>20), logger)
+	return true
+}
@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeapGuardCachesReadings(t *testing.T) {
+	reads := 0
+	g := &heapGuard{limit: 100, interval: time.Second, read: func() uint64 {
+		reads++
+		return uint64(reads)
+	}}
+
+	start := time.Now()
+	assert.Equal(t, uint64(1), g.usage(start))
+	assert.Equal(t, uint64(1), g.usage(start.Add(500*time.Millisecond)))
+	assert.Equal(t, uint64(2), g.usage(start.Add(time.Second)))
+	assert.Equal(t, 2, reads)
+}
+
+func TestMaxHeapMB(t *testing.T) {
+	runtime.GC()
+	baselineMB := int(readHeapAlloc() >> 20)
+
+	h, err := NewHandler(5*time.Second, "test-service", createTestLogger(), WithMaxHeapMB(baselineMB+64))
+	require.NoError(t, err)
+	// Read the heap on every request so the test needn't wait out the cache
+	h.heapGuard.interval = 0
+
+	get := func() *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rr
+	}
+
+	require.Equal(t, http.StatusOK, get().Code)
+
+	// Hold well over the headroom so the heap is over the limit
+	ballast := make([]byte, 128<<20)
+	for i := range ballast {
+		ballast[i] = 1
+	}
+	rr := get()
+	runtime.KeepAlive(ballast)
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+	assert.Equal(t, ErrCodeOverloaded, decodeErrorResponse(t, rr).Code)
+
+	// Requests are served again once the memory is collected; ballast is dead after KeepAlive
+	runtime.GC()
+	assert.Equal(t, http.StatusOK, get().Code)
+
+	t.Run("disabled by default", func(t *testing.T) {
+		h, err := NewHandler(5*time.Second, "test-service", createTestLogger())
+		require.NoError(t, err)
+		assert.Nil(t, h.heapGuard)
+	})
+}
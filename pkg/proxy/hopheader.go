@@ -0,0 +1,28 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// hopHeaderPrefix starts the header each hop stamps on its response, e.g. X-Hop-service-b
+const hopHeaderPrefix = "X-Hop-"
+
+// hopHeader is the header this service stamps, with characters that are not valid in a header
+// name replaced by '-'
+func (h *Handler) hopHeader() string {
+	name := strings.Map(func(r rune) rune {
+		if r < 0x80 && (r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("!#$%&'*+-.^_`|~", r)) {
+			return r
+		}
+		return '-'
+	}, h.serviceName)
+	return hopHeaderPrefix + name
+}
+
+// stampHop adds this service's hop header, holding the time it answered, to the response. As
+// each hop forwards the headers of the one after it, the final response carries one per hop.
+func (h *Handler) stampHop(w http.ResponseWriter) {
+	w.Header().Add(h.hopHeader(), time.Now().UTC().Format(time.RFC3339Nano))
+}
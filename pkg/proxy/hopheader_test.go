@@ -0,0 +1,63 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHopHeaders(t *testing.T) {
+	logger := createTestLogger()
+
+	serviceC, err := NewHandler(5*time.Second, "service-c", logger)
+	require.NoError(t, err)
+	upstreamC := httptest.NewServer(serviceC)
+	defer upstreamC.Close()
+
+	serviceB, err := NewHandler(5*time.Second, "service-b", logger)
+	require.NoError(t, err)
+	upstreamB := httptest.NewServer(serviceB)
+	defer upstreamB.Close()
+
+	serviceA, err := NewHandler(5*time.Second, "service-a", logger)
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	path := "/proxy/" + strings.TrimPrefix(upstreamB.URL, "http://") + "/proxy/" + strings.TrimPrefix(upstreamC.URL, "http://")
+	serviceA.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var stamps []time.Time
+	for _, service := range []string{"service-a", "service-b", "service-c"} {
+		value := rr.Header().Get("X-Hop-" + service)
+		require.NotEmpty(t, value, service)
+		stamp, err := time.Parse(time.RFC3339Nano, value)
+		require.NoError(t, err, service)
+		stamps = append(stamps, stamp)
+	}
+	// Each hop answers after the hop it forwarded to
+	assert.False(t, stamps[0].Before(stamps[1]))
+	assert.False(t, stamps[1].Before(stamps[2]))
+}
+
+func TestHopHeaderName(t *testing.T) {
+	tests := []struct {
+		service string
+		want    string
+	}{
+		{service: "service-b", want: "X-Hop-service-b"},
+		{service: "orders api", want: "X-Hop-orders-api"},
+		{service: "café:8080", want: "X-Hop-caf--8080"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.service, func(t *testing.T) {
+			h := &Handler{serviceName: tt.service}
+			assert.Equal(t, tt.want, h.hopHeader())
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// WithHostAliases resolves the given hostnames to fixed IPs when dialing upstream hops, like
+// /etc/hosts entries, so chains can name services without real DNS
+func WithHostAliases(aliases map[string]string) HandlerOption {
+	return func(h *Handler) {
+		h.hostAliases = aliases
+	}
+}
+
+// ParseHostAliases parses "name=ip" pairs such as "myservice=127.0.0.1" into a hostname mapping.
+// Hostnames are matched case-insensitively.
+func ParseHostAliases(pairs []string) (map[string]string, error) {
+	aliases := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		name, ip, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid host alias %q: must be <name>=<ip>", pair)
+		}
+		addr := net.ParseIP(strings.Trim(ip, "[]"))
+		if addr == nil {
+			return nil, fmt.Errorf("invalid host alias %q: %q is not an IP address", pair, ip)
+		}
+		name = strings.ToLower(name)
+		if _, dup := aliases[name]; dup {
+			return nil, fmt.Errorf("invalid host alias %q: %s is aliased more than once", pair, name)
+		}
+		aliases[name] = addr.String()
+	}
+	return aliases, nil
+}
+
+// aliasDialContext returns a dial function that swaps aliased hostnames for their IPs before
+// passing the address on to dial, leaving every other address to normal resolution
+func aliasDialContext(aliases map[string]string, dial dialFunc) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if host, port, err := net.SplitHostPort(addr); err == nil {
+			if ip, ok := aliases[strings.ToLower(host)]; ok {
+				addr = net.JoinHostPort(ip, port)
+			}
+		}
+		return dial(ctx, network, addr)
+	}
+}
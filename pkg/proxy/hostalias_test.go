@@ -0,0 +1,69 @@
+package proxy
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHostAliases(t *testing.T) {
+	tests := []struct {
+		name    string
+		pairs   []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "empty", pairs: nil, want: map[string]string{}},
+		{name: "ipv4", pairs: []string{"myservice=10.0.0.5"}, want: map[string]string{"myservice": "10.0.0.5"}},
+		{name: "ipv6 and case folding", pairs: []string{"MyService=[::1]", "other=127.0.0.1"}, want: map[string]string{"myservice": "::1", "other": "127.0.0.1"}},
+		{name: "missing separator", pairs: []string{"myservice"}, wantErr: true},
+		{name: "empty name", pairs: []string{"=10.0.0.5"}, wantErr: true},
+		{name: "hostname target", pairs: []string{"myservice=example.com"}, wantErr: true},
+		{name: "duplicate", pairs: []string{"a=10.0.0.1", "A=10.0.0.2"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseHostAliases(tt.pairs)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestHostAliases(t *testing.T) {
+	var host string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host = r.Host
+	}))
+	defer upstream.Close()
+	_, port, err := net.SplitHostPort(upstream.Listener.Addr().String())
+	require.NoError(t, err)
+
+	aliases, err := ParseHostAliases([]string{"myservice.test=127.0.0.1"})
+	require.NoError(t, err)
+	handler, err := NewHandler(5*time.Second, "test-service", createTestLogger(), WithHostAliases(aliases))
+	require.NoError(t, err)
+
+	t.Run("aliased hostname reaches the stub", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/proxy/myservice.test:"+port, nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "myservice.test:"+port, host, "the Host header keeps the alias")
+	})
+
+	t.Run("other hostnames resolve normally", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/proxy/unaliased.invalid:"+port, nil))
+		assert.Equal(t, http.StatusBadGateway, rr.Code)
+	})
+}
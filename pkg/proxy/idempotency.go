@@ -0,0 +1,196 @@
+package proxy
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// idempotencyKeyHeader carries the client-chosen key identifying retries of the same request
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// idempotentReplayHeader marks responses served from the idempotency cache
+const idempotentReplayHeader = "X-Idempotent-Replay"
+
+// cachedResponse is a response recorded for an idempotency key, along with the method and path
+// of the request that produced it
+type cachedResponse struct {
+	method     string
+	path       string
+	statusCode int
+	header     http.Header
+	body       []byte
+	expires    time.Time
+}
+
+// idempotencyCache holds responses by idempotency key for a fixed TTL. Keys with a request in
+// flight are tracked too, so a retry arriving before the first attempt finishes waits for its
+// response instead of calling the upstream again.
+type idempotencyCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu       sync.Mutex
+	entries  map[string]cachedResponse
+	inflight map[string]chan struct{}
+}
+
+// newIdempotencyCache creates a cache whose entries expire after ttl
+func newIdempotencyCache(ttl time.Duration) *idempotencyCache {
+	return &idempotencyCache{
+		ttl:      ttl,
+		now:      time.Now,
+		entries:  make(map[string]cachedResponse),
+		inflight: make(map[string]chan struct{}),
+	}
+}
+
+// get returns the unexpired response recorded for key
+func (c *idempotencyCache) get(key string) (cachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lookup(key)
+}
+
+// lookup is get for callers holding mu
+func (c *idempotencyCache) lookup(key string) (cachedResponse, bool) {
+	entry, ok := c.entries[key]
+	if !ok {
+		return cachedResponse{}, false
+	}
+	if c.now().After(entry.expires) {
+		delete(c.entries, key)
+		return cachedResponse{}, false
+	}
+	return entry, true
+}
+
+// begin returns the unexpired response recorded for key if there is one. Otherwise, if another
+// request with the key is in flight it returns a channel closed once that request finishes, and
+// if not it marks the caller's request as in flight, to be ended with finish.
+func (c *idempotencyCache) begin(key string) (cachedResponse, bool, <-chan struct{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.lookup(key); ok {
+		return entry, true, nil
+	}
+	if done, ok := c.inflight[key]; ok {
+		return cachedResponse{}, false, done
+	}
+	c.inflight[key] = make(chan struct{})
+	return cachedResponse{}, false, nil
+}
+
+// finish ends the in-flight request for key started by begin, releasing any requests waiting on it
+func (c *idempotencyCache) finish(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if done, ok := c.inflight[key]; ok {
+		close(done)
+		delete(c.inflight, key)
+	}
+}
+
+// set records the response to the method and path for key, evicting any expired entries. An
+// existing unexpired entry is kept so concurrent first requests cannot overwrite each other's
+// recording.
+func (c *idempotencyCache) set(key, method, path string, statusCode int, header http.Header, body []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for k, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, k)
+		}
+	}
+
+	if _, exists := c.entries[key]; exists {
+		return
+	}
+	c.entries[key] = cachedResponse{
+		method:     method,
+		path:       path,
+		statusCode: statusCode,
+		header:     header,
+		body:       body,
+		expires:    now.Add(c.ttl),
+	}
+}
+
+// responseCapture passes a response through to the client while recording it
+type responseCapture struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (c *responseCapture) WriteHeader(statusCode int) {
+	if c.statusCode == 0 {
+		c.statusCode = statusCode
+	}
+	c.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (c *responseCapture) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+func (c *responseCapture) Write(b []byte) (int, error) {
+	if c.statusCode == 0 {
+		c.statusCode = http.StatusOK
+	}
+	c.body.Write(b)
+	return c.ResponseWriter.Write(b)
+}
+
+// serveIdempotent replays the cached response for the request's idempotency key, or serves the
+// request and caches its response for subsequent retries. A retry arriving while the first request
+// with its key is still in flight waits for that response. Reusing a key for a different method
+// or path gets 422 rather than another request's response.
+func (h *Handler) serveIdempotent(w http.ResponseWriter, r *http.Request, key string) {
+	logger := h.logger.With(slog.String("method", r.Method), slog.String("path", r.URL.Path), slog.String("idempotency_key", key))
+
+	for {
+		cached, ok, inflight := h.idempotency.begin(key)
+		if ok {
+			if cached.method != r.Method || cached.path != r.URL.Path {
+				logger.Info("Rejecting reused idempotency key", slog.String("key_method", cached.method), slog.String("key_path", cached.path))
+				h.writeError(w, r, http.StatusUnprocessableEntity, ErrCodeIdempotencyKeyReused, fmt.Sprintf("Idempotency key was already used for %s %s", cached.method, cached.path), logger)
+				return
+			}
+			logger.Info("Replaying idempotent response", slog.Int("status_code", cached.statusCode))
+			for k, v := range cached.header {
+				w.Header()[k] = append([]string(nil), v...)
+			}
+			w.Header().Set(idempotentReplayHeader, "true")
+			w.WriteHeader(cached.statusCode)
+			_, _ = w.Write(cached.body)
+			return
+		}
+		if inflight == nil {
+			break
+		}
+
+		logger.Debug("Waiting for in-flight request with the same idempotency key")
+		select {
+		case <-inflight:
+		case <-r.Context().Done():
+			h.writeError(w, r, http.StatusGatewayTimeout, ErrCodeGatewayTimeout, "Request ended while waiting for an in-flight request with the same idempotency key", logger)
+			return
+		}
+	}
+	defer h.idempotency.finish(key)
+
+	capture := &responseCapture{ResponseWriter: w}
+	h.serveProxy(capture, r)
+	if capture.statusCode == 0 {
+		capture.statusCode = http.StatusOK
+	}
+	h.idempotency.set(key, r.Method, r.URL.Path, capture.statusCode, w.Header().Clone(), capture.body.Bytes())
+}
@@ -0,0 +1,155 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	var hits atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := hits.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Hit", fmt.Sprint(n))
+		w.WriteHeader(http.StatusCreated)
+		_, _ = fmt.Fprintf(w, `{"hit":%d}`, n)
+	}))
+	defer upstream.Close()
+	path := "/proxy/" + strings.TrimPrefix(upstream.URL, "http://")
+
+	logger := createTestLogger()
+
+	sendTo := func(handler *Handler, method, path, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+	send := func(handler *Handler, key string) *httptest.ResponseRecorder {
+		return sendTo(handler, http.MethodPost, path, key)
+	}
+
+	t.Run("same key replays the first response", func(t *testing.T) {
+		hits.Store(0)
+		handler, err := NewHandler(30*time.Second, "test-service", logger, WithIdempotencyTTL(time.Minute))
+		require.NoError(t, err)
+
+		first := send(handler, "key-1")
+		second := send(handler, "key-1")
+
+		assert.Equal(t, int32(1), hits.Load(), "upstream should only be hit once")
+		assert.Equal(t, http.StatusCreated, first.Code)
+		assert.Equal(t, first.Code, second.Code)
+		assert.Equal(t, first.Body.String(), second.Body.String())
+		assert.Equal(t, "1", second.Header().Get("X-Hit"))
+		assert.Empty(t, first.Header().Get("X-Idempotent-Replay"))
+		assert.Equal(t, "true", second.Header().Get("X-Idempotent-Replay"))
+	})
+
+	t.Run("different keys are served independently", func(t *testing.T) {
+		hits.Store(0)
+		handler, err := NewHandler(30*time.Second, "test-service", logger, WithIdempotencyTTL(time.Minute))
+		require.NoError(t, err)
+
+		first := send(handler, "key-a")
+		second := send(handler, "key-b")
+
+		assert.Equal(t, int32(2), hits.Load())
+		assert.NotEqual(t, first.Body.String(), second.Body.String())
+		assert.Empty(t, second.Header().Get("X-Idempotent-Replay"))
+	})
+
+	t.Run("reusing a key for another request gets 422", func(t *testing.T) {
+		hits.Store(0)
+		handler, err := NewHandler(30*time.Second, "test-service", logger, WithIdempotencyTTL(time.Minute))
+		require.NoError(t, err)
+
+		send(handler, "key-1")
+		otherMethod := sendTo(handler, http.MethodPut, path, "key-1")
+		otherPath := sendTo(handler, http.MethodPost, path+"/other", "key-1")
+
+		assert.Equal(t, int32(1), hits.Load(), "mismatched requests should not reach the upstream")
+		assert.Equal(t, http.StatusUnprocessableEntity, otherMethod.Code)
+		assert.Equal(t, ErrCodeIdempotencyKeyReused, decodeErrorResponse(t, otherMethod).Code)
+		assert.Equal(t, http.StatusUnprocessableEntity, otherPath.Code)
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		hits.Store(0)
+		handler, err := NewHandler(30*time.Second, "test-service", logger)
+		require.NoError(t, err)
+
+		send(handler, "key-1")
+		second := send(handler, "key-1")
+
+		assert.Equal(t, int32(2), hits.Load())
+		assert.Empty(t, second.Header().Get("X-Idempotent-Replay"))
+	})
+}
+
+func TestIdempotencyCacheExpiry(t *testing.T) {
+	now := time.Now()
+	cache := newIdempotencyCache(time.Minute)
+	cache.now = func() time.Time { return now }
+
+	cache.set("key", http.MethodPost, "/", http.StatusOK, http.Header{}, []byte("first"))
+	cache.set("key", http.MethodPost, "/", http.StatusOK, http.Header{}, []byte("second"))
+
+	entry, ok := cache.get("key")
+	require.True(t, ok)
+	assert.Equal(t, "first", string(entry.body), "an unexpired entry should not be overwritten")
+
+	now = now.Add(2 * time.Minute)
+	_, ok = cache.get("key")
+	assert.False(t, ok, "entry should expire after the TTL")
+
+	cache.set("other", http.MethodPost, "/", http.StatusOK, http.Header{}, nil)
+	assert.Len(t, cache.entries, 1, "expired entries should be evicted")
+}
+
+func TestIdempotencyKeyInFlight(t *testing.T) {
+	var hits atomic.Int32
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := hits.Add(1)
+		<-release
+		_, _ = fmt.Fprintf(w, `{"hit":%d}`, n)
+	}))
+	defer upstream.Close()
+	path := "/proxy/" + strings.TrimPrefix(upstream.URL, "http://")
+
+	handler, err := NewHandler(30*time.Second, "test-service", createTestLogger(), WithIdempotencyTTL(time.Minute))
+	require.NoError(t, err)
+
+	send := func(results chan<- *httptest.ResponseRecorder) {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("Idempotency-Key", "key-1")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		results <- rr
+	}
+
+	results := make(chan *httptest.ResponseRecorder, 2)
+	go send(results)
+	require.Eventually(t, func() bool { return hits.Load() == 1 }, 5*time.Second, 5*time.Millisecond)
+
+	// The retry arrives while the first request is still waiting on the upstream
+	go send(results)
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	first, second := <-results, <-results
+	assert.Equal(t, int32(1), hits.Load(), "the retry should wait for the first response instead of calling the upstream")
+	assert.Equal(t, first.Body.String(), second.Body.String())
+}
@@ -0,0 +1,61 @@
+package proxy
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// inspectResult reports how a chain path is parsed, one step per directive in the order the
+// hops along the chain would apply them
+type inspectResult struct {
+	Path  string    `json:"path"`
+	Steps []actions `json:"steps"`
+	Error string    `json:"error,omitempty"`
+}
+
+// inspectPath parses path the way each hop would, stopping at the directive that answers the
+// request or at the first error. The path is first cleaned as the server's router would clean
+// it, so "https://" in a hop becomes "https:/".
+func inspectPath(path string) inspectResult {
+	result := inspectResult{Path: path, Steps: []actions{}}
+	remaining := cleanPath(path)
+	for {
+		steps, err := parseHop(remaining, 0)
+		result.Steps = append(result.Steps, steps...)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		last := steps[len(steps)-1]
+		if !last.forwards() {
+			return result
+		}
+		remaining = last.Remaining
+	}
+}
+
+// ServeInspect answers /inspect?path=<path> with the parsed actions for the path as JSON,
+// without executing any of them. Malformed paths get 400 with the steps parsed before the error.
+func (h *Handler) ServeInspect(w http.ResponseWriter, r *http.Request) {
+	logger := h.logger.With(slog.String("method", r.Method), slog.String("path", r.URL.Path))
+
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		h.writeError(w, r, http.StatusBadRequest, ErrCodeBadRequest, "Missing path query parameter, e.g. /inspect?path=/proxy/service-b:8080", logger)
+		return
+	}
+
+	result := inspectPath(path)
+	statusCode := http.StatusOK
+	if result.Error != "" {
+		statusCode = http.StatusBadRequest
+	}
+	logger.Debug("Inspected path", slog.String("inspected_path", path), slog.Int("steps", len(result.Steps)), slog.String("error", result.Error))
+
+	w.Header().Set("Content-Type", mediaTypeJSON)
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logger.Error("Failed to encode inspect response", slog.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,91 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeInspect(t *testing.T) {
+	handler, err := NewHandler(30*time.Second, "test-service", createTestLogger())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantSteps  []actions
+		wantErr    bool
+	}{
+		{
+			name:       "proxy chain",
+			path:       "/proxy/svca:8080/proxy/https://svcb:8443",
+			wantStatus: http.StatusOK,
+			wantSteps: []actions{
+				{NextHop: "svca:8080", Remaining: "/proxy/https:/svcb:8443", Scheme: "http"},
+				{NextHop: "svcb:8443", Remaining: "/", Scheme: "https"},
+				{Remaining: "/", IsLastHop: true},
+			},
+		},
+		{
+			name:       "fault then proxy",
+			path:       "/fault/503/30/proxy/svca:8080",
+			wantStatus: http.StatusOK,
+			wantSteps: []actions{
+				{Remaining: "/proxy/svca:8080", IsFault: true, FaultCode: 503, FaultPercentage: 30},
+				{NextHop: "svca:8080", Remaining: "/", Scheme: "http"},
+				{Remaining: "/", IsLastHop: true},
+			},
+		},
+		{
+			name:       "stops at answering directive",
+			path:       "/delay/10/bytes/64",
+			wantStatus: http.StatusOK,
+			wantSteps: []actions{
+				{Remaining: "/bytes/64", IsDelay: true, Delay: 10 * time.Millisecond},
+				{Remaining: "/", IsBytes: true, Bytes: 64},
+			},
+		},
+		{
+			name:       "malformed path keeps steps parsed before the error",
+			path:       "/proxy/svca:8080/fault/abc",
+			wantStatus: http.StatusBadRequest,
+			wantSteps: []actions{
+				{NextHop: "svca:8080", Remaining: "/fault/abc", Scheme: "http"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeInspect(rr, httptest.NewRequest(http.MethodGet, "/inspect?path="+url.QueryEscape(tt.path), nil))
+			require.Equal(t, tt.wantStatus, rr.Code)
+			assert.Equal(t, mediaTypeJSON, rr.Header().Get("Content-Type"))
+
+			var got inspectResult
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+			assert.Equal(t, tt.path, got.Path)
+			assert.Equal(t, tt.wantErr, got.Error != "", got.Error)
+
+			// Normalize nil and empty target lists, which JSON does not distinguish
+			for i := range got.Steps {
+				got.Steps[i].FanoutTargets, got.Steps[i].TryTargets = nil, nil
+			}
+			assert.Equal(t, tt.wantSteps, got.Steps)
+		})
+	}
+
+	t.Run("missing path", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.ServeInspect(rr, httptest.NewRequest(http.MethodGet, "/inspect", nil))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
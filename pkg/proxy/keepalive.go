@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ValidateKeepaliveTarget checks that a keepalive target is an absolute http or https URL
+func ValidateKeepaliveTarget(target string) error {
+	u, err := url.Parse(target)
+	if err != nil {
+		return fmt.Errorf("invalid keepalive target %q: %w", target, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid keepalive target %q: must be an http:// or https:// URL", target)
+	}
+	return nil
+}
+
+// KeepWarm pings every target at the given interval through the handler's upstream client so
+// its pooled connections stay open between bursts of traffic. It blocks until ctx is cancelled.
+func (h *Handler) KeepWarm(ctx context.Context, interval time.Duration, targets []string) {
+	h.logger.Info("Starting keepalive pings", slog.Duration("interval", interval), slog.Any("targets", targets))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			h.logger.Info("Stopping keepalive pings")
+			return
+		case <-ticker.C:
+			for _, target := range targets {
+				h.ping(ctx, target)
+			}
+		}
+	}
+}
+
+// ping issues a single GET to target, draining the body so the connection returns to the pool
+func (h *Handler) ping(ctx context.Context, target string) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		h.logger.Warn("Failed to create keepalive ping", slog.String("target", target), slog.String("error", err.Error()))
+		return
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		if ctx.Err() == nil {
+			h.logger.Warn("Keepalive ping failed", slog.String("target", target), slog.String("error", err.Error()))
+		}
+		return
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	h.logger.Debug("Keepalive ping succeeded", slog.String("target", target), slog.Int("status_code", resp.StatusCode))
+}
@@ -0,0 +1,52 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeepWarm(t *testing.T) {
+	var pings atomic.Int32
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pings.Add(1)
+	}))
+	defer stub.Close()
+
+	handler, err := NewHandler(30*time.Second, "test-service", createTestLogger())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		handler.KeepWarm(ctx, 50*time.Millisecond, []string{stub.URL + "/health"})
+		close(done)
+	}()
+
+	time.Sleep(275 * time.Millisecond)
+	cancel()
+	<-done
+
+	// Five ticks fit in the window; allow one either way for scheduling jitter
+	count := pings.Load()
+	assert.GreaterOrEqual(t, count, int32(4))
+	assert.LessOrEqual(t, count, int32(6))
+
+	// No more pings once stopped
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, count, pings.Load())
+}
+
+func TestValidateKeepaliveTarget(t *testing.T) {
+	assert.NoError(t, ValidateKeepaliveTarget("http://service-b:8080/health"))
+	assert.NoError(t, ValidateKeepaliveTarget("https://service-b:8443"))
+	assert.Error(t, ValidateKeepaliveTarget("service-b:8080"))
+	assert.Error(t, ValidateKeepaliveTarget("ftp://service-b"))
+	assert.Error(t, ValidateKeepaliveTarget("http://"))
+}
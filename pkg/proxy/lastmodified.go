@@ -0,0 +1,47 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// LastModifiedStart is the --last-modified value that reports the process start time
+const LastModifiedStart = "start"
+
+// WithLastModified sets a Last-Modified header of t on final responses, answering 304 Not
+// Modified when the request's If-Modified-Since is not older. The zero time disables it.
+func WithLastModified(t time.Time) HandlerOption {
+	return func(h *Handler) {
+		h.lastModified = t.UTC().Truncate(time.Second)
+	}
+}
+
+// ParseLastModified parses a Last-Modified setting: LastModifiedStart for start, or an RFC 3339
+// timestamp. An empty value returns the zero time, disabling the header.
+func ParseLastModified(value string, start time.Time) (time.Time, error) {
+	switch value {
+	case "":
+		return time.Time{}, nil
+	case LastModifiedStart:
+		return start, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid last-modified %q: must be %q or an RFC 3339 timestamp", value, LastModifiedStart)
+	}
+	return t, nil
+}
+
+// notModifiedSince reports whether a GET or HEAD request's If-Modified-Since shows the client's
+// copy is at least as new as lastModified. Unparseable dates are ignored, as RFC 9110 requires.
+func notModifiedSince(r *http.Request, lastModified time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	return !lastModified.After(since)
+}
@@ -0,0 +1,116 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLastModified(t *testing.T) {
+	start := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "disabled", value: "", want: time.Time{}},
+		{name: "process start", value: "start", want: start},
+		{name: "fixed time", value: "2024-06-01T12:00:00Z", want: time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)},
+		{name: "not a time", value: "yesterday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLastModified(tt.value, start)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, tt.want.Equal(got), "got %s, want %s", got, tt.want)
+		})
+	}
+}
+
+func TestLastModified(t *testing.T) {
+	lastModified := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	handler, err := NewHandler(30*time.Second, "test-service", createTestLogger(), WithLastModified(lastModified.Add(500*time.Millisecond)))
+	require.NoError(t, err)
+
+	do := func(t *testing.T, method, path string, headers map[string]string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+	ims := func(t time.Time) map[string]string {
+		return map[string]string{"If-Modified-Since": t.Format(http.TimeFormat)}
+	}
+
+	first := do(t, http.MethodGet, "/", nil)
+	require.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "Sat, 01 Jun 2024 12:00:00 GMT", first.Header().Get("Last-Modified"))
+
+	t.Run("copy as new as the response returns 304 without a body", func(t *testing.T) {
+		rr := do(t, http.MethodGet, "/", ims(lastModified))
+		assert.Equal(t, http.StatusNotModified, rr.Code)
+		assert.Equal(t, first.Header().Get("Last-Modified"), rr.Header().Get("Last-Modified"))
+		assert.Empty(t, rr.Body.String())
+	})
+
+	t.Run("newer copy returns 304", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotModified, do(t, http.MethodGet, "/", ims(lastModified.Add(time.Hour))).Code)
+	})
+
+	t.Run("older copy returns the full response", func(t *testing.T) {
+		rr := do(t, http.MethodGet, "/", ims(lastModified.Add(-time.Second)))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, first.Body.String(), rr.Body.String())
+	})
+
+	t.Run("malformed date is ignored", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, do(t, http.MethodGet, "/", map[string]string{"If-Modified-Since": "yesterday"}).Code)
+	})
+
+	t.Run("only applies to GET and HEAD", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, do(t, http.MethodPost, "/", ims(lastModified)).Code)
+	})
+
+	t.Run("faults are not conditional", func(t *testing.T) {
+		rr := do(t, http.MethodGet, "/fault/500", ims(lastModified))
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+		assert.Empty(t, rr.Header().Get("Last-Modified"))
+	})
+
+	t.Run("If-None-Match takes precedence", func(t *testing.T) {
+		handler, err := NewHandler(30*time.Second, "test-service", createTestLogger(), WithETag(true), WithLastModified(lastModified))
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("If-None-Match", `W/"stale"`)
+		req.Header.Set("If-Modified-Since", lastModified.Format(http.TimeFormat))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code, "a stale ETag outweighs a current date")
+		assert.NotEmpty(t, rr.Header().Get("ETag"))
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		handler, err := NewHandler(30*time.Second, "test-service", createTestLogger())
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("If-Modified-Since", time.Now().Format(http.TimeFormat))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Empty(t, rr.Header().Get("Last-Modified"))
+	})
+}
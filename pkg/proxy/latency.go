@@ -0,0 +1,54 @@
+package proxy
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net/http"
+	"time"
+)
+
+// WithLatencyPerKB delays generated responses by d for every KiB of body, simulating
+// serialization cost. Zero disables the delay.
+func WithLatencyPerKB(d time.Duration) HandlerOption {
+	return func(h *Handler) {
+		h.latencyPerKB = d
+	}
+}
+
+// sizeLatency is the delay for a body of n bytes. It is computed in floating point and capped at
+// the longest representable duration, since bytes times nanoseconds per KiB overflows an int64
+// for large bodies and delays; a request deadline ends any such wait long before the cap.
+func (h *Handler) sizeLatency(n int64) time.Duration {
+	d := float64(n) * float64(h.latencyPerKB) / 1024
+	if d >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(d)
+}
+
+// waitForSize pauses in proportion to a response body of n bytes before it is written. It
+// returns the context's error if the request ends or its deadline passes first.
+func (h *Handler) waitForSize(ctx context.Context, n int64, logger *slog.Logger) error {
+	d := h.sizeLatency(n)
+	if d <= 0 {
+		return nil
+	}
+
+	logger.Debug("Delaying response for its size", slog.Int64("bytes", n), slog.Duration("delay", d))
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// writeSizeWaitError answers a response whose size delay was cut short by the request ending
+func (h *Handler) writeSizeWaitError(w http.ResponseWriter, r *http.Request, err error, logger *slog.Logger) {
+	logger.Info("Request ended during size delay", slog.String("error", err.Error()))
+	h.writeError(w, r, http.StatusGatewayTimeout, ErrCodeGatewayTimeout, "Request deadline exceeded while delaying the response for its size", logger)
+}
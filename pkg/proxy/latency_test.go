@@ -0,0 +1,80 @@
+package proxy
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatencyPerKB(t *testing.T) {
+	h, err := NewHandler(5*time.Second, "test-service", createTestLogger(), WithLatencyPerKB(10*time.Millisecond))
+	require.NoError(t, err)
+
+	timed := func(t *testing.T, path string) (*httptest.ResponseRecorder, time.Duration) {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		start := time.Now()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr, time.Since(start)
+	}
+
+	t.Run("scales with body size", func(t *testing.T) {
+		small, smallElapsed := timed(t, "/bytes/2048")
+		large, largeElapsed := timed(t, "/bytes/20480")
+		require.Equal(t, http.StatusOK, small.Code)
+		require.Equal(t, http.StatusOK, large.Code)
+
+		assert.GreaterOrEqual(t, smallElapsed, 20*time.Millisecond)
+		assert.GreaterOrEqual(t, largeElapsed, 200*time.Millisecond)
+		assert.Greater(t, largeElapsed, smallElapsed)
+	})
+
+	t.Run("applies to the final response", func(t *testing.T) {
+		rr, elapsed := timed(t, "/")
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.GreaterOrEqual(t, elapsed, h.sizeLatency(int64(rr.Body.Len())))
+	})
+
+	t.Run("stops when the request ends", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		err := h.waitForSize(ctx, 1<<20, createTestLogger())
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("bounded by the request deadline", func(t *testing.T) {
+		for _, path := range []string{"/bytes/1048576?timeout=50ms", "/?timeout=50ms"} {
+			t.Run(path, func(t *testing.T) {
+				slow, err := NewHandler(5*time.Second, "test-service", createTestLogger(), WithLatencyPerKB(time.Second))
+				require.NoError(t, err)
+
+				rr := httptest.NewRecorder()
+				start := time.Now()
+				slow.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+
+				assert.Less(t, time.Since(start), time.Second)
+				require.Equal(t, http.StatusGatewayTimeout, rr.Code)
+				assert.Equal(t, ErrCodeGatewayTimeout, decodeErrorResponse(t, rr).Code)
+			})
+		}
+	})
+
+	t.Run("saturates instead of overflowing", func(t *testing.T) {
+		huge, err := NewHandler(5*time.Second, "test-service", createTestLogger(), WithLatencyPerKB(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, time.Duration(math.MaxInt64), huge.sizeLatency(1<<40))
+		assert.Equal(t, 2*time.Hour, huge.sizeLatency(2048))
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		plain, err := NewHandler(5*time.Second, "test-service", createTestLogger())
+		require.NoError(t, err)
+		assert.Zero(t, plain.sizeLatency(1<<20))
+	})
+}
@@ -0,0 +1,56 @@
+package proxy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseLatencyProfile parses the "p50,p95,p99" millisecond list of a /latency/ directive
+func parseLatencyProfile(s string) ([]time.Duration, error) {
+	fields := strings.Split(s, ",")
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("invalid latency: must be /latency/<p50Ms>,<p95Ms>,<p99Ms>")
+	}
+
+	percentiles := make([]time.Duration, 0, len(fields))
+	for _, field := range fields {
+		ms, err := strconv.Atoi(field)
+		if err != nil || ms < 0 {
+			return nil, fmt.Errorf("invalid latency: %q is not a non-negative number of milliseconds", field)
+		}
+		d := time.Duration(ms) * time.Millisecond
+		if len(percentiles) > 0 && d < percentiles[len(percentiles)-1] {
+			return nil, fmt.Errorf("invalid latency: percentiles must not decrease, got %s", s)
+		}
+		percentiles = append(percentiles, d)
+	}
+	return percentiles, nil
+}
+
+// sampleLatency maps u, uniform in [0, 1), to a delay whose distribution has the given p50, p95
+// and p99. The quantile function is piecewise linear through half of p50 at the bottom, the
+// three percentiles, and a tail reaching p99 plus the p95-p99 gap at the top.
+func sampleLatency(percentiles []time.Duration, u float64) time.Duration {
+	p50, p95, p99 := percentiles[0], percentiles[1], percentiles[2]
+	points := []struct {
+		q float64
+		d time.Duration
+	}{
+		{0, p50 / 2},
+		{0.50, p50},
+		{0.95, p95},
+		{0.99, p99},
+		{1, p99 + (p99 - p95)},
+	}
+
+	for i := 1; i < len(points); i++ {
+		lo, hi := points[i-1], points[i]
+		if u < hi.q {
+			frac := (u - lo.q) / (hi.q - lo.q)
+			return lo.d + time.Duration(frac*float64(hi.d-lo.d))
+		}
+	}
+	return points[len(points)-1].d
+}
@@ -0,0 +1,80 @@
+package proxy
+
+import (
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLatencyProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []time.Duration
+		wantErr bool
+	}{
+		{name: "valid", input: "20,80,200", want: []time.Duration{20 * time.Millisecond, 80 * time.Millisecond, 200 * time.Millisecond}},
+		{name: "flat", input: "50,50,50", want: []time.Duration{50 * time.Millisecond, 50 * time.Millisecond, 50 * time.Millisecond}},
+		{name: "too few", input: "20,80", wantErr: true},
+		{name: "too many", input: "20,80,200,400", wantErr: true},
+		{name: "negative", input: "-1,80,200", wantErr: true},
+		{name: "not a number", input: "20,abc,200", wantErr: true},
+		{name: "decreasing", input: "20,200,80", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLatencyProfile(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSampleLatency(t *testing.T) {
+	profile := []time.Duration{20 * time.Millisecond, 80 * time.Millisecond, 200 * time.Millisecond}
+
+	t.Run("quantile function passes through the percentiles", func(t *testing.T) {
+		assert.Equal(t, 10*time.Millisecond, sampleLatency(profile, 0))
+		assert.Equal(t, 20*time.Millisecond, sampleLatency(profile, 0.50))
+		assert.Equal(t, 80*time.Millisecond, sampleLatency(profile, 0.95))
+		assert.Equal(t, 200*time.Millisecond, sampleLatency(profile, 0.99))
+		assert.Less(t, sampleLatency(profile, 0.999999), 320*time.Millisecond)
+	})
+
+	t.Run("sampled percentiles match the profile", func(t *testing.T) {
+		rng := rand.New(rand.NewSource(1)) // #nosec G404 -- deterministic test samples
+		samples := make([]time.Duration, 100000)
+		for i := range samples {
+			samples[i] = sampleLatency(profile, rng.Float64())
+		}
+		sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+
+		for i, q := range []float64{0.50, 0.95, 0.99} {
+			got := samples[int(q*float64(len(samples)))]
+			assert.InDelta(t, float64(profile[i]), float64(got), float64(2*time.Millisecond), "p%.0f", q*100)
+		}
+	})
+}
+
+func TestLatencyProfileDelay(t *testing.T) {
+	h, err := NewHandler(5*time.Second, "test-service", createTestLogger())
+	require.NoError(t, err)
+
+	start := time.Now()
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/latency/40,40,40", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	// Half of p50 is the shortest delay the profile can produce
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+}
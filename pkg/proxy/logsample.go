@@ -0,0 +1,48 @@
+package proxy
+
+import (
+	"context"
+	"hash/fnv"
+	"log/slog"
+	"math"
+)
+
+// WithLogSampleRate logs only the given fraction (0-1) of requests at info and debug level.
+// Warnings and errors are always logged. The default of 1 logs every request.
+func WithLogSampleRate(rate float64) HandlerOption {
+	return func(h *Handler) {
+		h.logSampleRate = rate
+	}
+}
+
+// requestLogger returns the logger for the request with the given ID: the handler's logger when
+// the request is in the sample, otherwise one that only passes on warnings and errors. The choice
+// is a hash of the ID, so hops that share an X-Request-Id log the same requests.
+func (h *Handler) requestLogger(id string) *slog.Logger {
+	if h.logSampleRate >= 1 {
+		return h.logger
+	}
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(id))
+	if float64(hash.Sum64())/math.MaxUint64 < h.logSampleRate {
+		return h.logger
+	}
+	return slog.New(warnLevelHandler{h.logger.Handler()})
+}
+
+// warnLevelHandler passes on only warnings and errors
+type warnLevelHandler struct {
+	slog.Handler
+}
+
+func (w warnLevelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= slog.LevelWarn && w.Handler.Enabled(ctx, level)
+}
+
+func (w warnLevelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return warnLevelHandler{w.Handler.WithAttrs(attrs)}
+}
+
+func (w warnLevelHandler) WithGroup(name string) slog.Handler {
+	return warnLevelHandler{w.Handler.WithGroup(name)}
+}
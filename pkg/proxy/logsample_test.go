@@ -0,0 +1,78 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogSampleRate(t *testing.T) {
+	newHandler := func(t *testing.T, rate float64) (*Handler, *bytes.Buffer) {
+		t.Helper()
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		h, err := NewHandler(5*time.Second, "test-service", logger, WithLogSampleRate(rate))
+		require.NoError(t, err)
+		return h, &buf
+	}
+
+	// count returns how many log entries with the given message were written
+	count := func(t *testing.T, buf *bytes.Buffer, msg string) int {
+		n := 0
+		scanner := bufio.NewScanner(bytes.NewReader(buf.Bytes()))
+		for scanner.Scan() {
+			var entry map[string]any
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+			if entry["msg"] == msg {
+				n++
+			}
+		}
+		return n
+	}
+
+	t.Run("logs roughly the configured fraction", func(t *testing.T) {
+		h, buf := newHandler(t, 0.1)
+		const requests = 5000
+		for range requests {
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		}
+
+		logged := count(t, buf, "Incoming request")
+		assert.InDelta(t, requests/10, logged, requests/50, "logged %d of %d requests", logged, requests)
+	})
+
+	t.Run("logs every request by default", func(t *testing.T) {
+		h, buf := newHandler(t, 1)
+		for range 100 {
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		}
+		assert.Equal(t, 100, count(t, buf, "Incoming request"))
+	})
+
+	t.Run("errors are always logged", func(t *testing.T) {
+		h, buf := newHandler(t, 0)
+		for range 20 {
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fault/abc", nil))
+		}
+		assert.Zero(t, count(t, buf, "Incoming request"))
+		assert.Equal(t, 20, count(t, buf, "Path parsing failed"))
+	})
+
+	t.Run("the same request ID gets the same decision", func(t *testing.T) {
+		h, _ := newHandler(t, 0.5)
+		for _, id := range []string{"a", "b", "c", "d", "e", "f"} {
+			first := h.requestLogger(id).Enabled(t.Context(), slog.LevelInfo)
+			for range 5 {
+				assert.Equal(t, first, h.requestLogger(id).Enabled(t.Context(), slog.LevelInfo), id)
+			}
+		}
+	})
+}
@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// proxiedMethods is the Allow header for a rejected CONNECT when no allowed methods are configured:
+// the standard methods, which are forwarded as they are
+const proxiedMethods = "GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS, TRACE"
+
+// WithAllowedMethods restricts requests to the given HTTP methods; others are answered with
+// 405 Method Not Allowed and an Allow header listing the permitted ones. Methods are compared
+// case-sensitively, so they should be upper case, as ParseAllowedMethods returns them. An empty
+// list allows every method.
+func WithAllowedMethods(methods []string) HandlerOption {
+	return func(h *Handler) {
+		h.allowedMethods = methods
+	}
+}
+
+// ParseAllowedMethods upper-cases and deduplicates a list of HTTP methods such as
+// ["get", "POST"], keeping their order
+func ParseAllowedMethods(methods []string) ([]string, error) {
+	var allowed []string
+	for _, method := range methods {
+		method = strings.ToUpper(strings.TrimSpace(method))
+		if method == "" || strings.IndexFunc(method, func(r rune) bool { return !isTokenChar(r) }) >= 0 {
+			return nil, fmt.Errorf("invalid allowed method %q: must be an HTTP method name such as GET", method)
+		}
+		if !slices.Contains(allowed, method) {
+			allowed = append(allowed, method)
+		}
+	}
+	return allowed, nil
+}
+
+// allowMethod reports whether the request's method is allowed, answering 405 if not
+func (h *Handler) allowMethod(w http.ResponseWriter, r *http.Request) bool {
+	if len(h.allowedMethods) == 0 || slices.Contains(h.allowedMethods, r.Method) {
+		return true
+	}
+
+	logger := h.logger.With(slog.String("method", r.Method), slog.String("path", r.URL.Path))
+	logger.Info("Rejected disallowed method")
+	allow := strings.Join(h.allowedMethods, ", ")
+	w.Header().Set("Allow", allow)
+	h.writeError(w, r, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, fmt.Sprintf("Method %s is not allowed, use one of %s", r.Method, allow), logger)
+	return false
+}
+
+// connectAllow is the Allow header for a rejected CONNECT: the allowed methods other than CONNECT,
+// which is never proxied, or the standard methods when every method is allowed
+func (h *Handler) connectAllow() string {
+	allowed := slices.DeleteFunc(slices.Clone(h.allowedMethods), func(method string) bool {
+		return method == http.MethodConnect
+	})
+	if len(allowed) == 0 {
+		return proxiedMethods
+	}
+	return strings.Join(allowed, ", ")
+}
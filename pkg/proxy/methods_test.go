@@ -0,0 +1,88 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAllowedMethods(t *testing.T) {
+	tests := []struct {
+		name    string
+		methods []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "none", methods: nil, want: nil},
+		{name: "upper cased", methods: []string{"get", " Post "}, want: []string{"GET", "POST"}},
+		{name: "duplicates dropped", methods: []string{"GET", "get", "HEAD"}, want: []string{"GET", "HEAD"}},
+		{name: "extension method", methods: []string{"M-SEARCH"}, want: []string{"M-SEARCH"}},
+		{name: "empty", methods: []string{"GET", ""}, wantErr: true},
+		{name: "space inside", methods: []string{"GE T"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAllowedMethods(tt.methods)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAllowedMethods(t *testing.T) {
+	logger := createTestLogger()
+
+	t.Run("all methods allowed by default", func(t *testing.T) {
+		h, err := NewHandler(5*time.Second, "test-service", logger)
+		require.NoError(t, err)
+		for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodDelete} {
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, httptest.NewRequest(method, "/", nil))
+			assert.Equal(t, http.StatusOK, rr.Code, method)
+		}
+	})
+
+	h, err := NewHandler(5*time.Second, "test-service", logger, WithAllowedMethods([]string{http.MethodGet, http.MethodHead}))
+	require.NoError(t, err)
+
+	t.Run("allowed methods are served", func(t *testing.T) {
+		for _, method := range []string{http.MethodGet, http.MethodHead} {
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, httptest.NewRequest(method, "/", nil))
+			assert.Equal(t, http.StatusOK, rr.Code, method)
+			assert.Empty(t, rr.Header().Get("Allow"), method)
+		}
+	})
+
+	t.Run("other methods get 405 with Allow", func(t *testing.T) {
+		for _, method := range []string{http.MethodPost, http.MethodDelete, "PURGE"} {
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, httptest.NewRequest(method, "/proxy/svc:8080", nil))
+			assert.Equal(t, http.StatusMethodNotAllowed, rr.Code, method)
+			assert.Equal(t, "GET, HEAD", rr.Header().Get("Allow"), method)
+			assert.Equal(t, ErrCodeMethodNotAllowed, decodeErrorResponse(t, rr).Code, method)
+		}
+	})
+	t.Run("composed requests are held to the allowed methods", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		h.ServeCompose(rr, httptest.NewRequest(http.MethodPost, "/compose", strings.NewReader(`{"method":"DELETE","hops":[{"target":"svc:8080"}]}`)))
+		assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+		assert.Equal(t, "GET, HEAD", rr.Header().Get("Allow"))
+	})
+
+	t.Run("replayed requests are held to the allowed methods", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		h.ServeReplay(rr, httptest.NewRequest(http.MethodPost, "/replay", strings.NewReader(`{"method":"PUT","path":"/proxy/svc:8080"}`)))
+		assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+		assert.Equal(t, "GET, HEAD", rr.Header().Get("Allow"))
+	})
+}
@@ -0,0 +1,54 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+)
+
+// maxMultipartParts caps the parts a /multipart/ directive may ask for
+const maxMultipartParts = 1000
+
+// MultipartPart is the JSON body of each part in a /multipart/ response
+type MultipartPart struct {
+	Part    int    `json:"part"`
+	Total   int    `json:"total"`
+	Service string `json:"service"`
+}
+
+// sendMultipart answers with a multipart/mixed response of n JSON parts
+func (h *Handler) sendMultipart(w http.ResponseWriter, n int, logger *slog.Logger) {
+	mw := multipart.NewWriter(w)
+	w.Header().Set("Content-Type", "multipart/mixed; boundary="+mw.Boundary())
+	w.WriteHeader(http.StatusOK)
+
+	for i := 1; i <= n; i++ {
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type": {mediaTypeJSON},
+			"Content-Id":   {strconv.Itoa(i)},
+		})
+		if err == nil {
+			err = json.NewEncoder(part).Encode(MultipartPart{Part: i, Total: n, Service: h.serviceName})
+		}
+		if err != nil {
+			logger.Error("Failed to write multipart part", slog.Int("part", i), slog.String("error", err.Error()))
+			return
+		}
+	}
+	if err := mw.Close(); err != nil {
+		logger.Error("Failed to close multipart response", slog.String("error", err.Error()))
+	}
+}
+
+// parseMultipartParts validates the part count of a /multipart/ directive
+func parseMultipartParts(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > maxMultipartParts {
+		return 0, fmt.Errorf("invalid multipart: parts must be a number between 1 and %d", maxMultipartParts)
+	}
+	return n, nil
+}
@@ -0,0 +1,49 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultipartDirective(t *testing.T) {
+	handler, err := NewHandler(30*time.Second, "test-service", createTestLogger())
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/delay/1/multipart/3", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	mediaType, params, err := mime.ParseMediaType(rr.Header().Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/mixed", mediaType)
+
+	reader := multipart.NewReader(rr.Body, params["boundary"])
+	var parts []MultipartPart
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		assert.Equal(t, mediaTypeJSON, part.Header.Get("Content-Type"))
+
+		var body MultipartPart
+		require.NoError(t, json.NewDecoder(part).Decode(&body))
+		parts = append(parts, body)
+	}
+
+	assert.Equal(t, []MultipartPart{
+		{Part: 1, Total: 3, Service: "test-service"},
+		{Part: 2, Total: 3, Service: "test-service"},
+		{Part: 3, Total: 3, Service: "test-service"},
+	}, parts)
+}
@@ -0,0 +1,77 @@
+package proxy
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Media types the final response can be serialized as
+const (
+	mediaTypeJSON = "application/json"
+	mediaTypeXML  = "application/xml"
+	mediaTypeText = "text/plain"
+)
+
+// acceptRange is a single media range from an Accept header
+type acceptRange struct {
+	mediaType string
+	quality   float64
+}
+
+// negotiateMediaType picks the response media type for the given Accept header.
+// Returns mediaTypeJSON when the header is empty and ok=false when no supported
+// type is acceptable to the client.
+func negotiateMediaType(accept string) (mediaType string, ok bool) {
+	if strings.TrimSpace(accept) == "" {
+		return mediaTypeJSON, true
+	}
+
+	ranges := parseAccept(accept)
+	for _, ar := range ranges {
+		if ar.quality <= 0 {
+			continue
+		}
+		switch ar.mediaType {
+		case "*/*", "application/*", mediaTypeJSON:
+			return mediaTypeJSON, true
+		case mediaTypeXML, "text/xml":
+			return mediaTypeXML, true
+		case "text/*", mediaTypeText:
+			return mediaTypeText, true
+		}
+	}
+
+	return "", false
+}
+
+// parseAccept splits an Accept header into media ranges ordered by descending quality.
+// Ranges with equal quality keep the order the client sent them in.
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(fields[0]))
+		if mediaType == "" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range fields[1:] {
+			key, value, found := strings.Cut(strings.TrimSpace(param), "=")
+			if !found || strings.TrimSpace(key) != "q" {
+				continue
+			}
+			if q, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				quality = q
+			}
+		}
+
+		ranges = append(ranges, acceptRange{mediaType: mediaType, quality: quality})
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].quality > ranges[j].quality
+	})
+	return ranges
+}
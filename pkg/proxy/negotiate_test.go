@@ -0,0 +1,113 @@
+package proxy
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateMediaType(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		want   string
+		wantOK bool
+	}{
+		{name: "empty defaults to json", accept: "", want: mediaTypeJSON, wantOK: true},
+		{name: "wildcard", accept: "*/*", want: mediaTypeJSON, wantOK: true},
+		{name: "json", accept: "application/json", want: mediaTypeJSON, wantOK: true},
+		{name: "xml", accept: "application/xml", want: mediaTypeXML, wantOK: true},
+		{name: "text xml", accept: "text/xml", want: mediaTypeXML, wantOK: true},
+		{name: "plain text", accept: "text/plain", want: mediaTypeText, wantOK: true},
+		{name: "text wildcard", accept: "text/*", want: mediaTypeText, wantOK: true},
+		{name: "first supported wins", accept: "image/png, text/plain, application/json", want: mediaTypeText, wantOK: true},
+		{name: "quality ordering", accept: "application/json;q=0.5, application/xml", want: mediaTypeXML, wantOK: true},
+		{name: "zero quality excluded", accept: "application/json;q=0, text/plain", want: mediaTypeText, wantOK: true},
+		{name: "case insensitive", accept: "Application/XML", want: mediaTypeXML, wantOK: true},
+		{name: "unsupported", accept: "image/png", want: "", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := negotiateMediaType(tt.accept)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFinalResponseContentNegotiation(t *testing.T) {
+	logger := createTestLogger()
+
+	serve := func(t *testing.T, handler *Handler, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	handler, err := NewHandler(30*time.Second, "test-service", logger)
+	require.NoError(t, err)
+
+	t.Run("no accept header returns json", func(t *testing.T) {
+		rr := serve(t, handler, "")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+		var resp Response
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.Equal(t, "test-service", resp.Service)
+		assert.Equal(t, http.StatusOK, resp.Status)
+	})
+
+	t.Run("json", func(t *testing.T) {
+		rr := serve(t, handler, "application/json")
+		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"status":200,"service":"test-service","message":"Request processed successfully"}`, rr.Body.String())
+	})
+
+	t.Run("xml", func(t *testing.T) {
+		rr := serve(t, handler, "application/xml")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "application/xml", rr.Header().Get("Content-Type"))
+
+		var resp Response
+		require.NoError(t, xml.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.Equal(t, "test-service", resp.Service)
+		assert.Equal(t, http.StatusOK, resp.Status)
+		assert.Equal(t, "Request processed successfully", resp.Message)
+	})
+
+	t.Run("plain text", func(t *testing.T) {
+		rr := serve(t, handler, "text/plain")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "text/plain", rr.Header().Get("Content-Type"))
+		assert.Equal(t, "status: 200\nservice: test-service\nmessage: Request processed successfully\n", rr.Body.String())
+	})
+
+	t.Run("unsupported falls back to json when not strict", func(t *testing.T) {
+		rr := serve(t, handler, "image/png")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	})
+
+	t.Run("unsupported returns 406 when strict", func(t *testing.T) {
+		strict, err := NewHandler(30*time.Second, "test-service", logger, WithStrictAccept(true))
+		require.NoError(t, err)
+
+		rr := serve(t, strict, "image/png")
+		assert.Equal(t, http.StatusNotAcceptable, rr.Code)
+
+		rr = serve(t, strict, "application/xml")
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+}
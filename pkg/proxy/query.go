@@ -0,0 +1,36 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+)
+
+// WithForwardQuery passes the incoming query string on to the next /proxy/ hop. By default a hop
+// drops the query, carrying only the trace and aggregate parameters when they are set. Full-path
+// forwarding always keeps the query.
+func WithForwardQuery(enabled bool) HandlerOption {
+	return func(h *Handler) {
+		h.forwardQuery = enabled
+	}
+}
+
+// nextHopQuery adds the query parameters the next hop should see to its URL: the whole incoming
+// query when forwarding queries, otherwise only the trace and aggregate flags
+func (h *Handler) nextHopQuery(r *http.Request, url string) string {
+	if !h.forwardQuery {
+		return withAggregate(r, withTrace(r, url))
+	}
+	return withQuery(url, r.URL.RawQuery)
+}
+
+// withQuery appends rawQuery to url, after any query url already has
+func withQuery(url, rawQuery string) string {
+	if rawQuery == "" {
+		return url
+	}
+	separator := "?"
+	if strings.Contains(url, "?") {
+		separator = "&"
+	}
+	return url + separator + rawQuery
+}
@@ -0,0 +1,76 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		rawQuery string
+		want     string
+	}{
+		{name: "no query", url: "http://svc:8080/", rawQuery: "", want: "http://svc:8080/"},
+		{name: "appended", url: "http://svc:8080/", rawQuery: "page=2&sort=name", want: "http://svc:8080/?page=2&sort=name"},
+		{name: "merged with existing query", url: "http://svc:8080/api?page=2", rawQuery: "sort=name", want: "http://svc:8080/api?page=2&sort=name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, withQuery(tt.url, tt.rawQuery))
+		})
+	}
+}
+
+func TestForwardQuery(t *testing.T) {
+	var got string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.RawQuery
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+	path := "/proxy/" + strings.TrimPrefix(upstream.URL, "http://")
+
+	tests := []struct {
+		name          string
+		query         string
+		wantDefault   string
+		wantForwarded string
+	}{
+		{name: "no query", query: "", wantDefault: "", wantForwarded: ""},
+		{name: "parameters", query: "page=2&sort=name", wantDefault: "", wantForwarded: "page=2&sort=name"},
+		{name: "escaped values", query: "q=a%20b%26c", wantDefault: "", wantForwarded: "q=a%20b%26c"},
+		{name: "trace is carried once", query: "trace=true&page=2", wantDefault: "trace=true", wantForwarded: "trace=true&page=2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := path
+			if tt.query != "" {
+				target += "?" + tt.query
+			}
+
+			for _, forward := range []bool{false, true} {
+				h, err := NewHandler(5*time.Second, "test-service", createTestLogger(), WithForwardQuery(forward))
+				require.NoError(t, err)
+
+				got = "unset"
+				rr := httptest.NewRecorder()
+				h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+				require.Equal(t, http.StatusOK, rr.Code)
+
+				want := tt.wantDefault
+				if forward {
+					want = tt.wantForwarded
+				}
+				assert.Equal(t, want, got, "forward query %t", forward)
+			}
+		})
+	}
+}
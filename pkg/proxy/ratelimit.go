@@ -0,0 +1,104 @@
+package proxy
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// rateLimiterIdleTTL is how long a client's limiter is kept after its last request
+const rateLimiterIdleTTL = 3 * time.Minute
+
+// WithRateLimit limits each client IP to rps requests per second with bursts of up to burst
+// requests. Requests over the limit are answered 429 with Retry-After. A burst of 0 defaults to
+// rps rounded up. An rps of 0 disables rate limiting.
+func WithRateLimit(rps float64, burst int) HandlerOption {
+	return func(h *Handler) {
+		if rps <= 0 {
+			h.rateLimiter = nil
+			return
+		}
+		if burst <= 0 {
+			burst = int(math.Ceil(rps))
+		}
+		h.rateLimiter = newRateLimiter(rate.Limit(rps), burst)
+	}
+}
+
+// clientLimiter is the token bucket for one client
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// rateLimiter keeps a token bucket per client, evicting buckets idle for rateLimiterIdleTTL
+type rateLimiter struct {
+	limit rate.Limit
+	burst int
+	now   func() time.Time
+
+	mu        sync.Mutex
+	clients   map[string]*clientLimiter
+	lastSweep time.Time
+}
+
+// newRateLimiter creates a limiter allowing limit requests per second per client
+func newRateLimiter(limit rate.Limit, burst int) *rateLimiter {
+	return &rateLimiter{
+		limit:     limit,
+		burst:     burst,
+		now:       time.Now,
+		clients:   make(map[string]*clientLimiter),
+		lastSweep: time.Now(),
+	}
+}
+
+// allow takes a token for client, returning how long to wait before retrying when none is left
+func (l *rateLimiter) allow(client string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) >= rateLimiterIdleTTL {
+		for k, c := range l.clients {
+			if now.Sub(c.lastSeen) >= rateLimiterIdleTTL {
+				delete(l.clients, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	c, ok := l.clients[client]
+	if !ok {
+		c = &clientLimiter{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[client] = c
+	}
+	c.lastSeen = now
+
+	reservation := c.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// rateLimited answers 429 and returns true when the request's client is over its rate limit
+func (h *Handler) rateLimited(w http.ResponseWriter, r *http.Request) bool {
+	client := clientIP(r, h.trustProxyHeaders)
+	ok, retryAfter := h.rateLimiter.allow(client)
+	if ok {
+		return false
+	}
+
+	// Retry-After is in whole seconds, so round up to never invite an early retry
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	h.writeError(w, r, http.StatusTooManyRequests, ErrCodeRateLimited, fmt.Sprintf("Rate limit exceeded, retry after %ds", seconds), h.logger.With("client_ip", client))
+	return true
+}
@@ -0,0 +1,89 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimit(t *testing.T) {
+	newRequest := func(remoteAddr string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		return req
+	}
+
+	t.Run("requests over the limit get 429 with Retry-After", func(t *testing.T) {
+		handler, err := NewHandler(30*time.Second, "test-service", createTestLogger(), WithRateLimit(1, 3))
+		require.NoError(t, err)
+
+		codes := map[int]int{}
+		var retryAfter string
+		for i := 0; i < 10; i++ {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, newRequest("10.0.0.1:1234"))
+			codes[rr.Code]++
+			if rr.Code == http.StatusTooManyRequests {
+				retryAfter = rr.Header().Get("Retry-After")
+			}
+		}
+
+		assert.Equal(t, 3, codes[http.StatusOK], "burst should be allowed")
+		assert.Equal(t, 7, codes[http.StatusTooManyRequests])
+		seconds, err := strconv.Atoi(retryAfter)
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, seconds, 1)
+	})
+
+	t.Run("clients are limited independently", func(t *testing.T) {
+		handler, err := NewHandler(30*time.Second, "test-service", createTestLogger(), WithRateLimit(1, 1))
+		require.NoError(t, err)
+
+		for _, addr := range []string{"10.0.0.1:1234", "10.0.0.2:1234"} {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, newRequest(addr))
+			assert.Equal(t, http.StatusOK, rr.Code, addr)
+		}
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, newRequest("10.0.0.1:5678"))
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code, "same IP on another port shares the limit")
+	})
+
+	t.Run("forwarded client IP is used when trusted", func(t *testing.T) {
+		handler, err := NewHandler(30*time.Second, "test-service", createTestLogger(), WithRateLimit(1, 1), WithTrustProxyHeaders(true))
+		require.NoError(t, err)
+
+		for _, ip := range []string{"203.0.113.1", "203.0.113.2"} {
+			req := newRequest("10.0.0.1:1234")
+			req.Header.Set("X-Forwarded-For", ip)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			assert.Equal(t, http.StatusOK, rr.Code, ip)
+		}
+	})
+}
+
+func TestRateLimiterEviction(t *testing.T) {
+	now := time.Now()
+	limiter := newRateLimiter(1, 1)
+	limiter.now = func() time.Time { return now }
+	limiter.lastSweep = now
+
+	ok, _ := limiter.allow("idle")
+	require.True(t, ok)
+	ok, _ = limiter.allow("idle")
+	require.False(t, ok)
+
+	// Once the sweep interval passes, the idle client's bucket is dropped
+	now = now.Add(rateLimiterIdleTTL)
+	ok, _ = limiter.allow("active")
+	require.True(t, ok)
+	assert.NotContains(t, limiter.clients, "idle")
+	assert.Contains(t, limiter.clients, "active")
+}
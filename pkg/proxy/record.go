@@ -0,0 +1,222 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithRecordFile appends one JSON line per request (method, path, status, duration, body) to the
+// file, for building test fixtures. Returns an error from NewHandler if the file cannot be opened.
+func WithRecordFile(path string) HandlerOption {
+	return func(h *Handler) {
+		h.recordFile = path
+	}
+}
+
+// WithRecordHeaders configures whether recorded requests include request and response
+// headers. Sensitive headers are redacted as they are in logs.
+func WithRecordHeaders(enabled bool) HandlerOption {
+	return func(h *Handler) {
+		h.recordHeaders = enabled
+	}
+}
+
+// maxRecordedBodyBytes caps the request body kept in a record; larger bodies are left out and the
+// record is marked with BodyOmitted
+const maxRecordedBodyBytes = 1 << 20
+
+// Record is a single recorded request/response exchange. Body holds the request body as the proxy
+// read it, base64 encoded in JSON.
+type Record struct {
+	Time            time.Time           `json:"time"`
+	Method          string              `json:"method"`
+	Path            string              `json:"path"`
+	Query           string              `json:"query,omitempty"`
+	Status          int                 `json:"status"`
+	DurationMs      float64             `json:"duration_ms"`
+	RequestHeaders  map[string][]string `json:"request_headers,omitempty"`
+	ResponseHeaders map[string][]string `json:"response_headers,omitempty"`
+	Body            []byte              `json:"body,omitempty"`
+	BodyOmitted     bool                `json:"body_omitted,omitempty"`
+}
+
+// recorder serializes records to a JSONL file. Writes are buffered and flushed per record
+// so the file is always made of complete lines.
+type recorder struct {
+	mu     sync.Mutex
+	file   *os.File
+	writer *bufio.Writer
+}
+
+// newRecorder opens path for appending, creating it if needed
+func newRecorder(path string) (*recorder, error) {
+	f, err := os.OpenFile(filepath.Clean(path), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("opening record file %q: %w", path, err)
+	}
+	return &recorder{file: f, writer: bufio.NewWriter(f)}, nil
+}
+
+// write appends rec as a single JSON line
+func (r *recorder) write(rec Record) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.writer.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return r.writer.Flush()
+}
+
+// close flushes any buffered records and closes the file
+func (r *recorder) close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.writer.Flush(); err != nil {
+		_ = r.file.Close()
+		return err
+	}
+	return r.file.Close()
+}
+
+// statusRecorder passes a response through while remembering its status code
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (s *statusRecorder) WriteHeader(statusCode int) {
+	if s.statusCode == 0 {
+		s.statusCode = statusCode
+	}
+	s.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.statusCode == 0 {
+		s.statusCode = http.StatusOK
+	}
+	return s.ResponseWriter.Write(b)
+}
+
+// bodyCapture passes a request body through while keeping a copy of what was read, up to
+// maxRecordedBodyBytes. The copy is guarded because the transport may still be reading the body
+// on its own goroutine when the record is written.
+type bodyCapture struct {
+	io.ReadCloser
+	mu       sync.Mutex
+	buf      bytes.Buffer
+	overflow bool
+}
+
+// captureBody wraps r's body so its content can be recorded, returning nil if it has none
+func captureBody(r *http.Request) *bodyCapture {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+	body := &bodyCapture{ReadCloser: r.Body}
+	r.Body = body
+	return body
+}
+
+func (b *bodyCapture) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.overflow {
+		if b.buf.Len()+n > maxRecordedBodyBytes {
+			b.overflow = true
+			b.buf = bytes.Buffer{}
+		} else {
+			b.buf.Write(p[:n])
+		}
+	}
+	return n, err
+}
+
+// captured returns a copy of the body read so far, and false if it grew too large to keep
+func (b *bodyCapture) captured() ([]byte, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.overflow {
+		return nil, false
+	}
+	return bytes.Clone(b.buf.Bytes()), true
+}
+
+// recordRequest writes the record for a completed request, including whatever of its body was read
+func (h *Handler) recordRequest(w *statusRecorder, r *http.Request, body *bodyCapture, start time.Time) {
+	status := w.statusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	rec := Record{
+		Time:       start.UTC(),
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Query:      r.URL.RawQuery,
+		Status:     status,
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if h.recordHeaders {
+		rec.RequestHeaders = redactHeaders(r.Header)
+		rec.ResponseHeaders = redactHeaders(w.Header())
+	}
+	if body != nil {
+		content, ok := body.captured()
+		rec.Body = content
+		rec.BodyOmitted = !ok
+	}
+
+	if err := h.recorder.write(rec); err != nil {
+		h.logger.Error("Failed to write request record", slog.String("error", err.Error()))
+	}
+}
+
+// redactHeaders copies headers, replacing the values of sensitive ones
+func redactHeaders(headers http.Header) map[string][]string {
+	if len(headers) == 0 {
+		return nil
+	}
+	out := make(map[string][]string, len(headers))
+	for key, values := range headers {
+		if sensitiveHeaders[strings.ToLower(key)] {
+			out[key] = []string{"[REDACTED]"}
+			continue
+		}
+		out[key] = append([]string(nil), values...)
+	}
+	return out
+}
+
+// Close stops any backend health probes and flushes and closes the record file, if any
+func (h *Handler) Close() error {
+	if h.backendHealth != nil {
+		h.backendHealth.close()
+	}
+	if h.recorder == nil {
+		return nil
+	}
+	return h.recorder.close()
+}
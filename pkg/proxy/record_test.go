@@ -0,0 +1,133 @@
+package proxy
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readRecords decodes every line of a record file
+func readRecords(t *testing.T, path string) []Record {
+	t.Helper()
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var rec Record
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &rec))
+		records = append(records, rec)
+	}
+	require.NoError(t, scanner.Err())
+	return records
+}
+
+func TestRecordFile(t *testing.T) {
+	t.Run("records each request", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "records.jsonl")
+		h, err := NewHandler(30*time.Second, "test-service", createTestLogger(), WithRecordFile(path))
+		require.NoError(t, err)
+
+		for _, p := range []string{"/", "/fault/503", "/bogus?x=1"} {
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, p, nil))
+		}
+		require.NoError(t, h.Close())
+
+		records := readRecords(t, path)
+		require.Len(t, records, 3)
+
+		assert.Equal(t, http.MethodGet, records[0].Method)
+		assert.Equal(t, "/", records[0].Path)
+		assert.Equal(t, http.StatusOK, records[0].Status)
+		assert.GreaterOrEqual(t, records[0].DurationMs, 0.0)
+		assert.Nil(t, records[0].RequestHeaders)
+
+		assert.Equal(t, "/fault/503", records[1].Path)
+		assert.Equal(t, http.StatusServiceUnavailable, records[1].Status)
+
+		assert.Equal(t, "/bogus", records[2].Path)
+		assert.Equal(t, "x=1", records[2].Query)
+		assert.Equal(t, http.StatusNotFound, records[2].Status)
+	})
+
+	t.Run("records headers with redaction", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "records.jsonl")
+		h, err := NewHandler(30*time.Second, "test-service", createTestLogger(), WithRecordFile(path), WithRecordHeaders(true))
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Test", "value")
+		req.Header.Set("Authorization", "Bearer secret")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		require.NoError(t, h.Close())
+
+		records := readRecords(t, path)
+		require.Len(t, records, 1)
+		assert.Equal(t, []string{"value"}, records[0].RequestHeaders["X-Test"])
+		assert.Equal(t, []string{"[REDACTED]"}, records[0].RequestHeaders["Authorization"])
+		assert.Equal(t, []string{"application/json"}, records[0].ResponseHeaders["Content-Type"])
+	})
+
+	t.Run("records request bodies", func(t *testing.T) {
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.Copy(w, r.Body)
+		}))
+		defer upstream.Close()
+		target := strings.TrimPrefix(upstream.URL, "http://")
+
+		path := filepath.Join(t.TempDir(), "records.jsonl")
+		h, err := NewHandler(30*time.Second, "test-service", createTestLogger(), WithRecordFile(path))
+		require.NoError(t, err)
+
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/proxy/"+target, strings.NewReader(`{"id":1}`)))
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/proxy/"+target, strings.NewReader(strings.Repeat("x", maxRecordedBodyBytes+1))))
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		require.NoError(t, h.Close())
+
+		records := readRecords(t, path)
+		require.Len(t, records, 3)
+		assert.Equal(t, `{"id":1}`, string(records[0].Body))
+		assert.False(t, records[0].BodyOmitted)
+		assert.Empty(t, records[1].Body)
+		assert.True(t, records[1].BodyOmitted, "bodies over the cap are left out")
+		assert.Empty(t, records[2].Body)
+		assert.False(t, records[2].BodyOmitted)
+	})
+
+	t.Run("concurrent requests produce complete lines", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "records.jsonl")
+		h, err := NewHandler(30*time.Second, "test-service", createTestLogger(), WithRecordFile(path))
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+			}()
+		}
+		wg.Wait()
+		require.NoError(t, h.Close())
+
+		assert.Len(t, readRecords(t, path), 50)
+	})
+
+	t.Run("unwritable path fails at construction", func(t *testing.T) {
+		_, err := NewHandler(30*time.Second, "test-service", createTestLogger(), WithRecordFile(filepath.Join(t.TempDir(), "missing", "records.jsonl")))
+		assert.Error(t, err)
+	})
+}
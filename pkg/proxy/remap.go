@@ -0,0 +1,59 @@
+package proxy
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// WithStatusRemap rewrites upstream status codes before they are forwarded to the client,
+// e.g. {404: 200} treats an upstream 404 as success
+func WithStatusRemap(remap map[int]int) HandlerOption {
+	return func(h *Handler) {
+		h.statusRemap = remap
+	}
+}
+
+// ParseStatusRemap parses "from:to" pairs such as "404:200" into a status code mapping
+func ParseStatusRemap(pairs []string) (map[int]int, error) {
+	remap := make(map[int]int, len(pairs))
+	for _, pair := range pairs {
+		fromStr, toStr, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid status remap %q: must be <from>:<to>", pair)
+		}
+		from, err := parseStatusCode(fromStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid status remap %q: %w", pair, err)
+		}
+		to, err := parseStatusCode(toStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid status remap %q: %w", pair, err)
+		}
+		if _, dup := remap[from]; dup {
+			return nil, fmt.Errorf("invalid status remap %q: %d is remapped more than once", pair, from)
+		}
+		remap[from] = to
+	}
+	return remap, nil
+}
+
+// parseStatusCode parses an HTTP status code in the range 100-599
+func parseStatusCode(s string) (int, error) {
+	code, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || code < 100 || code > 599 {
+		return 0, fmt.Errorf("status code %q must be a number between 100 and 599", s)
+	}
+	return code, nil
+}
+
+// remapStatus returns the status code to send the client for an upstream status code
+func (h *Handler) remapStatus(statusCode int, logger *slog.Logger) int {
+	to, ok := h.statusRemap[statusCode]
+	if !ok {
+		return statusCode
+	}
+	logger.Info("Remapping upstream status", slog.Int("upstream_status", statusCode), slog.Int("status_code", to))
+	return to
+}
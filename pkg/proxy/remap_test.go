@@ -0,0 +1,71 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStatusRemap(t *testing.T) {
+	tests := []struct {
+		name    string
+		pairs   []string
+		want    map[int]int
+		wantErr bool
+	}{
+		{name: "no pairs", pairs: nil, want: map[int]int{}},
+		{name: "single pair", pairs: []string{"404:200"}, want: map[int]int{404: 200}},
+		{name: "multiple pairs", pairs: []string{"404:200", " 500:503 "}, want: map[int]int{404: 200, 500: 503}},
+		{name: "missing separator", pairs: []string{"404"}, wantErr: true},
+		{name: "non-numeric code", pairs: []string{"abc:200"}, wantErr: true},
+		{name: "code out of range", pairs: []string{"404:600"}, wantErr: true},
+		{name: "duplicate source", pairs: []string{"404:200", "404:204"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseStatusRemap(tt.pairs)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestStatusRemap(t *testing.T) {
+	newUpstream := func(t *testing.T, status int) string {
+		t.Helper()
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte("upstream body"))
+		}))
+		t.Cleanup(upstream.Close)
+		return strings.TrimPrefix(upstream.URL, "http://")
+	}
+	notFound := newUpstream(t, http.StatusNotFound)
+	failing := newUpstream(t, http.StatusInternalServerError)
+
+	handler, err := NewHandler(30*time.Second, "test-service", createTestLogger(), WithStatusRemap(map[int]int{404: 200}))
+	require.NoError(t, err)
+
+	t.Run("configured code is remapped", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/proxy/"+notFound, nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "upstream body", rr.Body.String())
+	})
+
+	t.Run("other codes pass through", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/proxy/"+failing, nil))
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+}
@@ -0,0 +1,46 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// DefaultMaxRepeat is the default limit on the count of a single /repeat/ directive
+const DefaultMaxRepeat = 100
+
+// WithMaxRepeat limits how many times a /repeat/ directive may send a request; requests over the
+// limit get 400. A limit of 0 allows any number.
+func WithMaxRepeat(n int) HandlerOption {
+	return func(h *Handler) {
+		h.maxRepeat = n
+	}
+}
+
+// parseRepeat parses the count of a /repeat/ directive
+func parseRepeat(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid repeat: count must be a positive number")
+	}
+	return n, nil
+}
+
+// doRepeated sends the request built by newRequest n times in turn, each with the usual retries,
+// and returns the last response. Earlier responses are read to the end and discarded; a send that
+// fails ends the repetition with its error.
+func (h *Handler) doRepeated(ctx context.Context, newRequest func() (*http.Request, error), n int, logger *slog.Logger) (*http.Response, error) {
+	for i := 1; i < n; i++ {
+		resp, err := h.doWithRetries(ctx, newRequest, logger)
+		if err != nil {
+			return nil, fmt.Errorf("repeat %d of %d: %w", i, n, err)
+		}
+		logger.Debug("Repeated request answered", slog.Int("repeat", i), slog.Int("status_code", resp.StatusCode))
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}
+	return h.doWithRetries(ctx, newRequest, logger)
+}
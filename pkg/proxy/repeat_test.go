@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepeat(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		n := len(bodies)
+		mu.Unlock()
+		w.Header().Set("X-Request-Number", strings.Repeat("i", n))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+	hop := strings.TrimPrefix(upstream.URL, "http://")
+
+	h, err := NewHandler(5*time.Second, "test-service", createTestLogger(), WithMaxRepeat(10))
+	require.NoError(t, err)
+
+	t.Run("sends the request n times and answers with the last response", func(t *testing.T) {
+		bodies = nil
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/repeat/5/proxy/"+hop, strings.NewReader(`{"n":1}`)))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, []string{`{"n":1}`, `{"n":1}`, `{"n":1}`, `{"n":1}`, `{"n":1}`}, bodies)
+		assert.Equal(t, "iiiii", rr.Header().Get("X-Request-Number"))
+	})
+
+	t.Run("without repeat the request is sent once", func(t *testing.T) {
+		bodies = nil
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/proxy/"+hop, nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Len(t, bodies, 1)
+	})
+
+	t.Run("counts over the maximum are rejected", func(t *testing.T) {
+		bodies = nil
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/repeat/11/proxy/"+hop, nil))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, ErrCodeBadPath, decodeErrorResponse(t, rr).Code)
+		assert.Empty(t, bodies)
+	})
+
+	t.Run("an unreachable hop ends the repetition", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/repeat/3/proxy/127.0.0.1:1", nil))
+		assert.Equal(t, http.StatusBadGateway, rr.Code)
+	})
+}
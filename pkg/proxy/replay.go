@@ -0,0 +1,89 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// replayStatusHeader carries the recorded status on a replayed response so callers can compare
+const replayStatusHeader = "X-Replay-Recorded-Status"
+
+// maxReplayBodyBytes caps the size of a record posted to /replay. Recorded bodies of up to
+// maxRecordedBodyBytes grow by a third when base64 encoded, so this leaves room for them and headers.
+const maxReplayBodyBytes = 2 * maxRecordedBodyBytes
+
+// ServeReplay answers POST /replay, whose body is a Record as written by --record-file, by running
+// the recorded request through the proxy chain again and returning its response. The recorded body
+// and headers are resent, except redacted headers. Records whose body was too large to keep are
+// rejected, since replaying them without it would not reproduce the request.
+func (h *Handler) ServeReplay(w http.ResponseWriter, r *http.Request) {
+	logger := h.logger.With(slog.String("method", r.Method), slog.String("path", r.URL.Path))
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		h.writeError(w, r, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Replay requires POST with a recorded request as the body", logger)
+		return
+	}
+
+	var rec Record
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxReplayBodyBytes)).Decode(&rec); err != nil {
+		h.writeDecodeError(w, r, err, "recorded request", logger)
+		return
+	}
+
+	req, err := replayRequest(r, rec)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("Invalid recorded request: %v", err), logger)
+		return
+	}
+
+	logger.Info("Replaying recorded request", slog.String("replay_method", req.Method), slog.String("replay_path", rec.Path), slog.Int("recorded_status", rec.Status))
+	if rec.Status != 0 {
+		w.Header().Set(replayStatusHeader, strconv.Itoa(rec.Status))
+	}
+	h.serve(w, req)
+}
+
+// replayRequest rebuilds the request described by rec, on the context and connection of the
+// replay request r
+func replayRequest(r *http.Request, rec Record) (*http.Request, error) {
+	if rec.Method == "" {
+		rec.Method = http.MethodGet
+	}
+	if !strings.HasPrefix(rec.Path, "/") {
+		return nil, fmt.Errorf("path %q must start with /", rec.Path)
+	}
+	if rec.BodyOmitted {
+		return nil, errors.New("request body was too large to record, so the request cannot be replayed")
+	}
+
+	target := &url.URL{Path: rec.Path, RawQuery: rec.Query}
+	body := io.Reader(http.NoBody)
+	if len(rec.Body) > 0 {
+		body = bytes.NewReader(rec.Body)
+	}
+	req, err := http.NewRequestWithContext(r.Context(), rec.Method, target.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Host = r.Host
+	req.RemoteAddr = r.RemoteAddr
+	req.RequestURI = target.RequestURI()
+
+	for key, values := range rec.RequestHeaders {
+		for _, value := range values {
+			if value != "[REDACTED]" {
+				req.Header.Add(key, value)
+			}
+		}
+	}
+	return req, nil
+}
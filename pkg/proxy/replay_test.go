@@ -0,0 +1,117 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplay(t *testing.T) {
+	var upstreamHeader, upstreamBody string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamHeader = r.Header.Get("X-Test")
+		body, _ := io.ReadAll(r.Body)
+		upstreamBody = string(body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"upstream":true}`))
+	}))
+	defer upstream.Close()
+	target := strings.TrimPrefix(upstream.URL, "http://")
+
+	path := filepath.Join(t.TempDir(), "records.jsonl")
+	h, err := NewHandler(30*time.Second, "test-service", createTestLogger(), WithRecordFile(path), WithRecordHeaders(true))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = h.Close() })
+
+	replay := func(t *testing.T, rec Record) *httptest.ResponseRecorder {
+		t.Helper()
+		body, err := json.Marshal(rec)
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		h.ServeReplay(rr, httptest.NewRequest(http.MethodPost, "/replay", bytes.NewReader(body)))
+		return rr
+	}
+
+	t.Run("replays a recorded request with the same result", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/proxy/"+target+"?id=1", nil)
+		req.Header.Set("X-Test", "recorded")
+		req.Header.Set("Authorization", "Bearer secret")
+		original := httptest.NewRecorder()
+		h.ServeHTTP(original, req)
+
+		records := readRecords(t, path)
+		require.Len(t, records, 1)
+		upstreamHeader = ""
+
+		rr := replay(t, records[0])
+		assert.Equal(t, original.Code, rr.Code)
+		assert.Equal(t, original.Body.String(), rr.Body.String())
+		assert.Equal(t, "202", rr.Header().Get(replayStatusHeader))
+		assert.Equal(t, "recorded", upstreamHeader, "recorded headers are resent")
+
+		assert.Len(t, readRecords(t, path), 1, "replays are not recorded again")
+	})
+
+	t.Run("replays the recorded body", func(t *testing.T) {
+		before := len(readRecords(t, path))
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/proxy/"+target, strings.NewReader(`{"id":2}`)))
+
+		records := readRecords(t, path)
+		require.Len(t, records, before+1)
+		upstreamBody = ""
+
+		rr := replay(t, records[before])
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		assert.Equal(t, `{"id":2}`, upstreamBody)
+	})
+
+	t.Run("rejects records whose body was left out", func(t *testing.T) {
+		rr := replay(t, Record{Method: http.MethodPost, Path: "/proxy/" + target, BodyOmitted: true})
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("replays local directives", func(t *testing.T) {
+		rr := replay(t, Record{Method: http.MethodGet, Path: "/fault/503", Status: http.StatusServiceUnavailable})
+		assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+		assert.Equal(t, "503", rr.Header().Get(replayStatusHeader))
+	})
+
+	t.Run("redacted headers are not resent", func(t *testing.T) {
+		req, err := replayRequest(httptest.NewRequest(http.MethodPost, "/replay", nil), Record{
+			Path:           "/",
+			RequestHeaders: map[string][]string{"Authorization": {"[REDACTED]"}, "X-Test": {"a"}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, http.MethodGet, req.Method)
+		assert.Empty(t, req.Header.Get("Authorization"))
+		assert.Equal(t, "a", req.Header.Get("X-Test"))
+	})
+
+	t.Run("rejects oversized records with 413", func(t *testing.T) {
+		rr := replay(t, Record{Method: http.MethodPost, Path: "/", Body: bytes.Repeat([]byte("x"), maxReplayBodyBytes)})
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		assert.Equal(t, ErrCodePayloadTooLarge, decodeErrorResponse(t, rr).Code)
+	})
+
+	t.Run("rejects invalid records", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		h.ServeReplay(rr, httptest.NewRequest(http.MethodPost, "/replay", strings.NewReader("not json")))
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+		assert.Equal(t, http.StatusBadRequest, replay(t, Record{Path: "no-slash"}).Code)
+
+		rr = httptest.NewRecorder()
+		h.ServeReplay(rr, httptest.NewRequest(http.MethodGet, "/replay", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+	})
+}
@@ -0,0 +1,22 @@
+package proxy
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// requestIDHeader carries a caller-chosen request ID, used in place of a generated one
+const requestIDHeader = "X-Request-Id"
+
+// maxRequestIDLength bounds caller-supplied request IDs so they cannot bloat every log line
+const maxRequestIDLength = 128
+
+// requestID returns the request's X-Request-Id, or a new random (version 4) UUID when the header
+// is missing or too long
+func requestID(r *http.Request) string {
+	if id := r.Header.Get(requestIDHeader); id != "" && len(id) <= maxRequestIDLength {
+		return id
+	}
+	return uuid.NewString()
+}
@@ -0,0 +1,89 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	h, err := NewHandler(5*time.Second, "test-service", logger)
+	require.NoError(t, err)
+
+	// incoming returns the "Incoming request" log entries written so far
+	incoming := func(t *testing.T) []map[string]any {
+		var entries []map[string]any
+		scanner := bufio.NewScanner(bytes.NewReader(buf.Bytes()))
+		for scanner.Scan() {
+			var entry map[string]any
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+			if entry["msg"] == "Incoming request" {
+				entries = append(entries, entry)
+			}
+		}
+		return entries
+	}
+
+	t.Run("generated IDs are unique UUIDs", func(t *testing.T) {
+		buf.Reset()
+		const requests = 500
+		var wg sync.WaitGroup
+		for range requests {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+			}()
+		}
+		wg.Wait()
+
+		entries := incoming(t)
+		require.Len(t, entries, requests)
+		seen := make(map[string]bool, requests)
+		for _, entry := range entries {
+			id, ok := entry["request_id"].(string)
+			require.True(t, ok)
+			_, err := uuid.Parse(id)
+			assert.NoError(t, err, id)
+			assert.False(t, seen[id], "duplicate request ID %s", id)
+			seen[id] = true
+			assert.Greater(t, entry["request_timestamp"], float64(0))
+		}
+	})
+
+	t.Run("uses the X-Request-Id header", func(t *testing.T) {
+		buf.Reset()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Request-Id", "caller-chosen-id")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+
+		entries := incoming(t)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "caller-chosen-id", entries[0]["request_id"])
+	})
+
+	t.Run("overlong header is replaced", func(t *testing.T) {
+		buf.Reset()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Request-Id", strings.Repeat("x", maxRequestIDLength+1))
+		h.ServeHTTP(httptest.NewRecorder(), req)
+
+		entries := incoming(t)
+		require.Len(t, entries, 1)
+		_, err := uuid.Parse(entries[0]["request_id"].(string))
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,54 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+)
+
+// sendReset starts a response promising more than n bytes, writes n bytes of it, then closes the
+// connection with SO_LINGER 0 so the client sees a TCP reset mid-body rather than a clean close
+func (h *Handler) sendReset(w http.ResponseWriter, r *http.Request, n int64, logger *slog.Logger) {
+	if n > h.maxPayloadBytes {
+		logger.Info("Requested payload too large", slog.Int64("bytes", n), slog.Int64("max_payload_bytes", h.maxPayloadBytes))
+		h.writeError(w, r, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("Requested %d bytes exceeds the maximum of %d", n, h.maxPayloadBytes), logger)
+		return
+	}
+
+	conn, buf, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		logger.Error("Failed to hijack connection for reset", slog.String("error", err.Error()))
+		if errors.Is(err, http.ErrNotSupported) {
+			h.writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Connection reset is only supported over HTTP/1.x", logger)
+		}
+		return
+	}
+	defer resetConn(conn, logger)
+
+	// Declare one byte more than is sent so the body can never complete
+	_, _ = fmt.Fprintf(buf, "HTTP/1.1 200 OK\r\nContent-Type: application/octet-stream\r\nContent-Length: %d\r\n\r\n", n+1)
+	for i := int64(0); i < n; i++ {
+		_ = buf.WriteByte('x')
+	}
+	if err := buf.Flush(); err != nil {
+		logger.Error("Failed to write bytes before reset", slog.String("error", err.Error()))
+	}
+	logger.Info("Resetting connection", slog.Int64("bytes_written", n))
+}
+
+// resetConn closes conn with SO_LINGER 0, which discards unsent data and sends a RST
+func resetConn(conn net.Conn, logger *slog.Logger) {
+	raw := conn
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		raw = tlsConn.NetConn()
+	}
+	if tcpConn, ok := raw.(*net.TCPConn); ok {
+		if err := tcpConn.SetLinger(0); err != nil {
+			logger.Error("Failed to set linger for reset", slog.String("error", err.Error()))
+		}
+	}
+	_ = raw.Close()
+}
@@ -0,0 +1,46 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResetDirective(t *testing.T) {
+	handler, err := NewHandler(30*time.Second, "test-service", createTestLogger(), WithMaxPayloadBytes(1<<20))
+	require.NoError(t, err)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	// get returns the error the client saw, whether before or while reading the body
+	get := func(path string) error {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = resp.Body.Close() }()
+		_, err = io.ReadAll(resp.Body)
+		return err
+	}
+
+	for _, path := range []string{"/reset/0", "/reset/1024", "/delay/1/reset/10"} {
+		t.Run(path, func(t *testing.T) {
+			err := get(path)
+			require.Error(t, err)
+			assert.ErrorIs(t, err, syscall.ECONNRESET, "client should see a reset, not a clean close")
+		})
+	}
+
+	t.Run("size above the cap is rejected", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/reset/2000000")
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
@@ -0,0 +1,47 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// responseTimeHeader reports how long this hop took to start its response, in milliseconds
+const responseTimeHeader = "X-Response-Time"
+
+// WithLatencyHeader sets an X-Response-Time header on every response with the time in
+// milliseconds from the request reaching the handler to the response status being written.
+// A value from a later hop is replaced with this hop's own.
+func WithLatencyHeader(enabled bool) HandlerOption {
+	return func(h *Handler) {
+		h.latencyHeader = enabled
+	}
+}
+
+// responseTimeWriter stamps X-Response-Time as the final status is written. Informational
+// responses such as 100 Continue pass through unstamped.
+type responseTimeWriter struct {
+	http.ResponseWriter
+	start       time.Time
+	wroteHeader bool
+}
+
+func (t *responseTimeWriter) WriteHeader(statusCode int) {
+	if !t.wroteHeader && statusCode >= http.StatusOK {
+		t.wroteHeader = true
+		t.Header().Set(responseTimeHeader, fmt.Sprintf("%.3f", float64(time.Since(t.start).Microseconds())/1000))
+	}
+	t.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (t *responseTimeWriter) Write(b []byte) (int, error) {
+	if !t.wroteHeader {
+		t.WriteHeader(http.StatusOK)
+	}
+	return t.ResponseWriter.Write(b)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (t *responseTimeWriter) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
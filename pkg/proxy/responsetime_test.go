@@ -0,0 +1,66 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatencyHeader(t *testing.T) {
+	logger := createTestLogger()
+
+	// responseTime returns the X-Response-Time of a response in milliseconds
+	responseTime := func(t *testing.T, rr *httptest.ResponseRecorder) float64 {
+		value := rr.Header().Get("X-Response-Time")
+		require.NotEmpty(t, value)
+		ms, err := strconv.ParseFloat(value, 64)
+		require.NoError(t, err)
+		return ms
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		h, err := NewHandler(5*time.Second, "test-service", logger)
+		require.NoError(t, err)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Empty(t, rr.Header().Get("X-Response-Time"))
+	})
+
+	h, err := NewHandler(5*time.Second, "test-service", logger, WithLatencyHeader(true))
+	require.NoError(t, err)
+
+	t.Run("reports the handling time", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/delay/50", nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+		ms := responseTime(t, rr)
+		assert.GreaterOrEqual(t, ms, 50.0)
+		assert.Less(t, ms, 5000.0)
+	})
+
+	t.Run("error responses are timed too", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/nope", nil))
+		require.Equal(t, http.StatusNotFound, rr.Code)
+		assert.GreaterOrEqual(t, responseTime(t, rr), 0.0)
+	})
+
+	t.Run("replaces the next hop's value with this hop's", func(t *testing.T) {
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Response-Time", "999999.000")
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer upstream.Close()
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/proxy/"+strings.TrimPrefix(upstream.URL, "http://"), nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Less(t, responseTime(t, rr), 5000.0)
+	})
+}
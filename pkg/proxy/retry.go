@@ -0,0 +1,90 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+// WithMaxRetries retries a failed upstream request up to n more times. Transport errors are
+// always retried; responses are retried only when their status is set with WithRetryOnStatus.
+func WithMaxRetries(n int) HandlerOption {
+	return func(h *Handler) {
+		h.maxRetries = n
+	}
+}
+
+// WithRetryOnStatus sets the upstream status codes that warrant a retry, within the WithMaxRetries budget
+func WithRetryOnStatus(codes []int) HandlerOption {
+	return func(h *Handler) {
+		h.retryOnStatus = make(map[int]bool, len(codes))
+		for _, code := range codes {
+			h.retryOnStatus[code] = true
+		}
+	}
+}
+
+// upstreamRequests returns a function building a fresh upstream request for each attempt. When
+// the request is to be sent more than once (replay), retries are enabled or a transform is set,
+// the incoming body is buffered so every attempt can resend it.
+func (h *Handler) upstreamRequests(ctx context.Context, r *http.Request, url string, replay bool) (func() (*http.Request, error), error) {
+	contentLength := r.ContentLength
+	var buffered []byte
+	if (replay || h.maxRetries > 0 || h.transform != nil) && contentLength != 0 {
+		body, err := h.readBody(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+		buffered = body
+		contentLength = int64(len(body))
+	}
+
+	return func() (*http.Request, error) {
+		var body io.Reader = r.Body
+		if buffered != nil {
+			body = bytes.NewReader(buffered)
+		}
+		req, err := h.newUpstreamRequest(ctx, r, url, body)
+		if err != nil {
+			return nil, err
+		}
+
+		// Keep the body's length so it is forwarded as-is rather than re-chunked. An empty body is sent as
+		// none at all, otherwise methods such as TRACE go out with an empty chunked body.
+		req.ContentLength = contentLength
+		if contentLength == 0 {
+			req.Body = http.NoBody
+		}
+		return req, nil
+	}, nil
+}
+
+// doWithRetries sends the request built by newRequest, retrying on transport errors and
+// retryable statuses until it succeeds, the retry budget runs out, or ctx ends
+func (h *Handler) doWithRetries(ctx context.Context, newRequest func() (*http.Request, error), logger *slog.Logger) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := h.client.Do(req)
+		if attempt == h.maxRetries || ctx.Err() != nil {
+			return resp, err
+		}
+
+		if err != nil {
+			logger.Warn("Retrying next hop after error", slog.Int("attempt", attempt+1), slog.String("error", err.Error()))
+			continue
+		}
+		if !h.retryOnStatus[resp.StatusCode] {
+			return resp, nil
+		}
+
+		logger.Warn("Retrying next hop after status", slog.Int("attempt", attempt+1), slog.Int("status_code", resp.StatusCode))
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}
+}
@@ -0,0 +1,140 @@
+package proxy
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sequenceUpstream returns each status in turn (repeating the last), recording the request bodies it received
+type sequenceUpstream struct {
+	mu       sync.Mutex
+	statuses []int
+	bodies   []string
+}
+
+func (s *sequenceUpstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	s.mu.Lock()
+	status := s.statuses[min(len(s.bodies), len(s.statuses)-1)]
+	s.bodies = append(s.bodies, string(body))
+	s.mu.Unlock()
+
+	w.WriteHeader(status)
+}
+
+func (s *sequenceUpstream) attempts() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.bodies)
+}
+
+func TestRetryOnStatus(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		maxRetries   int
+		retryOn      []int
+		wantStatus   int
+		wantAttempts int
+	}{
+		{
+			name:         "retryable then non-retryable code",
+			statuses:     []int{503, 404},
+			maxRetries:   3,
+			retryOn:      []int{502, 503},
+			wantStatus:   http.StatusNotFound,
+			wantAttempts: 2,
+		},
+		{
+			name:         "retryable then success",
+			statuses:     []int{502, 503, 200},
+			maxRetries:   3,
+			retryOn:      []int{502, 503},
+			wantStatus:   http.StatusOK,
+			wantAttempts: 3,
+		},
+		{
+			name:         "retries exhausted",
+			statuses:     []int{503},
+			maxRetries:   2,
+			retryOn:      []int{503},
+			wantStatus:   http.StatusServiceUnavailable,
+			wantAttempts: 3,
+		},
+		{
+			name:         "status not listed is not retried",
+			statuses:     []int{500, 200},
+			maxRetries:   3,
+			retryOn:      []int{502, 503},
+			wantStatus:   http.StatusInternalServerError,
+			wantAttempts: 1,
+		},
+		{
+			name:         "codes ignored without max retries",
+			statuses:     []int{503, 200},
+			retryOn:      []int{503},
+			wantStatus:   http.StatusServiceUnavailable,
+			wantAttempts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := &sequenceUpstream{statuses: tt.statuses}
+			server := httptest.NewServer(upstream)
+			defer server.Close()
+
+			handler, err := NewHandler(30*time.Second, "test-service", createTestLogger(),
+				WithMaxRetries(tt.maxRetries), WithRetryOnStatus(tt.retryOn))
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, "/proxy/"+strings.TrimPrefix(server.URL, "http://"), strings.NewReader("payload"))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			assert.Equal(t, tt.wantAttempts, upstream.attempts())
+			for _, body := range upstream.bodies {
+				assert.Equal(t, "payload", body, "every attempt should resend the body")
+			}
+		})
+	}
+}
+
+func TestRetryOnTransportError(t *testing.T) {
+	// Accept connections and hang up immediately so every attempt fails at the transport
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = ln.Close() }()
+
+	var accepted atomic.Int32
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			accepted.Add(1)
+			_ = conn.Close()
+		}
+	}()
+
+	handler, err := NewHandler(30*time.Second, "test-service", createTestLogger(), WithMaxRetries(2))
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/proxy/"+ln.Addr().String(), nil))
+	assert.Equal(t, http.StatusBadGateway, rr.Code)
+	assert.GreaterOrEqual(t, accepted.Load(), int32(3))
+}
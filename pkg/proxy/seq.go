@@ -0,0 +1,101 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxSeqStatuses caps how many statuses a single /seq/ directive may list
+const maxSeqStatuses = 100
+
+// parseSeqStatuses parses the comma-separated status list of a /seq/ directive
+func parseSeqStatuses(s string) ([]int, error) {
+	fields := strings.Split(s, ",")
+	if len(fields) > maxSeqStatuses {
+		return nil, fmt.Errorf("invalid seq: at most %d statuses allowed", maxSeqStatuses)
+	}
+
+	statuses := make([]int, 0, len(fields))
+	for _, field := range fields {
+		code, err := strconv.Atoi(field)
+		if err != nil || code < 200 || code > 599 {
+			return nil, fmt.Errorf("invalid seq: %q is not a status code between 200 and 599", field)
+		}
+		// Every response carries a JSON body, which these statuses forbid
+		if code == http.StatusNoContent || code == http.StatusNotModified {
+			return nil, fmt.Errorf("invalid seq: status %d cannot carry a response body", code)
+		}
+		statuses = append(statuses, code)
+	}
+	return statuses, nil
+}
+
+// sequenceTTL is how long a path no request has reached stays tracked. Paths come from clients,
+// so forgotten ones are dropped, starting their cycle afresh, to keep the map bounded.
+const sequenceTTL = 5 * time.Minute
+
+// maxTrackedSequences caps how many paths are tracked at once; beyond it, new paths go untracked,
+// always answering the first position, until older ones expire
+const maxTrackedSequences = 1000
+
+// sequences tracks how far each path has advanced through a cycle of calls: its /seq/ status
+// list, or its /flaky/ period
+type sequences struct {
+	now func() time.Time // Clock for expiring idle paths, time.Now when nil
+
+	mu   sync.Mutex
+	next map[string]*sequenceState
+}
+
+// sequenceState is the next position of a tracked path and when a request last reached it
+type sequenceState struct {
+	next     int
+	lastSeen time.Time
+}
+
+// advance returns the status for this call to key and moves the key on to the next status,
+// wrapping around to the start once the list is exhausted
+func (s *sequences) advance(key string, statuses []int) int {
+	return statuses[s.position(key, len(statuses))]
+}
+
+// position returns where this call to key falls in a cycle of n calls, from 0 to n-1, and moves
+// the key on to the next position. A new key evicts keys not seen within sequenceTTL to make room.
+func (s *sequences) position(key string, n int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.now != nil {
+		now = s.now()
+	}
+	if s.next == nil {
+		s.next = make(map[string]*sequenceState)
+	}
+	state, ok := s.next[key]
+	if !ok {
+		for k, existing := range s.next {
+			if now.Sub(existing.lastSeen) > sequenceTTL {
+				delete(s.next, k)
+			}
+		}
+		state = &sequenceState{}
+		if len(s.next) < maxTrackedSequences {
+			s.next[key] = state
+		}
+	}
+	state.lastSeen = now
+
+	i := state.next % n
+	state.next = (i + 1) % n
+	return i
+}
+
+// isErrorStatus reports whether a /seq/ status should answer the request rather than let it continue
+func isErrorStatus(code int) bool {
+	return code >= http.StatusBadRequest
+}
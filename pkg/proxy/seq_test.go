@@ -0,0 +1,83 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeqDirective(t *testing.T) {
+	handler, err := NewHandler(30*time.Second, "test-service", createTestLogger())
+	require.NoError(t, err)
+
+	get := func(path string) int {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		return rr.Code
+	}
+
+	t.Run("follows the list and repeats", func(t *testing.T) {
+		var got []int
+		for range 6 {
+			got = append(got, get("/seq/503,503,200"))
+		}
+		assert.Equal(t, []int{503, 503, 200, 503, 503, 200}, got)
+	})
+
+	t.Run("sequences are kept per path", func(t *testing.T) {
+		assert.Equal(t, http.StatusInternalServerError, get("/seq/500,201"))
+		assert.Equal(t, http.StatusTooManyRequests, get("/seq/429,202"))
+		assert.Equal(t, http.StatusCreated, get("/seq/500,201"))
+		assert.Equal(t, http.StatusAccepted, get("/seq/429,202"))
+	})
+
+	t.Run("success continues down the path", func(t *testing.T) {
+		var hits int
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits++
+			w.WriteHeader(http.StatusTeapot)
+		}))
+		defer upstream.Close()
+
+		path := "/seq/503,200/proxy/" + strings.TrimPrefix(upstream.URL, "http://")
+		assert.Equal(t, http.StatusServiceUnavailable, get(path))
+		assert.Equal(t, 0, hits, "error status should answer without forwarding")
+		assert.Equal(t, http.StatusTeapot, get(path))
+		assert.Equal(t, 1, hits)
+	})
+}
+
+func TestSequencesForgetIdlePaths(t *testing.T) {
+	now := time.Now()
+	seqs := sequences{now: func() time.Time { return now }}
+
+	assert.Equal(t, 0, seqs.position("/seq/500,200", 2))
+	assert.Equal(t, 0, seqs.position("/seq/503,200", 2))
+
+	// Paths still reached by requests are kept; the rest are dropped once a new one arrives
+	now = now.Add(sequenceTTL / 2)
+	assert.Equal(t, 1, seqs.position("/seq/503,200", 2))
+	now = now.Add(sequenceTTL/2 + time.Second)
+	seqs.position("/seq/429,200", 2)
+
+	assert.Len(t, seqs.next, 2)
+	assert.NotContains(t, seqs.next, "/seq/500,200")
+	assert.Equal(t, 0, seqs.position("/seq/500,200", 2), "a forgotten path starts its cycle afresh")
+	assert.Equal(t, 0, seqs.position("/seq/503,200", 2))
+}
+
+func TestSequencesCap(t *testing.T) {
+	var seqs sequences
+	for i := range maxTrackedSequences + 10 {
+		seqs.position(fmt.Sprintf("/seq/%d", i), 2)
+	}
+	assert.Len(t, seqs.next, maxTrackedSequences, "paths beyond the cap are not tracked")
+	assert.Equal(t, 0, seqs.position("/seq/untracked", 2))
+	assert.Equal(t, 0, seqs.position("/seq/untracked", 2), "an untracked path stays at the first position")
+}
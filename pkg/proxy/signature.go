@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// signatureHeader carries the hex-encoded HMAC-SHA256 of the request body, optionally prefixed
+// with "sha256=" as webhook senders commonly do
+const signatureHeader = "X-Signature"
+
+// WithHMACSecret requires every request to carry an X-Signature header holding the HMAC-SHA256 of
+// its body under secret. Requests with a missing or wrong signature get 401.
+func WithHMACSecret(secret string) HandlerOption {
+	return func(h *Handler) {
+		h.hmacSecret = []byte(secret)
+	}
+}
+
+// verifySignature reads the request body and checks it against the X-Signature header, comparing
+// in constant time. The body is replaced so it can still be forwarded. On failure the request is
+// answered with 401 (or 408 if the body arrives too slowly) and false is returned.
+func (h *Handler) verifySignature(w http.ResponseWriter, r *http.Request) bool {
+	signature := strings.TrimPrefix(r.Header.Get(signatureHeader), "sha256=")
+	if signature == "" {
+		h.logger.Info("Rejecting unsigned request", slog.String("path", r.URL.Path))
+		h.writeError(w, r, http.StatusUnauthorized, ErrCodeUnauthorized, fmt.Sprintf("Missing %s header", signatureHeader), h.logger)
+		return false
+	}
+
+	body, err := io.ReadAll(r.Body)
+	_ = r.Body.Close()
+	if errors.Is(err, errBodyReadTimeout) {
+		h.writeBodyReadError(w, r, err, h.logger)
+		return false
+	}
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("Failed to read request body: %v", err), h.logger)
+		return false
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	got, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(got, bodySignature(h.hmacSecret, body)) {
+		h.logger.Info("Rejecting request with invalid signature", slog.String("path", r.URL.Path))
+		h.writeError(w, r, http.StatusUnauthorized, ErrCodeUnauthorized, fmt.Sprintf("Invalid %s header", signatureHeader), h.logger)
+		return false
+	}
+	return true
+}
+
+// bodySignature returns the HMAC-SHA256 of body under secret
+func bodySignature(secret, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
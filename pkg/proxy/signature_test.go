@@ -0,0 +1,72 @@
+package proxy
+
+import (
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHMACSignature(t *testing.T) {
+	const secret = "test-secret"
+	const body = `{"event":"push"}`
+
+	var forwarded string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		forwarded = string(b)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+	path := "/proxy/" + strings.TrimPrefix(upstream.URL, "http://")
+
+	h, err := NewHandler(5*time.Second, "test-service", createTestLogger(), WithHMACSecret(secret))
+	require.NoError(t, err)
+
+	valid := hex.EncodeToString(bodySignature([]byte(secret), []byte(body)))
+	send := func(signature string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if signature != "" {
+			req.Header.Set(signatureHeader, signature)
+		}
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("valid signature", func(t *testing.T) {
+		forwarded = ""
+		assert.Equal(t, http.StatusOK, send(valid).Code)
+		assert.Equal(t, body, forwarded, "the verified body is forwarded")
+	})
+
+	t.Run("valid signature with sha256 prefix", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, send("sha256="+valid).Code)
+	})
+
+	tests := []struct {
+		name      string
+		signature string
+	}{
+		{name: "missing signature", signature: ""},
+		{name: "wrong secret", signature: hex.EncodeToString(bodySignature([]byte("other"), []byte(body)))},
+		{name: "signature of another body", signature: hex.EncodeToString(bodySignature([]byte(secret), []byte("{}")))},
+		{name: "not hex", signature: "not-a-signature"},
+		{name: "truncated", signature: valid[:32]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forwarded = ""
+			rr := send(tt.signature)
+			assert.Equal(t, http.StatusUnauthorized, rr.Code)
+			assert.Equal(t, ErrCodeUnauthorized, decodeErrorResponse(t, rr).Code)
+			assert.Empty(t, forwarded, "rejected requests are not forwarded")
+		})
+	}
+}
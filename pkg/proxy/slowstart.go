@@ -0,0 +1,17 @@
+package proxy
+
+import "time"
+
+// slowStartDelay returns the delay for a request arriving elapsed after server start, decreasing
+// linearly from maxDelay at start to zero once the window has passed. This simulates warmup such
+// as JIT compilation or cold caches.
+func slowStartDelay(elapsed, window, maxDelay time.Duration) time.Duration {
+	if elapsed >= window {
+		return 0
+	}
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	remaining := float64(window-elapsed) / float64(window)
+	return time.Duration(float64(maxDelay) * remaining)
+}
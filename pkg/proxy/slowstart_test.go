@@ -0,0 +1,55 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlowStartDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		want    time.Duration
+	}{
+		{name: "at start", elapsed: 0, want: 400 * time.Millisecond},
+		{name: "quarter way", elapsed: 2500 * time.Millisecond, want: 300 * time.Millisecond},
+		{name: "half way", elapsed: 5 * time.Second, want: 200 * time.Millisecond},
+		{name: "window ended", elapsed: 10 * time.Second, want: 0},
+		{name: "after window", elapsed: time.Minute, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, slowStartDelay(tt.elapsed, 10*time.Second, 400*time.Millisecond))
+		})
+	}
+}
+
+func TestSlowStartRamp(t *testing.T) {
+	handler, err := NewHandler(30*time.Second, "test-service", createTestLogger())
+	require.NoError(t, err)
+
+	measure := func(sinceStart time.Duration) time.Duration {
+		handler.started = time.Now().Add(-sinceStart)
+		start := time.Now()
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/slowstart/2/300", nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+		return time.Since(start)
+	}
+
+	// Requests later in the 2s window are delayed less, and not at all once it has passed
+	early := measure(0)
+	middle := measure(time.Second)
+	late := measure(3 * time.Second)
+
+	assert.GreaterOrEqual(t, early, 300*time.Millisecond)
+	assert.GreaterOrEqual(t, middle, 150*time.Millisecond)
+	assert.Less(t, middle, early)
+	assert.Less(t, late, 100*time.Millisecond)
+}
@@ -0,0 +1,144 @@
+package proxy
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// ResponseFields names the fields of response bodies built from Response
+type ResponseFields struct {
+	Status  string
+	Service string
+	Message string
+}
+
+// DefaultResponseFields are the field names of the Response struct
+var DefaultResponseFields = ResponseFields{Status: "status", Service: "service", Message: "message"}
+
+// responseStyles are the named styles accepted by ParseResponseStyle
+var responseStyles = map[string]ResponseFields{
+	"default": DefaultResponseFields,
+	"code":    {Status: "code", Service: "name", Message: "detail"},
+}
+
+// fieldNamePattern keeps custom names valid as JSON keys, XML element names, and text keys
+var fieldNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// WithResponseFields sets the field names used in final and fault response bodies
+func WithResponseFields(fields ResponseFields) HandlerOption {
+	return func(h *Handler) {
+		h.responseFields = fields
+	}
+}
+
+// ParseResponseStyle parses a named style ("default" or "code") or a custom mapping such as
+// "status=code,service=name,message=detail". Fields left out of a mapping keep their default names.
+func ParseResponseStyle(style string) (ResponseFields, error) {
+	if fields, ok := responseStyles[style]; ok {
+		return fields, nil
+	}
+	if !strings.Contains(style, "=") {
+		return ResponseFields{}, fmt.Errorf("invalid response style %q: must be default, code, or a mapping like status=code,service=name", style)
+	}
+
+	fields := DefaultResponseFields
+	for _, pair := range strings.Split(style, ",") {
+		field, name, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || !fieldNamePattern.MatchString(name) {
+			return ResponseFields{}, fmt.Errorf("invalid response style mapping %q: must be <field>=<name> with a name of letters, digits, '_', '.', or '-'", pair)
+		}
+		switch field {
+		case "status":
+			fields.Status = name
+		case "service":
+			fields.Service = name
+		case "message":
+			fields.Message = name
+		default:
+			return ResponseFields{}, fmt.Errorf("invalid response style mapping %q: field must be status, service, or message", pair)
+		}
+	}
+
+	if fields.Status == fields.Service || fields.Status == fields.Message || fields.Service == fields.Message {
+		return ResponseFields{}, fmt.Errorf("invalid response style %q: field names must be distinct", style)
+	}
+	return fields, nil
+}
+
+// encode writes response in the given media type using these field names, in the same layout
+// as encoding Response directly: fields in order, with an empty message omitted
+func (f ResponseFields) encode(w io.Writer, mediaType string, response Response) error {
+	switch mediaType {
+	case mediaTypeXML:
+		enc := xml.NewEncoder(w)
+		root := xml.StartElement{Name: xml.Name{Local: "response"}}
+		if err := enc.EncodeToken(root); err != nil {
+			return err
+		}
+		if err := enc.EncodeElement(response.Status, xml.StartElement{Name: xml.Name{Local: f.Status}}); err != nil {
+			return err
+		}
+		if err := enc.EncodeElement(response.Service, xml.StartElement{Name: xml.Name{Local: f.Service}}); err != nil {
+			return err
+		}
+		if response.Message != "" {
+			if err := enc.EncodeElement(response.Message, xml.StartElement{Name: xml.Name{Local: f.Message}}); err != nil {
+				return err
+			}
+		}
+		if err := enc.EncodeToken(root.End()); err != nil {
+			return err
+		}
+		return enc.Flush()
+
+	case mediaTypeText:
+		_, err := fmt.Fprintf(w, "%s: %d\n%s: %s\n%s: %s\n", f.Status, response.Status, f.Service, response.Service, f.Message, response.Message)
+		return err
+
+	default:
+		body, err := f.marshalJSON(response)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(body, '\n'))
+		return err
+	}
+}
+
+// marshalJSON renders response as a JSON object using these field names
+func (f ResponseFields) marshalJSON(response Response) ([]byte, error) {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, field := range []struct {
+		name  string
+		value any
+	}{
+		{f.Status, response.Status},
+		{f.Service, response.Service},
+		{f.Message, response.Message},
+	} {
+		if field.name == f.Message && response.Message == "" {
+			continue
+		}
+		key, err := json.Marshal(field.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,106 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseResponseStyle(t *testing.T) {
+	tests := []struct {
+		name    string
+		style   string
+		want    ResponseFields
+		wantErr bool
+	}{
+		{name: "default", style: "default", want: DefaultResponseFields},
+		{name: "code", style: "code", want: ResponseFields{Status: "code", Service: "name", Message: "detail"}},
+		{name: "full mapping", style: "status=http_status,service=svc,message=msg", want: ResponseFields{Status: "http_status", Service: "svc", Message: "msg"}},
+		{name: "partial mapping keeps defaults", style: "service=name", want: ResponseFields{Status: "status", Service: "name", Message: "message"}},
+		{name: "unknown style", style: "fancy", wantErr: true},
+		{name: "unknown field", style: "status=code,color=blue", wantErr: true},
+		{name: "invalid name", style: "status=has space", wantErr: true},
+		{name: "duplicate names", style: "status=name,service=name", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseResponseStyle(tt.style)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestResponseStyles(t *testing.T) {
+	get := func(t *testing.T, fields ResponseFields, path, accept string) *httptest.ResponseRecorder {
+		t.Helper()
+		handler, err := NewHandler(30*time.Second, "test-service", createTestLogger(), WithResponseFields(fields))
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept", accept)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("default matches encoding Response directly", func(t *testing.T) {
+		response := Response{Status: http.StatusOK, Service: "test-service", Message: "Request processed successfully"}
+
+		var wantJSON bytes.Buffer
+		require.NoError(t, json.NewEncoder(&wantJSON).Encode(response))
+		assert.Equal(t, wantJSON.String(), get(t, DefaultResponseFields, "/", "application/json").Body.String())
+
+		wantXML, err := xml.Marshal(response)
+		require.NoError(t, err)
+		assert.Equal(t, string(wantXML), get(t, DefaultResponseFields, "/", "application/xml").Body.String())
+	})
+
+	codeStyle := ResponseFields{Status: "code", Service: "name", Message: "detail"}
+
+	t.Run("code style JSON", func(t *testing.T) {
+		rr := get(t, codeStyle, "/", "application/json")
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, `{"code":200,"name":"test-service","detail":"Request processed successfully"}`+"\n", rr.Body.String())
+	})
+
+	t.Run("code style XML", func(t *testing.T) {
+		rr := get(t, codeStyle, "/", "application/xml")
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, `<response><code>200</code><name>test-service</name><detail>Request processed successfully</detail></response>`, rr.Body.String())
+	})
+
+	t.Run("code style plain text", func(t *testing.T) {
+		rr := get(t, codeStyle, "/", "text/plain")
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "code: 200\nname: test-service\ndetail: Request processed successfully\n", rr.Body.String())
+	})
+
+	t.Run("custom mapping applies to faults", func(t *testing.T) {
+		fields, err := ParseResponseStyle("status=http_status,message=reason")
+		require.NoError(t, err)
+
+		rr := get(t, fields, "/fault/503", "application/json")
+		require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+		assert.Equal(t, map[string]any{
+			"http_status": float64(503),
+			"service":     "test-service",
+			"reason":      "Fault injected: 503 Service Unavailable",
+		}, body)
+	})
+}
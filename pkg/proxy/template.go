@@ -0,0 +1,50 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"text/template"
+)
+
+// TemplateData is the data available to a response template
+type TemplateData struct {
+	Service string      // Name of the service rendering the response
+	Status  int         // Status code of the response
+	Method  string      // Method of the incoming request
+	Path    string      // Path of the incoming request
+	Query   string      // Raw query string of the incoming request
+	Headers http.Header // Headers of the incoming request
+}
+
+// loadResponseTemplate parses the template file so syntax errors surface at startup
+func loadResponseTemplate(path string) (*template.Template, error) {
+	content, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("reading response template %q: %w", path, err)
+	}
+
+	tmpl, err := template.New(filepath.Base(path)).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("parsing response template %q: %w", path, err)
+	}
+	return tmpl, nil
+}
+
+// renderResponseTemplate executes the template for the request, returning the body and its content type.
+// Output that is valid JSON is served as application/json, anything else is sniffed.
+func renderResponseTemplate(tmpl *template.Template, data TemplateData) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, "", fmt.Errorf("executing response template: %w", err)
+	}
+
+	body := buf.Bytes()
+	if json.Valid(body) {
+		return body, mediaTypeJSON, nil
+	}
+	return body, http.DetectContentType(body), nil
+}
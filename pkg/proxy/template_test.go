@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTemplate writes a response template to a temporary file and returns its path
+func writeTemplate(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "response.tmpl")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestResponseTemplate(t *testing.T) {
+	logger := createTestLogger()
+
+	t.Run("json template interpolates service and path", func(t *testing.T) {
+		path := writeTemplate(t, `{"svc":"{{.Service}}","path":"{{.Path}}","method":"{{.Method}}","trace":"{{.Headers.Get "X-Trace"}}"}`)
+		handler, err := NewHandler(30*time.Second, "test-service", logger, WithResponseTemplate(path))
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Trace", "abc")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{"svc":"test-service","path":"/","method":"GET","trace":"abc"}`, rr.Body.String())
+	})
+
+	t.Run("plain text template", func(t *testing.T) {
+		path := writeTemplate(t, "hello from {{.Service}} at {{.Path}} ({{.Status}})")
+		handler, err := NewHandler(30*time.Second, "test-service", logger, WithResponseTemplate(path))
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodGet, "/fault/500/0", nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "text/plain; charset=utf-8", rr.Header().Get("Content-Type"))
+		assert.Equal(t, "hello from test-service at /fault/500/0 (200)", rr.Body.String())
+	})
+
+	t.Run("invalid template fails at startup", func(t *testing.T) {
+		path := writeTemplate(t, "{{.Service")
+		_, err := NewHandler(30*time.Second, "test-service", logger, WithResponseTemplate(path))
+		assert.Error(t, err)
+	})
+
+	t.Run("missing template file fails at startup", func(t *testing.T) {
+		_, err := NewHandler(30*time.Second, "test-service", logger, WithResponseTemplate("/nonexistent/response.tmpl"))
+		assert.Error(t, err)
+	})
+}
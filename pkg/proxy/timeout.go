@@ -0,0 +1,38 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// timeoutParam is the query parameter that overrides the timeout for a single request
+const timeoutParam = "timeout"
+
+// WithMaxRequestTimeout sets the ceiling for per-request ?timeout= overrides. Zero caps them at
+// the handler's configured timeout, so requests can only shorten it.
+func WithMaxRequestTimeout(max time.Duration) HandlerOption {
+	return func(h *Handler) {
+		h.maxRequestTimeout = max
+	}
+}
+
+// requestTimeout returns the timeout for r: its ?timeout= duration clamped to the maximum
+// request timeout, or the configured timeout when the parameter is absent
+func (h *Handler) requestTimeout(r *http.Request) (time.Duration, error) {
+	value := r.URL.Query().Get(timeoutParam)
+	if value == "" {
+		return h.timeout, nil
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("invalid timeout %q: must be a positive duration such as 2s or 500ms", value)
+	}
+
+	ceiling := h.maxRequestTimeout
+	if ceiling <= 0 {
+		ceiling = h.timeout
+	}
+	return min(timeout, ceiling), nil
+}
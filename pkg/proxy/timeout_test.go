@@ -0,0 +1,78 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestTimeout(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		maxTimeout time.Duration
+		want       time.Duration
+		wantErr    bool
+	}{
+		{name: "no parameter uses configured timeout", query: "", want: 30 * time.Second},
+		{name: "shorter timeout", query: "?timeout=2s", want: 2 * time.Second},
+		{name: "longer timeout capped at configured timeout", query: "?timeout=5m", want: 30 * time.Second},
+		{name: "longer timeout within ceiling", query: "?timeout=45s", maxTimeout: time.Minute, want: 45 * time.Second},
+		{name: "longer timeout capped at ceiling", query: "?timeout=5m", maxTimeout: time.Minute, want: time.Minute},
+		{name: "invalid duration", query: "?timeout=soon", wantErr: true},
+		{name: "zero duration", query: "?timeout=0s", wantErr: true},
+		{name: "negative duration", query: "?timeout=-1s", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := NewHandler(30*time.Second, "test-service", createTestLogger(), WithMaxRequestTimeout(tt.maxTimeout))
+			require.NoError(t, err)
+
+			got, err := h.requestTimeout(httptest.NewRequest(http.MethodGet, "/"+tt.query, nil))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRequestTimeoutOverride(t *testing.T) {
+	logger := createTestLogger()
+
+	slow, err := NewHandler(30*time.Second, "slow", logger)
+	require.NoError(t, err)
+	slowServer := httptest.NewServer(slow)
+	defer slowServer.Close()
+
+	handler, err := NewHandler(30*time.Second, "test-service", logger)
+	require.NoError(t, err)
+
+	t.Run("short per-request timeout yields 504", func(t *testing.T) {
+		start := time.Now()
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/proxy/"+strings.TrimPrefix(slowServer.URL, "http://")+"/delay/2000?timeout=200ms", nil))
+
+		assert.Equal(t, http.StatusGatewayTimeout, rr.Code)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("invalid timeout yields 400", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/?timeout=banana", nil))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		var resp ErrorResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.Equal(t, ErrCodeBadRequest, resp.Code)
+	})
+}
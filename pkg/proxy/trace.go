@@ -0,0 +1,60 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// traceParam is the query parameter that turns on tracing for a request and its later hops
+	traceParam = "trace"
+	// traceHeader lists one entry per hop, first hop first, as service;status=<code>;dur=<ms>
+	traceHeader = "X-Proxy-Trace"
+)
+
+// traceRequested reports whether the request asked for a hop-by-hop trace
+func traceRequested(r *http.Request) bool {
+	return r.URL.Query().Get(traceParam) == "true"
+}
+
+// withTrace carries the trace parameter onto an upstream URL when the request is traced
+func withTrace(r *http.Request, url string) string {
+	if !traceRequested(r) {
+		return url
+	}
+	return url + "?" + traceParam + "=true"
+}
+
+// traceWriter prepends this hop's trace entry to the response's X-Proxy-Trace header as the
+// status is written. Any trace set on the header beforehand is treated as the later hops' entries.
+type traceWriter struct {
+	http.ResponseWriter
+	service     string
+	start       time.Time
+	wroteHeader bool
+}
+
+func (t *traceWriter) WriteHeader(statusCode int) {
+	if !t.wroteHeader {
+		t.wroteHeader = true
+		entry := fmt.Sprintf("%s;status=%d;dur=%.1f", t.service, statusCode, float64(time.Since(t.start).Microseconds())/1000)
+		if downstream := t.Header().Get(traceHeader); downstream != "" {
+			entry += ", " + downstream
+		}
+		t.Header().Set(traceHeader, entry)
+	}
+	t.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (t *traceWriter) Write(b []byte) (int, error) {
+	if !t.wroteHeader {
+		t.WriteHeader(http.StatusOK)
+	}
+	return t.ResponseWriter.Write(b)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (t *traceWriter) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
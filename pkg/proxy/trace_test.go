@@ -0,0 +1,82 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrace(t *testing.T) {
+	logger := createTestLogger()
+
+	newService := func(t *testing.T, name string, opts ...HandlerOption) string {
+		t.Helper()
+		h, err := NewHandler(time.Second, name, logger, opts...)
+		require.NoError(t, err)
+		server := httptest.NewServer(h)
+		t.Cleanup(server.Close)
+		return strings.TrimPrefix(server.URL, "http://")
+	}
+
+	get := func(t *testing.T, url string) *http.Response {
+		t.Helper()
+		resp, err := http.Get(url)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return resp
+	}
+
+	entry := regexp.MustCompile(`^([^;]+);status=(\d+);dur=\d+\.\d$`)
+	hops := func(t *testing.T, header string) [][]string {
+		t.Helper()
+		var parsed [][]string
+		for _, e := range strings.Split(header, ", ") {
+			m := entry.FindStringSubmatch(e)
+			require.NotNil(t, m, "malformed trace entry %q", e)
+			parsed = append(parsed, m[1:])
+		}
+		return parsed
+	}
+
+	t.Run("lists every hop in order", func(t *testing.T) {
+		svcc := newService(t, "svcc")
+		svcb := newService(t, "svcb")
+		svca := newService(t, "svca")
+
+		resp := get(t, "http://"+svca+"/proxy/"+svcb+"/proxy/"+svcc+"?trace=true")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, [][]string{{"svca", "200"}, {"svcb", "200"}, {"svcc", "200"}}, hops(t, resp.Header.Get(traceHeader)))
+	})
+
+	t.Run("records the status each hop returned", func(t *testing.T) {
+		svcb := newService(t, "svcb")
+		svca := newService(t, "svca", WithStatusRemap(map[int]int{503: 500}))
+
+		resp := get(t, "http://"+svca+"/proxy/"+svcb+"/fault/503?trace=true")
+		require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		assert.Equal(t, [][]string{{"svca", "500"}, {"svcb", "503"}}, hops(t, resp.Header.Get(traceHeader)))
+	})
+
+	t.Run("not duplicated when propagating response headers", func(t *testing.T) {
+		svcb := newService(t, "svcb")
+		svca := newService(t, "svca", WithPropagateResponseHeaders(true))
+
+		resp := get(t, "http://"+svca+"/proxy/"+svcb+"?trace=true")
+		assert.Len(t, hops(t, resp.Header.Get(traceHeader)), 2, "trace should not be duplicated")
+		assert.Len(t, resp.Header.Values(traceHeader), 1)
+	})
+
+	t.Run("absent unless requested", func(t *testing.T) {
+		svcb := newService(t, "svcb")
+		svca := newService(t, "svca")
+
+		resp := get(t, "http://"+svca+"/proxy/"+svcb)
+		assert.Empty(t, resp.Header.Get(traceHeader))
+	})
+}
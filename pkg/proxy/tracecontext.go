@@ -0,0 +1,199 @@
+package proxy
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Trace context propagation formats for WithTracePropagation
+const (
+	// TracePropagationNone forwards trace headers untouched, like any other header
+	TracePropagationNone = "none"
+	// TracePropagationW3C reads and writes the W3C traceparent header
+	TracePropagationW3C = "w3c"
+	// TracePropagationB3 reads and writes Zipkin B3 headers, in single or multi-header form
+	TracePropagationB3 = "b3"
+)
+
+// Trace context headers
+const (
+	traceparentHeader  = "Traceparent"
+	b3Header           = "B3"
+	b3TraceIDHeader    = "X-B3-TraceId"
+	b3SpanIDHeader     = "X-B3-SpanId"
+	b3ParentSpanHeader = "X-B3-ParentSpanId"
+	b3SampledHeader    = "X-B3-Sampled"
+	b3FlagsHeader      = "X-B3-Flags"
+)
+
+// WithTracePropagation makes each hop a span in the caller's trace: the trace context is read
+// from the incoming request in the given format, or a new trace started, and passed to the next
+// hop with this hop's span as the parent. TracePropagationNone, the default, leaves trace headers
+// alone.
+func WithTracePropagation(format string) HandlerOption {
+	return func(h *Handler) {
+		h.tracePropagation = format
+	}
+}
+
+// ValidateTracePropagation checks that format is a supported propagation format
+func ValidateTracePropagation(format string) error {
+	switch format {
+	case TracePropagationNone, TracePropagationW3C, TracePropagationB3:
+		return nil
+	}
+	return fmt.Errorf("trace-propagation must be one of [%s, %s, %s], got %q", TracePropagationNone, TracePropagationW3C, TracePropagationB3, format)
+}
+
+// spanContext is this hop's span within a trace
+type spanContext struct {
+	TraceID  string // 32 hex digits, or 16 for 64-bit B3 trace IDs
+	SpanID   string // This hop's span, 16 hex digits
+	ParentID string // The caller's span, empty when this hop started the trace
+	Sampled  bool
+	// singleB3 records that the caller sent the single b3 header, so it is passed on in that form
+	singleB3 bool
+}
+
+// spanContextKey is the context key under which serveProxy passes the hop's span to upstream requests
+type spanContextKey struct{}
+
+// startSpan returns r carrying a new span for this hop, continuing the trace in the request's
+// headers when they hold a valid one
+func (h *Handler) startSpan(r *http.Request) (*http.Request, spanContext, bool) {
+	if h.tracePropagation == "" || h.tracePropagation == TracePropagationNone {
+		return r, spanContext{}, false
+	}
+
+	var parent spanContext
+	var ok bool
+	switch h.tracePropagation {
+	case TracePropagationW3C:
+		parent, ok = extractW3C(r.Header)
+	case TracePropagationB3:
+		parent, ok = extractB3(r.Header)
+	}
+
+	span := spanContext{TraceID: parent.TraceID, ParentID: parent.SpanID, Sampled: parent.Sampled, singleB3: parent.singleB3, SpanID: randomHex(8)}
+	if !ok {
+		span = spanContext{TraceID: randomHex(16), SpanID: span.SpanID, Sampled: true}
+	}
+	return r.WithContext(context.WithValue(r.Context(), spanContextKey{}, span)), span, true
+}
+
+// injectSpan writes the span held by ctx into header as the parent of the next hop's span
+func (h *Handler) injectSpan(ctx context.Context, header http.Header) {
+	span, ok := ctx.Value(spanContextKey{}).(spanContext)
+	if !ok {
+		return
+	}
+
+	switch h.tracePropagation {
+	case TracePropagationW3C:
+		flags := "00"
+		if span.Sampled {
+			flags = "01"
+		}
+		header.Set(traceparentHeader, "00-"+span.TraceID+"-"+span.SpanID+"-"+flags)
+	case TracePropagationB3:
+		sampled := "0"
+		if span.Sampled {
+			sampled = "1"
+		}
+		for _, key := range []string{b3Header, b3TraceIDHeader, b3SpanIDHeader, b3ParentSpanHeader, b3SampledHeader, b3FlagsHeader} {
+			header.Del(key)
+		}
+		// The next hop's parent is this hop's span, so this hop's span ID is the one sent, with the
+		// caller's span as its parent
+		if span.singleB3 {
+			single := span.TraceID + "-" + span.SpanID + "-" + sampled
+			if span.ParentID != "" {
+				single += "-" + span.ParentID
+			}
+			header.Set(b3Header, single)
+			return
+		}
+		header.Set(b3TraceIDHeader, span.TraceID)
+		header.Set(b3SpanIDHeader, span.SpanID)
+		header.Set(b3SampledHeader, sampled)
+		if span.ParentID != "" {
+			header.Set(b3ParentSpanHeader, span.ParentID)
+		}
+	}
+}
+
+// extractW3C reads a version 00 traceparent header of the form 00-<trace-id>-<parent-id>-<flags>
+func extractW3C(header http.Header) (spanContext, bool) {
+	fields := strings.Split(strings.TrimSpace(header.Get(traceparentHeader)), "-")
+	if len(fields) != 4 || fields[0] != "00" || !isTraceID(fields[1], 32) || !isTraceID(fields[2], 16) || !isHex(fields[3], 2) {
+		return spanContext{}, false
+	}
+	flags, _ := hex.DecodeString(fields[3])
+	return spanContext{TraceID: fields[1], SpanID: fields[2], Sampled: flags[0]&1 == 1}, true
+}
+
+// extractB3 reads the single b3 header, <trace-id>-<span-id>[-<sampled>[-<parent-span-id>]], or
+// failing that the X-B3-* headers. A debug flag counts as sampled, and an absent sampling decision
+// defaults to sampled.
+func extractB3(header http.Header) (spanContext, bool) {
+	if single := strings.TrimSpace(header.Get(b3Header)); single != "" {
+		fields := strings.Split(single, "-")
+		if len(fields) < 2 || len(fields) > 4 || !isB3TraceID(fields[0]) || !isTraceID(fields[1], 16) {
+			return spanContext{}, false
+		}
+		span := spanContext{TraceID: fields[0], SpanID: fields[1], Sampled: true, singleB3: true}
+		if len(fields) > 2 {
+			span.Sampled = fields[2] == "1" || fields[2] == "d"
+		}
+		return span, true
+	}
+
+	traceID := strings.TrimSpace(header.Get(b3TraceIDHeader))
+	spanID := strings.TrimSpace(header.Get(b3SpanIDHeader))
+	if !isB3TraceID(traceID) || !isTraceID(spanID, 16) {
+		return spanContext{}, false
+	}
+	span := spanContext{TraceID: traceID, SpanID: spanID, Sampled: true}
+	if sampled := header.Get(b3SampledHeader); sampled != "" {
+		span.Sampled = sampled == "1" || strings.EqualFold(sampled, "true")
+	}
+	if header.Get(b3FlagsHeader) == "1" {
+		span.Sampled = true
+	}
+	return span, true
+}
+
+// isB3TraceID reports whether s is a 64- or 128-bit B3 trace ID
+func isB3TraceID(s string) bool {
+	return isTraceID(s, 16) || isTraceID(s, 32)
+}
+
+// isTraceID reports whether s is an n-digit lowercase hex ID that is not all zeros, as both
+// formats require
+func isTraceID(s string, n int) bool {
+	return isHex(s, n) && strings.Trim(s, "0") != ""
+}
+
+// isHex reports whether s is exactly n lowercase hex digits
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// randomHex returns n random bytes as 2n lowercase hex digits
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
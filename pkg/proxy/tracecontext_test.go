@@ -0,0 +1,235 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractB3(t *testing.T) {
+	const traceID = "463ac35c9f6413ad48485a3953bb6124"
+	const spanID = "a2fb4a1d1a96d312"
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    spanContext
+		ok      bool
+	}{
+		{
+			name:    "multi header",
+			headers: map[string]string{"X-B3-TraceId": traceID, "X-B3-SpanId": spanID, "X-B3-Sampled": "1"},
+			want:    spanContext{TraceID: traceID, SpanID: spanID, Sampled: true},
+			ok:      true,
+		},
+		{
+			name:    "multi header not sampled",
+			headers: map[string]string{"X-B3-TraceId": traceID, "X-B3-SpanId": spanID, "X-B3-Sampled": "0"},
+			want:    spanContext{TraceID: traceID, SpanID: spanID},
+			ok:      true,
+		},
+		{
+			name:    "multi header debug",
+			headers: map[string]string{"X-B3-TraceId": traceID, "X-B3-SpanId": spanID, "X-B3-Sampled": "0", "X-B3-Flags": "1"},
+			want:    spanContext{TraceID: traceID, SpanID: spanID, Sampled: true},
+			ok:      true,
+		},
+		{
+			name:    "64-bit trace ID",
+			headers: map[string]string{"X-B3-TraceId": "48485a3953bb6124", "X-B3-SpanId": spanID},
+			want:    spanContext{TraceID: "48485a3953bb6124", SpanID: spanID, Sampled: true},
+			ok:      true,
+		},
+		{
+			name:    "single header",
+			headers: map[string]string{"b3": traceID + "-" + spanID + "-1-05e3ac9a4f6e3b90"},
+			want:    spanContext{TraceID: traceID, SpanID: spanID, Sampled: true, singleB3: true},
+			ok:      true,
+		},
+		{
+			name:    "single header without sampling",
+			headers: map[string]string{"b3": traceID + "-" + spanID},
+			want:    spanContext{TraceID: traceID, SpanID: spanID, Sampled: true, singleB3: true},
+			ok:      true,
+		},
+		{
+			name:    "single header takes precedence",
+			headers: map[string]string{"b3": traceID + "-" + spanID + "-0", "X-B3-TraceId": "48485a3953bb6124", "X-B3-SpanId": "05e3ac9a4f6e3b90"},
+			want:    spanContext{TraceID: traceID, SpanID: spanID, singleB3: true},
+			ok:      true,
+		},
+		{name: "absent", headers: map[string]string{}},
+		{name: "sampling decision only", headers: map[string]string{"b3": "0"}},
+		{name: "uppercase trace ID", headers: map[string]string{"X-B3-TraceId": strings.ToUpper(traceID), "X-B3-SpanId": spanID}},
+		{name: "zero span ID", headers: map[string]string{"X-B3-TraceId": traceID, "X-B3-SpanId": "0000000000000000"}},
+		{name: "missing span ID", headers: map[string]string{"X-B3-TraceId": traceID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for k, v := range tt.headers {
+				header.Set(k, v)
+			}
+			got, ok := extractB3(header)
+			assert.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestExtractW3C(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceparent string
+		want        spanContext
+		ok          bool
+	}{
+		{
+			name:        "sampled",
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			want:        spanContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true},
+			ok:          true,
+		},
+		{
+			name:        "not sampled",
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+			want:        spanContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"},
+			ok:          true,
+		},
+		{name: "absent"},
+		{name: "unknown version", traceparent: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{name: "zero trace ID", traceparent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{name: "short span ID", traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.traceparent != "" {
+				header.Set("traceparent", tt.traceparent)
+			}
+			got, ok := extractW3C(header)
+			assert.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestTracePropagation(t *testing.T) {
+	const traceID = "463ac35c9f6413ad48485a3953bb6124"
+	const spanID = "a2fb4a1d1a96d312"
+
+	var received http.Header
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+	}))
+	defer upstream.Close()
+	path := "/proxy/" + strings.TrimPrefix(upstream.URL, "http://")
+
+	// forward sends a request with headers through a handler using format and returns the
+	// headers the upstream received
+	forward := func(t *testing.T, format string, headers map[string]string) http.Header {
+		h, err := NewHandler(5*time.Second, "test-service", createTestLogger(), WithTracePropagation(format))
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		require.Equal(t, http.StatusOK, rr.Code)
+		return received
+	}
+
+	t.Run("b3 multi header", func(t *testing.T) {
+		got := forward(t, TracePropagationB3, map[string]string{"X-B3-TraceId": traceID, "X-B3-SpanId": spanID, "X-B3-Sampled": "1"})
+		assert.Equal(t, traceID, got.Get("X-B3-TraceId"))
+		assert.Equal(t, spanID, got.Get("X-B3-ParentSpanId"))
+		assert.True(t, isTraceID(got.Get("X-B3-SpanId"), 16))
+		assert.NotEqual(t, spanID, got.Get("X-B3-SpanId"))
+		assert.Equal(t, "1", got.Get("X-B3-Sampled"))
+		assert.Empty(t, got.Get("b3"))
+	})
+
+	t.Run("b3 single header", func(t *testing.T) {
+		got := forward(t, TracePropagationB3, map[string]string{"b3": traceID + "-" + spanID + "-0"})
+		fields := strings.Split(got.Get("b3"), "-")
+		require.Len(t, fields, 4)
+		assert.Equal(t, traceID, fields[0])
+		assert.NotEqual(t, spanID, fields[1])
+		assert.Equal(t, "0", fields[2])
+		assert.Equal(t, spanID, fields[3])
+		assert.Empty(t, got.Get("X-B3-TraceId"))
+	})
+
+	t.Run("b3 starts a trace", func(t *testing.T) {
+		got := forward(t, TracePropagationB3, nil)
+		assert.True(t, isTraceID(got.Get("X-B3-TraceId"), 32))
+		assert.True(t, isTraceID(got.Get("X-B3-SpanId"), 16))
+		assert.Empty(t, got.Get("X-B3-ParentSpanId"))
+		assert.Equal(t, "1", got.Get("X-B3-Sampled"))
+	})
+
+	t.Run("w3c", func(t *testing.T) {
+		got := forward(t, TracePropagationW3C, map[string]string{"traceparent": "00-" + traceID + "-" + spanID + "-01"})
+		parent, ok := extractW3C(got)
+		require.True(t, ok)
+		assert.Equal(t, traceID, parent.TraceID)
+		assert.NotEqual(t, spanID, parent.SpanID)
+		assert.True(t, parent.Sampled)
+	})
+
+	t.Run("none leaves headers alone", func(t *testing.T) {
+		got := forward(t, TracePropagationNone, map[string]string{"X-B3-TraceId": traceID, "X-B3-SpanId": spanID})
+		assert.Equal(t, traceID, got.Get("X-B3-TraceId"))
+		assert.Equal(t, spanID, got.Get("X-B3-SpanId"))
+		assert.Empty(t, got.Get("X-B3-ParentSpanId"))
+		assert.Empty(t, got.Get("traceparent"))
+	})
+}
+
+func TestTracePropagationChain(t *testing.T) {
+	logger := createTestLogger()
+	const traceID = "463ac35c9f6413ad48485a3953bb6124"
+
+	var received http.Header
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+	}))
+	defer upstream.Close()
+
+	serviceB, err := NewHandler(5*time.Second, "service-b", logger, WithTracePropagation(TracePropagationB3))
+	require.NoError(t, err)
+	upstreamB := httptest.NewServer(serviceB)
+	defer upstreamB.Close()
+
+	serviceA, err := NewHandler(5*time.Second, "service-a", logger, WithTracePropagation(TracePropagationB3))
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/proxy/"+strings.TrimPrefix(upstreamB.URL, "http://")+"/proxy/"+strings.TrimPrefix(upstream.URL, "http://"), nil)
+	req.Header.Set("X-B3-TraceId", traceID)
+	req.Header.Set("X-B3-SpanId", "a2fb4a1d1a96d312")
+	rr := httptest.NewRecorder()
+	serviceA.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	// The trace survives both hops, and the last hop's parent is service-b's span, not the client's
+	assert.Equal(t, traceID, received.Get("X-B3-TraceId"))
+	assert.NotEqual(t, "a2fb4a1d1a96d312", received.Get("X-B3-ParentSpanId"))
+	assert.True(t, isTraceID(received.Get("X-B3-ParentSpanId"), 16))
+}
+
+func TestValidateTracePropagation(t *testing.T) {
+	for _, format := range []string{TracePropagationNone, TracePropagationW3C, TracePropagationB3} {
+		assert.NoError(t, ValidateTracePropagation(format))
+	}
+	assert.Error(t, ValidateTracePropagation("jaeger"))
+	assert.Error(t, ValidateTracePropagation(""))
+}
@@ -0,0 +1,81 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// forbiddenTrailers are fields that framing or routing depend on, which may not be sent as trailers
+var forbiddenTrailers = map[string]bool{
+	"Content-Length":    true,
+	"Content-Type":      true,
+	"Host":              true,
+	"Trailer":           true,
+	"Transfer-Encoding": true,
+}
+
+// parseTrailer validates a /trailer/<name>/<value> directive, returning the canonical name
+func parseTrailer(name, value string) (string, error) {
+	if name == "" || strings.IndexFunc(name, func(r rune) bool { return !isTokenChar(r) }) >= 0 {
+		return "", fmt.Errorf("invalid trailer: %q is not a valid header name", name)
+	}
+	name = http.CanonicalHeaderKey(name)
+	if forbiddenTrailers[name] {
+		return "", fmt.Errorf("invalid trailer: %s cannot be sent as a trailer", name)
+	}
+	if strings.ContainsAny(value, "\r\n\x00") {
+		return "", fmt.Errorf("invalid trailer: value for %s contains control characters", name)
+	}
+	return name, nil
+}
+
+// isTokenChar reports whether r may appear in an HTTP token such as a header name (RFC 9110)
+func isTokenChar(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	default:
+		return strings.ContainsRune("!#$%&'*+-.^_`|~", r)
+	}
+}
+
+// declareTrailers announces trailers in the response header and returns a writer to use for the
+// response, plus a function that sets the trailer values once the body has been written
+func declareTrailers(w http.ResponseWriter, trailers http.Header) (http.ResponseWriter, func()) {
+	for name := range trailers {
+		w.Header().Add("Trailer", name)
+	}
+	return &trailerWriter{ResponseWriter: w}, func() {
+		for name, values := range trailers {
+			w.Header()[name] = values
+		}
+	}
+}
+
+// trailerWriter drops any Content-Length, such as one copied from an upstream response, as the
+// status is written so the body is chunked and trailers can follow it
+type trailerWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (t *trailerWriter) WriteHeader(statusCode int) {
+	if !t.wroteHeader {
+		t.wroteHeader = true
+		t.Header().Del("Content-Length")
+	}
+	t.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (t *trailerWriter) Write(b []byte) (int, error) {
+	if !t.wroteHeader {
+		t.WriteHeader(http.StatusOK)
+	}
+	return t.ResponseWriter.Write(b)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (t *trailerWriter) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
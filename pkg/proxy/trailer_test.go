@@ -0,0 +1,95 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrailerDirective(t *testing.T) {
+	newServer := func(t *testing.T, name string) string {
+		t.Helper()
+		handler, err := NewHandler(30*time.Second, name, createTestLogger())
+		require.NoError(t, err)
+		server := httptest.NewServer(handler)
+		t.Cleanup(server.Close)
+		return server.URL
+	}
+
+	get := func(t *testing.T, url string) *http.Response {
+		t.Helper()
+		resp, err := http.Get(url)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = resp.Body.Close() })
+
+		// Trailers are only populated once the body has been read
+		_, err = io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp
+	}
+
+	t.Run("final response carries trailers after a chunked body", func(t *testing.T) {
+		resp := get(t, newServer(t, "svca")+"/trailer/x-checksum/abc123/trailer/x-count/2")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, []string{"chunked"}, resp.TransferEncoding)
+		assert.Equal(t, "abc123", resp.Trailer.Get("X-Checksum"))
+		assert.Equal(t, "2", resp.Trailer.Get("X-Count"))
+	})
+
+	t.Run("trailers are added to forwarded responses", func(t *testing.T) {
+		upstream := strings.TrimPrefix(newServer(t, "svcb"), "http://")
+		resp := get(t, newServer(t, "svca")+"/trailer/x-hop/svca/proxy/"+upstream)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "svca", resp.Trailer.Get("X-Hop"))
+	})
+}
+
+func TestForwardUpstreamTrailers(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "X-Checksum")
+		_, _ = io.WriteString(w, "payload")
+		w.Header().Set("X-Checksum", "abc123")
+		// Sent without being announced
+		w.Header().Set(http.TrailerPrefix+"X-Late", "late")
+	}))
+	defer upstream.Close()
+	upstreamHost := strings.TrimPrefix(upstream.URL, "http://")
+
+	get := func(t *testing.T, opts ...HandlerOption) *http.Response {
+		t.Helper()
+		handler, err := NewHandler(30*time.Second, "test-service", createTestLogger(), opts...)
+		require.NoError(t, err)
+		server := httptest.NewServer(handler)
+		t.Cleanup(server.Close)
+
+		resp, err := http.Get(server.URL + "/proxy/" + upstreamHost)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		return resp
+	}
+
+	t.Run("trailers reach the client", func(t *testing.T) {
+		resp := get(t)
+		assert.Contains(t, resp.Trailer, "X-Checksum", "announced in the Trailer header")
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "payload", string(body))
+		assert.Equal(t, "abc123", resp.Trailer.Get("X-Checksum"))
+		assert.Equal(t, "late", resp.Trailer.Get("X-Late"))
+	})
+
+	t.Run("not forwarded without response header propagation", func(t *testing.T) {
+		resp := get(t, WithPropagateResponseHeaders(false))
+		_, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Empty(t, resp.Trailer)
+	})
+}
@@ -0,0 +1,84 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/itchyny/gojq"
+)
+
+// WithTransform sets a jq expression applied to JSON request bodies before they are forwarded
+func WithTransform(expr string) HandlerOption {
+	return func(h *Handler) {
+		h.transformExpr = expr
+	}
+}
+
+// compileTransform parses and compiles a jq expression so errors surface at startup
+func compileTransform(expr string) (*gojq.Code, error) {
+	query, err := gojq.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing transform %q: %w", expr, err)
+	}
+	code, err := gojq.Compile(query)
+	if err != nil {
+		return nil, fmt.Errorf("compiling transform %q: %w", expr, err)
+	}
+	return code, nil
+}
+
+// transformError is returned when the transform cannot be applied to a request body
+type transformError struct {
+	err error
+}
+
+func (e *transformError) Error() string {
+	return fmt.Sprintf("transforming request body: %v", e.err)
+}
+
+func (e *transformError) Unwrap() error {
+	return e.err
+}
+
+// isJSONContentType reports whether a Content-Type header declares JSON, including +json types
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == mediaTypeJSON || strings.HasSuffix(mediaType, "+json")
+}
+
+// readBody reads the request body in full, applying the transform when one is configured and
+// the body is JSON. Bodies that are not declared or do not parse as JSON are returned unchanged.
+func (h *Handler) readBody(ctx context.Context, r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil || h.transform == nil || len(body) == 0 || !isJSONContentType(r.Header.Get("Content-Type")) {
+		return body, err
+	}
+
+	var input any
+	if err := json.Unmarshal(body, &input); err != nil {
+		return body, nil
+	}
+
+	// Use the first result, as jq does when a single value is expected
+	output, ok := h.transform.RunWithContext(ctx, input).Next()
+	if !ok {
+		return nil, &transformError{err: fmt.Errorf("expression produced no output")}
+	}
+	if err, isErr := output.(error); isErr {
+		return nil, &transformError{err: err}
+	}
+
+	transformed, err := json.Marshal(output)
+	if err != nil {
+		return nil, &transformError{err: err}
+	}
+	return transformed, nil
+}
@@ -0,0 +1,89 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransform(t *testing.T) {
+	var received string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+	}))
+	defer upstream.Close()
+	target := strings.TrimPrefix(upstream.URL, "http://")
+
+	post := func(t *testing.T, handler *Handler, path, contentType, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		received = ""
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	handler, err := NewHandler(30*time.Second, "test-service", createTestLogger(),
+		WithTransform(`{name: .user.name, count: (.items | length)}`))
+	require.NoError(t, err)
+
+	sample := `{"user":{"name":"ada","id":7},"items":[1,2,3]}`
+
+	t.Run("reshapes JSON bodies", func(t *testing.T) {
+		rr := post(t, handler, "/proxy/"+target, "application/json", sample)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"name":"ada","count":3}`, received)
+	})
+
+	t.Run("applies to +json media types", func(t *testing.T) {
+		rr := post(t, handler, "/proxy/"+target, "application/vnd.api+json; charset=utf-8", sample)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"name":"ada","count":3}`, received)
+	})
+
+	t.Run("applies to fanout targets", func(t *testing.T) {
+		rr := post(t, handler, "/fanout/"+target, "application/json", sample)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"name":"ada","count":3}`, received)
+	})
+
+	t.Run("non-JSON bodies pass through unchanged", func(t *testing.T) {
+		rr := post(t, handler, "/proxy/"+target, "text/plain", sample)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, sample, received)
+	})
+
+	t.Run("malformed JSON passes through unchanged", func(t *testing.T) {
+		rr := post(t, handler, "/proxy/"+target, "application/json", `{"user":`)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, `{"user":`, received)
+	})
+
+	t.Run("failing expression returns 400", func(t *testing.T) {
+		rr := post(t, handler, "/proxy/"+target, "application/json", `{"user":"ada"}`)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Empty(t, received, "request should not be forwarded")
+	})
+
+	t.Run("combines with retries", func(t *testing.T) {
+		handler, err := NewHandler(30*time.Second, "test-service", createTestLogger(), WithTransform(`.a + 1`), WithMaxRetries(2))
+		require.NoError(t, err)
+
+		rr := post(t, handler, "/proxy/"+target, "application/json", `{"a":41}`)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "42", received)
+	})
+
+	t.Run("invalid expression fails at startup", func(t *testing.T) {
+		_, err := NewHandler(30*time.Second, "test-service", createTestLogger(), WithTransform(`{name: `))
+		assert.Error(t, err)
+	})
+}
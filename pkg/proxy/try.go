@@ -0,0 +1,76 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+)
+
+// handleTry forwards the request to the try targets in random order, falling through to the next
+// target on a connection error or 5xx response. The first response below 500 is returned, or the
+// last failure once every target has been tried. With passive health tracking, targets marked
+// unhealthy are skipped while any healthy target remains.
+func (h *Handler) handleTry(ctx context.Context, w http.ResponseWriter, r *http.Request, actions actions, logger *slog.Logger) {
+	// Buffer the body once so every attempt can resend it
+	body, err := h.readBody(ctx, r)
+	if err != nil {
+		h.writeBodyReadError(w, r, err, logger)
+		return
+	}
+
+	order := rand.Perm(len(actions.TryTargets))
+	if h.backendHealth != nil {
+		order = h.backendHealth.healthy(actions.TryTargets, order)
+	}
+	logger.Info("Trying targets", slog.Any("targets", actions.TryTargets), slog.Any("order", order), slog.String("remaining", actions.Remaining))
+
+	var lastErr error
+	for i, idx := range order {
+		target := actions.TryTargets[idx]
+		scheme, host := parseScheme(target)
+		url := fmt.Sprintf("%s://%s%s", scheme, host, actions.Remaining)
+
+		req, err := h.newUpstreamRequest(ctx, r, url, bytes.NewReader(body))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		resp, err := h.client.Do(req)
+		if err != nil {
+			logger.Warn("Try target failed", slog.String("target", url), slog.String("error", err.Error()))
+			h.observeBackend(target, true, logger)
+			lastErr = err
+			continue
+		}
+
+		h.observeBackend(target, resp.StatusCode >= http.StatusInternalServerError, logger)
+
+		// Fall through on server errors unless this is the last target
+		if resp.StatusCode >= http.StatusInternalServerError && i < len(order)-1 {
+			logger.Warn("Try target returned server error", slog.String("target", url), slog.Int("status_code", resp.StatusCode))
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+			continue
+		}
+
+		logger.Info("Try target selected", slog.String("target", url), slog.Int("status_code", resp.StatusCode))
+		err = h.forwardResponse(w, resp, logger)
+		_ = resp.Body.Close()
+		if err != nil {
+			logger.Error("Failed to forward response", slog.String("error", err.Error()))
+		}
+		return
+	}
+
+	// The last target could not be reached at all
+	if isTimeout(lastErr) {
+		h.writeError(w, r, http.StatusGatewayTimeout, ErrCodeGatewayTimeout, fmt.Sprintf("Try targets timed out: %v", lastErr), logger)
+		return
+	}
+	h.writeError(w, r, http.StatusBadGateway, ErrCodeBadGateway, fmt.Sprintf("No try target reachable: %v", lastErr), logger)
+}
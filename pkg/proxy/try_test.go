@@ -0,0 +1,105 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTry(t *testing.T) {
+	logger := createTestLogger()
+
+	newBackend := func(t *testing.T, name string) string {
+		t.Helper()
+		backend, err := NewHandler(30*time.Second, name, logger)
+		require.NoError(t, err)
+		server := httptest.NewServer(backend)
+		t.Cleanup(server.Close)
+		return strings.TrimPrefix(server.URL, "http://")
+	}
+	newFailing := func(t *testing.T, status int) string {
+		t.Helper()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+		t.Cleanup(server.Close)
+		return strings.TrimPrefix(server.URL, "http://")
+	}
+	unreachable := func(t *testing.T) string {
+		t.Helper()
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := ln.Addr().String()
+		require.NoError(t, ln.Close())
+		return addr
+	}
+
+	handler, err := NewHandler(30*time.Second, "test-service", logger)
+	require.NoError(t, err)
+
+	try := func(targets ...string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/try/"+strings.Join(targets, ","), nil))
+		return rr
+	}
+
+	t.Run("falls through 5xx to a healthy backend", func(t *testing.T) {
+		failing := newFailing(t, http.StatusServiceUnavailable)
+		healthy := newBackend(t, "healthy")
+
+		// The order is random, so repeat to cover both orderings
+		for i := 0; i < 10; i++ {
+			rr := try(failing, healthy)
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			assert.Equal(t, "healthy", resp.Service)
+		}
+	})
+
+	t.Run("falls through connection errors", func(t *testing.T) {
+		rr := try(unreachable(t), newBackend(t, "healthy"))
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("client errors are returned without falling through", func(t *testing.T) {
+		rr := try(newFailing(t, http.StatusNotFound))
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("returns the last failure when all fail", func(t *testing.T) {
+		rr := try(newFailing(t, http.StatusServiceUnavailable), newFailing(t, http.StatusServiceUnavailable))
+		assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	})
+
+	t.Run("bad gateway when nothing is reachable", func(t *testing.T) {
+		rr := try(unreachable(t), unreachable(t))
+		assert.Equal(t, http.StatusBadGateway, rr.Code)
+
+		var resp ErrorResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+		assert.Equal(t, ErrCodeBadGateway, resp.Code)
+	})
+	t.Run("target count is limited by the max fanout", func(t *testing.T) {
+		limited, err := NewHandler(30*time.Second, "test-service", logger, WithMaxFanout(2))
+		require.NoError(t, err)
+		healthy := newBackend(t, "healthy")
+
+		rr := httptest.NewRecorder()
+		limited.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/try/"+healthy+","+healthy, nil))
+		assert.Equal(t, http.StatusOK, rr.Code, "at the limit")
+
+		rr = httptest.NewRecorder()
+		limited.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/try/"+strings.Join([]string{healthy, healthy, healthy}, ","), nil))
+		assert.Equal(t, http.StatusBadRequest, rr.Code, "above the limit")
+		assert.Equal(t, ErrCodeBadPath, decodeErrorResponse(t, rr).Code)
+	})
+}
@@ -0,0 +1,38 @@
+package proxy
+
+import (
+	"context"
+	"net"
+	"net/http/httptrace"
+	"sync"
+)
+
+// upstreamConn records the remote address of the connection an upstream request was sent on
+type upstreamConn struct {
+	mu   sync.Mutex
+	addr string
+}
+
+// traceUpstreamConn returns ctx with a client trace that records the remote address of the
+// connection each upstream request gets, whether freshly dialed or reused from the pool. Retries
+// overwrite it, so it holds the address used by the last attempt.
+func traceUpstreamConn(ctx context.Context) (context.Context, *upstreamConn) {
+	u := &upstreamConn{}
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			u.mu.Lock()
+			defer u.mu.Unlock()
+			u.addr = info.Conn.RemoteAddr().String()
+		},
+	}), u
+}
+
+// ip returns the IP the upstream connection was made to, or "" if no connection was made
+func (u *upstreamConn) ip() string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if host, _, err := net.SplitHostPort(u.addr); err == nil {
+		return host
+	}
+	return u.addr
+}
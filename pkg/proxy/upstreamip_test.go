@@ -0,0 +1,52 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpstreamIPLogged(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+	u, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+	stubIP, port, err := net.SplitHostPort(u.Host)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	// Reach the stub by a name that only resolves through the alias
+	h, err := NewHandler(5*time.Second, "test-service", logger, WithHostAliases(map[string]string{"stub.internal": stubIP}))
+	require.NoError(t, err)
+
+	// The second request reuses the pooled connection, which must still be reported
+	for range 2 {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/proxy/stub.internal:"+port, nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+	}
+
+	var ips []any
+	scanner := bufio.NewScanner(bytes.NewReader(buf.Bytes()))
+	for scanner.Scan() {
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		if entry["msg"] == "Next hop response received" {
+			ips = append(ips, entry["upstream_ip"])
+		}
+	}
+	assert.Equal(t, []any{stubIP, stubIP}, ips)
+}
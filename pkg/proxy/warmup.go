@@ -0,0 +1,141 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxWarmupUpstreams caps how many upstreams a single warmup request may name
+const maxWarmupUpstreams = 100
+
+// WarmupRequest is the body of POST /admin/warmup. Each upstream is a host:port, reached over
+// plain HTTP at "/", or an http:// or https:// URL.
+type WarmupRequest struct {
+	Upstreams []string `json:"upstreams"`
+}
+
+// WarmupResult reports how warming one upstream went. Any HTTP response counts as a success,
+// since the connection it came over is now pooled.
+type WarmupResult struct {
+	Upstream   string  `json:"upstream"`
+	URL        string  `json:"url"`
+	OK         bool    `json:"ok"`
+	Status     int     `json:"status,omitempty"`
+	DurationMs float64 `json:"duration_ms"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// WarmupReport is the response to POST /admin/warmup
+type WarmupReport struct {
+	Succeeded int            `json:"succeeded"`
+	Failed    int            `json:"failed"`
+	Results   []WarmupResult `json:"results"`
+}
+
+// warmupURL returns the URL requested to warm upstream
+func warmupURL(upstream string) (string, error) {
+	if !strings.Contains(upstream, "://") {
+		if upstream == "" || strings.ContainsAny(upstream, "/?#") {
+			return "", fmt.Errorf("invalid upstream %q: must be host:port or an http:// or https:// URL", upstream)
+		}
+		return "http://" + upstream + "/", nil
+	}
+	if err := ValidateKeepaliveTarget(upstream); err != nil {
+		return "", fmt.Errorf("invalid upstream %q: must be host:port or an http:// or https:// URL", upstream)
+	}
+	return upstream, nil
+}
+
+// ServeWarmup answers POST /admin/warmup by sending one GET to each listed upstream through the
+// upstream client, concurrently, so their hostnames are resolved and a connection to each is
+// left in the pool before a benchmark starts. The report lists the outcome for each upstream.
+func (h *Handler) ServeWarmup(w http.ResponseWriter, r *http.Request) {
+	logger := h.logger.With(slog.String("method", r.Method), slog.String("path", r.URL.Path))
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		h.writeError(w, r, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Warmup requires POST with a list of upstreams as the body", logger)
+		return
+	}
+
+	var req WarmupRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		h.writeError(w, r, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("Invalid warmup request: %v", err), logger)
+		return
+	}
+	if len(req.Upstreams) == 0 || len(req.Upstreams) > maxWarmupUpstreams {
+		h.writeError(w, r, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("Invalid warmup request: between 1 and %d upstreams required", maxWarmupUpstreams), logger)
+		return
+	}
+
+	urls := make([]string, len(req.Upstreams))
+	for i, upstream := range req.Upstreams {
+		u, err := warmupURL(upstream)
+		if err != nil {
+			h.writeError(w, r, http.StatusBadRequest, ErrCodeBadRequest, fmt.Sprintf("Invalid warmup request: %v", err), logger)
+			return
+		}
+		urls[i] = u
+	}
+
+	report := WarmupReport{Results: make([]WarmupResult, len(urls))}
+	var wg sync.WaitGroup
+	for i := range urls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Results[i] = h.warm(r.Context(), req.Upstreams[i], urls[i])
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range report.Results {
+		if result.OK {
+			report.Succeeded++
+		} else {
+			report.Failed++
+		}
+	}
+	logger.Info("Warmed upstreams", slog.Int("succeeded", report.Succeeded), slog.Int("failed", report.Failed))
+
+	w.Header().Set("Content-Type", mediaTypeJSON)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logger.Error("Failed to encode warmup report", slog.String("error", err.Error()))
+	}
+}
+
+// warm issues a single GET to url, draining the body so the connection returns to the pool
+func (h *Handler) warm(ctx context.Context, upstream, url string) (result WarmupResult) {
+	result = WarmupResult{Upstream: upstream, URL: url}
+	start := time.Now()
+	defer func() {
+		result.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		h.logger.Warn("Warmup request failed", slog.String("upstream", upstream), slog.String("error", err.Error()))
+		result.Error = err.Error()
+		return result
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	result.OK = true
+	result.Status = resp.StatusCode
+	return result
+}
@@ -0,0 +1,117 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarmupURL(t *testing.T) {
+	tests := []struct {
+		upstream string
+		want     string
+		wantErr  bool
+	}{
+		{upstream: "service-b:8080", want: "http://service-b:8080/"},
+		{upstream: "https://service-c:8443/health", want: "https://service-c:8443/health"},
+		{upstream: "", wantErr: true},
+		{upstream: "service-b:8080/health", wantErr: true},
+		{upstream: "ftp://service-b", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.upstream, func(t *testing.T) {
+			got, err := warmupURL(tt.upstream)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestServeWarmup(t *testing.T) {
+	handler, err := NewHandler(5*time.Second, "test-service", createTestLogger())
+	require.NoError(t, err)
+
+	// Count the connections each stub accepts, to see that later requests reuse the warmed one
+	newStub := func(t *testing.T, status int) (string, *atomic.Int32) {
+		t.Helper()
+		var conns atomic.Int32
+		stub := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+		stub.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				conns.Add(1)
+			}
+		}
+		stub.Start()
+		t.Cleanup(stub.Close)
+		return strings.TrimPrefix(stub.URL, "http://"), &conns
+	}
+
+	warmup := func(t *testing.T, body string) (*httptest.ResponseRecorder, WarmupReport) {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		handler.ServeWarmup(rr, httptest.NewRequest(http.MethodPost, "/admin/warmup", strings.NewReader(body)))
+		var report WarmupReport
+		if rr.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &report))
+		}
+		return rr, report
+	}
+
+	t.Run("reports each upstream", func(t *testing.T) {
+		ok, okConns := newStub(t, http.StatusOK)
+		failing, _ := newStub(t, http.StatusServiceUnavailable)
+		closed := httptest.NewServer(http.NotFoundHandler())
+		closedHost := strings.TrimPrefix(closed.URL, "http://")
+		closed.Close()
+
+		body := `{"upstreams":["` + ok + `","http://` + failing + `/health","` + closedHost + `"]}`
+		rr, report := warmup(t, body)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		assert.Equal(t, mediaTypeJSON, rr.Header().Get("Content-Type"))
+		assert.Equal(t, 2, report.Succeeded)
+		assert.Equal(t, 1, report.Failed)
+		require.Len(t, report.Results, 3)
+
+		assert.Equal(t, WarmupResult{Upstream: ok, URL: "http://" + ok + "/", OK: true, Status: http.StatusOK, DurationMs: report.Results[0].DurationMs}, report.Results[0])
+		assert.True(t, report.Results[1].OK, "any response means a pooled connection")
+		assert.Equal(t, http.StatusServiceUnavailable, report.Results[1].Status)
+		assert.False(t, report.Results[2].OK)
+		assert.NotEmpty(t, report.Results[2].Error)
+
+		// The proxied request rides the connection warmup opened
+		proxied := httptest.NewRecorder()
+		handler.ServeHTTP(proxied, httptest.NewRequest(http.MethodGet, "/proxy/"+ok, nil))
+		assert.Equal(t, http.StatusOK, proxied.Code)
+		assert.Equal(t, int32(1), okConns.Load())
+	})
+
+	t.Run("rejects malformed requests", func(t *testing.T) {
+		for _, body := range []string{`{"upstreams":[]}`, `{"upstreams":["a:1/b"]}`, `{"targets":["a:1"]}`, `not json`} {
+			rr, _ := warmup(t, body)
+			assert.Equal(t, http.StatusBadRequest, rr.Code, body)
+			assert.Equal(t, ErrCodeBadRequest, decodeErrorResponse(t, rr).Code, body)
+		}
+	})
+
+	t.Run("requires POST", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.ServeWarmup(rr, httptest.NewRequest(http.MethodGet, "/admin/warmup", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+		assert.Equal(t, http.MethodPost, rr.Header().Get("Allow"))
+	})
+}
@@ -0,0 +1,134 @@
+package proxy
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithWebSocket configures whether upgrade requests such as WebSocket handshakes are forwarded
+// to the next hop and, once it switches protocols, piped through in both directions
+func WithWebSocket(enabled bool) HandlerOption {
+	return func(h *Handler) {
+		h.websocket = enabled
+	}
+}
+
+// isUpgradeRequest reports whether r asks to switch protocols via Connection: Upgrade
+func isUpgradeRequest(r *http.Request) bool {
+	if r.Header.Get("Upgrade") == "" {
+		return false
+	}
+	for _, value := range r.Header.Values("Connection") {
+		for _, token := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// forwardUpgrade sends the upgrade request r to the next hop. If the hop switches protocols, the
+// client connection is hijacked and bytes are piped both ways until either side closes;
+// otherwise the hop's response is forwarded as usual.
+func (h *Handler) forwardUpgrade(w http.ResponseWriter, r *http.Request, url string, logger *slog.Logger) {
+	// The session outlives the request timeout, so only the client going away ends the handshake
+	req, err := h.newUpstreamRequest(r.Context(), r, url, nil)
+	if err != nil {
+		logger.Error("Failed to create upgrade request", slog.String("error", err.Error()), slog.String("next_hop_url", url))
+		h.writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, fmt.Sprintf("Failed to create next hop request: %v", err), logger)
+		return
+	}
+	// The handshake headers are needed even when header propagation is off
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", r.Header.Get("Upgrade"))
+	for key, values := range r.Header {
+		if strings.HasPrefix(key, "Sec-Websocket-") {
+			req.Header[key] = values
+		}
+	}
+
+	client := *h.client
+	client.Timeout = 0
+	resp, err := client.Do(req)
+	if err != nil {
+		logger.Error("Upgrade request failed", slog.String("error", err.Error()), slog.String("next_hop_url", url))
+		h.writeError(w, r, http.StatusBadGateway, ErrCodeBadGateway, fmt.Sprintf("Next hop error: %v", err), logger)
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		logger.Info("Next hop declined upgrade", slog.Int("status_code", resp.StatusCode))
+		if err := h.forwardResponse(w, resp, logger); err != nil {
+			logger.Error("Failed to forward response", slog.String("error", err.Error()), slog.Int("upstream_status", resp.StatusCode))
+		}
+		return
+	}
+
+	upstream, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		logger.Error("Upgraded response body is not writable")
+		h.writeError(w, r, http.StatusBadGateway, ErrCodeBadGateway, "Next hop switched protocols without a usable connection", logger)
+		return
+	}
+
+	conn, buf, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		logger.Error("Failed to hijack connection for upgrade", slog.String("error", err.Error()))
+		if errors.Is(err, http.ErrNotSupported) {
+			h.writeError(w, r, http.StatusInternalServerError, ErrCodeInternal, "Protocol upgrades are only supported over HTTP/1.x", logger)
+		}
+		return
+	}
+	defer func() { _ = conn.Close() }()
+
+	// Relay the hop's 101 so the client completes the same handshake
+	_, _ = fmt.Fprintf(buf, "HTTP/1.1 101 Switching Protocols\r\n")
+	_ = resp.Header.Write(buf)
+	_, _ = buf.WriteString("\r\n")
+	if err := buf.Flush(); err != nil {
+		logger.Error("Failed to write upgrade response", slog.String("error", err.Error()))
+		return
+	}
+
+	logger.Info("Connection upgraded", slog.String("protocol", resp.Header.Get("Upgrade")), slog.String("next_hop_url", url))
+	start := time.Now()
+	pipeUpgraded(conn, buf.Reader, upstream)
+	logger.Info("Upgraded connection closed", slog.Duration("duration", time.Since(start)))
+}
+
+// pipeUpgraded copies bytes between the hijacked client connection and the upstream connection
+// until either direction ends, then closes both. Reads from the client go through its buffered
+// reader so bytes sent straight after the handshake are not lost.
+func pipeUpgraded(client net.Conn, clientReader *bufio.Reader, upstream io.ReadWriteCloser) {
+	var once sync.Once
+	closeBoth := func() {
+		once.Do(func() {
+			_ = client.Close()
+			_ = upstream.Close()
+		})
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		defer closeBoth()
+		_, _ = io.Copy(upstream, clientReader)
+	}()
+	go func() {
+		defer wg.Done()
+		defer closeBoth()
+		_, _ = io.Copy(client, upstream)
+	}()
+	wg.Wait()
+}
@@ -0,0 +1,139 @@
+package proxy
+
+import (
+	"bufio"
+	"crypto/sha1" // #nosec G505 -- required by the WebSocket handshake, not used for security
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// websocketAccept computes the Sec-WebSocket-Accept value for a handshake key
+func websocketAccept(key string) string {
+	sum := sha1.Sum([]byte(key + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11")) // #nosec G401 -- see import
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// newEchoUpgradeServer completes a WebSocket handshake and then echoes raw bytes back
+func newEchoUpgradeServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isUpgradeRequest(r) {
+			http.Error(w, "upgrade required", http.StatusUpgradeRequired)
+			return
+		}
+		conn, buf, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		_, _ = fmt.Fprintf(buf, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+			websocketAccept(r.Header.Get("Sec-WebSocket-Key")))
+		_ = buf.Flush()
+		_, _ = io.Copy(conn, buf)
+	}))
+}
+
+func TestWebSocketForwarding(t *testing.T) {
+	upstream := newEchoUpgradeServer(t)
+	defer upstream.Close()
+	target := strings.TrimPrefix(upstream.URL, "http://")
+
+	// handshake dials the proxy and sends a WebSocket upgrade for path
+	handshake := func(t *testing.T, proxyURL, path string) (net.Conn, *bufio.Reader, *http.Response) {
+		t.Helper()
+		conn, err := net.Dial("tcp", strings.TrimPrefix(proxyURL, "http://"))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = conn.Close() })
+		require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+
+		_, err = fmt.Fprintf(conn, "GET %s HTTP/1.1\r\nHost: proxy\r\nConnection: Upgrade\r\nUpgrade: websocket\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n", path)
+		require.NoError(t, err)
+		reader := bufio.NewReader(conn)
+		resp, err := http.ReadResponse(reader, nil)
+		require.NoError(t, err)
+		return conn, reader, resp
+	}
+
+	t.Run("pipes bytes through the hop", func(t *testing.T) {
+		h, err := NewHandler(5*time.Second, "test-service", createTestLogger(), WithWebSocket(true))
+		require.NoError(t, err)
+		proxy := httptest.NewServer(h)
+		defer proxy.Close()
+
+		conn, reader, resp := handshake(t, proxy.URL, "/proxy/"+target)
+		require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+		assert.Equal(t, "websocket", resp.Header.Get("Upgrade"))
+		assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", resp.Header.Get("Sec-WebSocket-Accept"))
+
+		for _, msg := range []string{"hello", "world"} {
+			_, err = conn.Write([]byte(msg))
+			require.NoError(t, err)
+			echoed := make([]byte, len(msg))
+			_, err = io.ReadFull(reader, echoed)
+			require.NoError(t, err)
+			assert.Equal(t, msg, string(echoed))
+		}
+	})
+
+	t.Run("declined upgrade is forwarded", func(t *testing.T) {
+		plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "no websockets here", http.StatusBadRequest)
+		}))
+		defer plain.Close()
+
+		h, err := NewHandler(5*time.Second, "test-service", createTestLogger(), WithWebSocket(true))
+		require.NoError(t, err)
+		proxy := httptest.NewServer(h)
+		defer proxy.Close()
+
+		_, _, resp := handshake(t, proxy.URL, "/proxy/"+strings.TrimPrefix(plain.URL, "http://"))
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("final hop ignores upgrades", func(t *testing.T) {
+		h, err := NewHandler(5*time.Second, "test-service", createTestLogger(), WithWebSocket(true))
+		require.NoError(t, err)
+		proxy := httptest.NewServer(h)
+		defer proxy.Close()
+
+		_, _, resp := handshake(t, proxy.URL, "/")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+}
+
+func TestIsUpgradeRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		connection string
+		upgrade    string
+		want       bool
+	}{
+		{name: "websocket", connection: "Upgrade", upgrade: "websocket", want: true},
+		{name: "token list", connection: "keep-alive, upgrade", upgrade: "websocket", want: true},
+		{name: "no upgrade header", connection: "Upgrade", want: false},
+		{name: "no connection token", connection: "keep-alive", upgrade: "websocket", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.connection != "" {
+				r.Header.Set("Connection", tt.connection)
+			}
+			if tt.upgrade != "" {
+				r.Header.Set("Upgrade", tt.upgrade)
+			}
+			assert.Equal(t, tt.want, isUpgradeRequest(r))
+		})
+	}
+}
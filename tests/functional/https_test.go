@@ -15,6 +15,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -392,6 +393,20 @@ func TestMixedHTTPAndHTTPS(t *testing.T) {
 
 		// Should successfully reach the HTTPS service
 		assert.Equal(t, "https-service", response["service"])
+
+		t.Run("proxys_shortcut", func(t *testing.T) {
+			url := fmt.Sprintf("http://localhost:%s/proxys/https-service:8443", mappedPort.Port())
+
+			resp, err := client.Get(url)
+			require.NoError(t, err)
+			defer func() { _ = resp.Body.Close() }()
+
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+			var response map[string]interface{}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+			assert.Equal(t, "https-service", response["service"])
+		})
 	})
 
 	// Verify both services exist
@@ -486,6 +501,107 @@ func TestAutoSchemeDetection(t *testing.T) {
 	})
 }
 
+func TestTLSHandshakeLogging(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping functional test in short mode")
+	}
+
+	ctx := context.Background()
+	certPath, keyPath := generateTestCertificates(t)
+
+	nw := createTestNetwork(t, ctx)
+
+	containerCertPath := "/tmp/cert.pem"
+	containerKeyPath := "/tmp/key.pem"
+
+	// Handshakes are logged at debug level
+	exposedPort := "8443/tcp"
+	containerReq := testcontainers.ContainerRequest{
+		FromDockerfile: testcontainers.FromDockerfile{
+			Context:    "../..",
+			Dockerfile: "Dockerfile",
+		},
+		ExposedPorts: []string{exposedPort},
+		Networks:     []string{nw.Name},
+		NetworkAliases: map[string][]string{
+			nw.Name: {"handshake-service"},
+		},
+		Files: []testcontainers.ContainerFile{
+			{
+				HostFilePath:      certPath,
+				ContainerFilePath: containerCertPath,
+				FileMode:          0644,
+			},
+			{
+				HostFilePath:      keyPath,
+				ContainerFilePath: containerKeyPath,
+				FileMode:          0644,
+			},
+		},
+		WaitingFor: wait.ForHTTP("/health").
+			WithPort(nat.Port(exposedPort)).
+			WithTLS(true, &tls.Config{InsecureSkipVerify: true}).
+			WithStartupTimeout(30 * time.Second),
+		Cmd: []string{
+			"serve",
+			"--port=8443",
+			"--service-name=handshake-service",
+			"--log-format=text",
+			"--log-level=debug",
+			fmt.Sprintf("--tls-cert=%s", containerCertPath),
+			fmt.Sprintf("--tls-key=%s", containerKeyPath),
+		},
+	}
+
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: containerReq,
+		Started:          true,
+	})
+	require.NoError(t, err)
+	defer func() { _ = container.Terminate(ctx) }()
+
+	mappedPort, err := container.MappedPort(ctx, nat.Port(exposedPort))
+	require.NoError(t, err)
+
+	t.Run("logs_negotiated_parameters", func(t *testing.T) {
+		client := &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{
+					ServerName:         "handshake.example.com",
+					InsecureSkipVerify: true,
+					MinVersion:         tls.VersionTLS13,
+				},
+			},
+		}
+		resp, err := client.Get(fmt.Sprintf("https://localhost:%s/health", mappedPort.Port()))
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		// containerLogs returns everything the service has logged so far
+		containerLogs := func() string {
+			logs, err := container.Logs(ctx)
+			if err != nil {
+				return ""
+			}
+			defer func() { _ = logs.Close() }()
+			logBytes, _ := io.ReadAll(logs)
+			return string(logBytes)
+		}
+
+		require.Eventually(t, func() bool {
+			logs := containerLogs()
+			return strings.Contains(logs, "TLS handshake completed") && strings.Contains(logs, "sni=handshake.example.com")
+		}, 10*time.Second, 100*time.Millisecond)
+
+		logs := containerLogs()
+		assert.Contains(t, logs, `tls_version="TLS 1.3"`)
+		assert.Contains(t, logs, "cipher_suite=TLS_")
+		assert.Contains(t, logs, "alpn=")
+	})
+}
+
 func TestCustomCABundle(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping functional test in short mode")
@@ -1,14 +1,30 @@
 package functional
 
 import (
+	"bufio"
 	"context"
+	"crypto/sha1" // #nosec G505 -- required by the WebSocket handshake, not used for security
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
+	"mime"
+	"mime/multipart"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
 )
 
 func TestProxyChainWithMultipleServices(t *testing.T) {
@@ -270,6 +286,24 @@ func TestFaultInjection(t *testing.T) {
 		t.Logf("✓ Fault injection 50%% triggered %d/%d times (%.1f%%)", faultCount, totalRequests, faultPercentage)
 	})
 
+	// Test malformed JSON fault - returns 200 with a body that fails to parse
+	t.Run("fault_badjson_always", func(t *testing.T) {
+		url := fmt.Sprintf("http://localhost:%s/fault/badjson/100", services[0].Port)
+		resp, err := http.Get(url)
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		var parsed map[string]any
+		assert.Error(t, json.Unmarshal(body, &parsed), "body should be malformed JSON: %s", string(body))
+		t.Logf("✓ Malformed JSON fault returned unparseable body")
+	})
+
 	// Test fault injection chained with proxy - fault doesn't trigger, continues to next service
 	t.Run("fault_0_percent_chains_to_proxy", func(t *testing.T) {
 		url := fmt.Sprintf("http://localhost:%s/fault/500/0/proxy/%s:%s",
@@ -290,6 +324,26 @@ func TestFaultInjection(t *testing.T) {
 		t.Logf("✓ Fault injection 0%% chained to proxy: %s -> %s", services[0].Name, services[1].Name)
 	})
 
+	// Test fault directive after a proxy hop - the downstream service applies it
+	t.Run("proxy_then_downstream_fault", func(t *testing.T) {
+		url := fmt.Sprintf("http://localhost:%s/proxy/%s:%s/fault/503",
+			services[0].Port, services[1].Name, serviceConfigs[1].Port)
+
+		resp, err := http.Get(url)
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		// The fault response comes from service-b, not service-a
+		assert.Contains(t, string(body), "Fault injected")
+		assert.Contains(t, string(body), services[1].Name)
+		t.Logf("✓ Trailing fault applied downstream by %s", services[1].Name)
+	})
+
 	// Test fault injection that triggers immediately in chain
 	t.Run("fault_100_percent_terminates_chain", func(t *testing.T) {
 		url := fmt.Sprintf("http://localhost:%s/fault/502/100/proxy/%s:%s",
@@ -312,3 +366,620 @@ func TestFaultInjection(t *testing.T) {
 		t.Logf("✓ Fault injection 100%% terminated chain before reaching %s", services[1].Name)
 	})
 }
+
+func TestFanout(t *testing.T) {
+	ctx := context.Background()
+	nw := createTestNetwork(t, ctx)
+
+	serviceConfigs := []ServiceConfig{
+		{Name: "fanout-entry", Port: "8080"},
+		{Name: "fanout-a", Port: "8080"},
+		{Name: "fanout-b", Port: "8080"},
+	}
+	services := createServices(t, ctx, nw, serviceConfigs)
+
+	url := fmt.Sprintf("http://localhost:%s/fanout/%s:%s,%s:%s",
+		services[0].Port, services[1].Name, serviceConfigs[1].Port, services[2].Name, serviceConfigs[2].Port)
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var results []struct {
+		Target string          `json:"target"`
+		Status int             `json:"status"`
+		Body   json.RawMessage `json:"body"`
+		Error  string          `json:"error"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&results))
+	require.Len(t, results, 2)
+
+	for i, result := range results {
+		assert.Equal(t, http.StatusOK, result.Status)
+		assert.Empty(t, result.Error)
+		assert.Contains(t, string(result.Body), services[i+1].Name)
+	}
+	t.Logf("✓ Fanout merged responses from %s and %s", services[1].Name, services[2].Name)
+}
+
+func TestDeadlinePropagation(t *testing.T) {
+	ctx := context.Background()
+	nw := createTestNetwork(t, ctx)
+
+	// Each hop's own timeout would allow its delay; only the propagated deadline stops the chain
+	flags := []string{"--propagate-deadline", "--timeout=2s"}
+	serviceConfigs := []ServiceConfig{
+		{Name: "deadline-a", Port: "8080", ExtraFlags: flags},
+		{Name: "deadline-b", Port: "8080", ExtraFlags: flags},
+		{Name: "deadline-c", Port: "8080", ExtraFlags: flags},
+	}
+	services := createServices(t, ctx, nw, serviceConfigs)
+
+	url := fmt.Sprintf("http://localhost:%s/delay/800/proxy/%s:%s/delay/800/proxy/%s:%s/delay/800",
+		services[0].Port, services[1].Name, serviceConfigs[1].Port, services[2].Name, serviceConfigs[2].Port)
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+
+	var body struct {
+		Code    string `json:"code"`
+		Service string `json:"service"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "PROXY_GATEWAY_TIMEOUT", body.Code)
+	assert.Equal(t, services[2].Name, body.Service, "the hop that would exceed the deadline should answer")
+	t.Logf("✓ %s returned 504 before exceeding the propagated deadline", services[2].Name)
+}
+
+func TestMaxTotalDuration(t *testing.T) {
+	ctx := context.Background()
+	nw := createTestNetwork(t, ctx)
+
+	// Every delay fits each hop's own timeout; only the chain-wide maximum stops the request
+	flags := []string{"--max-total-duration=1500ms", "--timeout=10s"}
+	serviceConfigs := []ServiceConfig{
+		{Name: "total-a", Port: "8080", ExtraFlags: flags},
+		{Name: "total-b", Port: "8080", ExtraFlags: flags},
+		{Name: "total-c", Port: "8080", ExtraFlags: flags},
+	}
+	services := createServices(t, ctx, nw, serviceConfigs)
+
+	url := fmt.Sprintf("http://localhost:%s/delay/800/proxy/%s:%s/delay/800/proxy/%s:%s/delay/800",
+		services[0].Port, services[1].Name, serviceConfigs[1].Port, services[2].Name, serviceConfigs[2].Port)
+	start := time.Now()
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+	assert.Less(t, time.Since(start), 2400*time.Millisecond, "the chain should stop well before its delays add up")
+
+	var body struct {
+		Code    string `json:"code"`
+		Service string `json:"service"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "PROXY_GATEWAY_TIMEOUT", body.Code)
+	assert.Equal(t, services[1].Name, body.Service, "the second hop's delay would pass the chain deadline")
+	t.Logf("✓ %s returned 504 once the chain would exceed its maximum total duration", services[1].Name)
+}
+
+func TestPatchBodyForwarding(t *testing.T) {
+	ctx := context.Background()
+	nw := createTestNetwork(t, ctx)
+
+	// Echo upstream on the host, reached by the last hop through testcontainers.HostInternal
+	type echoed struct {
+		Method string `json:"method"`
+		Body   string `json:"body"`
+	}
+	echo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(echoed{Method: r.Method, Body: string(body)})
+	}))
+	defer echo.Close()
+	echoPort, err := strconv.Atoi(echo.URL[strings.LastIndex(echo.URL, ":")+1:])
+	require.NoError(t, err)
+
+	serviceConfigs := []ServiceConfig{
+		{Name: "patch-a", Port: "8080"},
+		{Name: "patch-b", Port: "8080", HostAccessPorts: []int{echoPort}},
+	}
+	services := createServices(t, ctx, nw, serviceConfigs)
+
+	body := `{"op":"replace","path":"/name","value":"new"}`
+	url := fmt.Sprintf("http://localhost:%s/proxy/%s:%s/proxy/%s:%d",
+		services[0].Port, services[1].Name, serviceConfigs[1].Port, testcontainers.HostInternal, echoPort)
+	req, err := http.NewRequest(http.MethodPatch, url, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json-patch+json")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var got echoed
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+	assert.Equal(t, http.MethodPatch, got.Method)
+	assert.Equal(t, body, got.Body)
+	t.Logf("✓ PATCH body arrived intact after two hops")
+}
+
+func TestExpectContinue(t *testing.T) {
+	ctx := context.Background()
+	nw := createTestNetwork(t, ctx)
+
+	echo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	}))
+	defer echo.Close()
+	echoPort, err := strconv.Atoi(echo.URL[strings.LastIndex(echo.URL, ":")+1:])
+	require.NoError(t, err)
+
+	services := createServices(t, ctx, nw, []ServiceConfig{
+		{Name: "expect-a", Port: "8080", ExtraFlags: []string{"--enable-expect-continue"}, HostAccessPorts: []int{echoPort}},
+	})
+
+	// The delay runs before the body is read, so only the flag gets 100 Continue out early
+	body := `{"upload":"payload"}`
+	url := fmt.Sprintf("http://localhost:%s/delay/1500/proxy/%s:%d", services[0].Port, testcontainers.HostInternal, echoPort)
+	start := time.Now()
+	var continueAfter time.Duration
+	trace := &httptrace.ClientTrace{Got100Continue: func() { continueAfter = time.Since(start) }}
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodPost, url, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Expect", "100-continue")
+
+	client := &http.Client{Transport: &http.Transport{ExpectContinueTimeout: 10 * time.Second}}
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	got, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, body, string(got), "the body sent after 100 Continue reaches the upstream")
+	assert.NotZero(t, continueAfter, "the client should have received 100 Continue")
+	assert.Less(t, continueAfter, time.Second, "100 Continue should arrive before the delay ends")
+	t.Logf("✓ 100 Continue after %s, body accepted", continueAfter)
+}
+
+func TestConnectRejected(t *testing.T) {
+	ctx := context.Background()
+	nw := createTestNetwork(t, ctx)
+
+	services := createServices(t, ctx, nw, []ServiceConfig{{Name: "connect-a", Port: "8080"}})
+
+	req, err := http.NewRequest(http.MethodConnect, fmt.Sprintf("http://localhost:%s/proxy/example.com:443", services[0].Port), nil)
+	require.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestPerRequestTimeout(t *testing.T) {
+	ctx := context.Background()
+	nw := createTestNetwork(t, ctx)
+
+	serviceConfigs := []ServiceConfig{
+		{Name: "timeout-a", Port: "8080"},
+		{Name: "timeout-b", Port: "8080"},
+	}
+	services := createServices(t, ctx, nw, serviceConfigs)
+
+	// timeout-b takes 3s to answer, well within the default --timeout but not the per-request one
+	url := fmt.Sprintf("http://localhost:%s/proxy/%s:%s/delay/3000?timeout=500ms",
+		services[0].Port, services[1].Name, serviceConfigs[1].Port)
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+	t.Logf("✓ Per-request timeout of 500ms returned 504 against a slow upstream")
+}
+
+func TestTry(t *testing.T) {
+	ctx := context.Background()
+	nw := createTestNetwork(t, ctx)
+
+	serviceConfigs := []ServiceConfig{
+		{Name: "try-entry", Port: "8080"},
+		{Name: "try-unhealthy", Port: "8080", ExtraFlags: []string{"--drain-grace-period=1ms"}},
+		{Name: "try-healthy", Port: "8080"},
+	}
+	services := createServices(t, ctx, nw, serviceConfigs)
+
+	// Draining try-unhealthy makes it answer proxy requests with 503
+	drainResp, err := http.Post(fmt.Sprintf("http://localhost:%s/drain", services[1].Port), "", nil)
+	require.NoError(t, err)
+	_ = drainResp.Body.Close()
+	require.Equal(t, http.StatusAccepted, drainResp.StatusCode)
+	time.Sleep(100 * time.Millisecond)
+
+	url := fmt.Sprintf("http://localhost:%s/try/%s:%s,%s:%s",
+		services[0].Port, services[1].Name, serviceConfigs[1].Port, services[2].Name, serviceConfigs[2].Port)
+
+	// Targets are tried in random order, so repeat to cover the unhealthy one going first
+	for i := 0; i < 5; i++ {
+		resp, err := http.Get(url)
+		require.NoError(t, err)
+
+		var body struct {
+			Service string `json:"service"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		_ = resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, services[2].Name, body.Service)
+	}
+	t.Logf("✓ Try skipped the 503 from %s and returned %s", services[1].Name, services[2].Name)
+}
+
+func TestTrace(t *testing.T) {
+	ctx := context.Background()
+	nw := createTestNetwork(t, ctx)
+
+	serviceConfigs := []ServiceConfig{
+		{Name: "trace-a", Port: "8080"},
+		{Name: "trace-b", Port: "8080"},
+		{Name: "trace-c", Port: "8080"},
+	}
+	services := createServices(t, ctx, nw, serviceConfigs)
+
+	url := fmt.Sprintf("http://localhost:%s/proxy/%s:%s/proxy/%s:%s?trace=true",
+		services[0].Port, services[1].Name, serviceConfigs[1].Port, services[2].Name, serviceConfigs[2].Port)
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	trace := resp.Header.Get("X-Proxy-Trace")
+	entries := strings.Split(trace, ", ")
+	require.Len(t, entries, 3, "trace: %s", trace)
+	for i, entry := range entries {
+		assert.True(t, strings.HasPrefix(entry, services[i].Name+";status=200;dur="), "hop %d: %s", i, entry)
+	}
+	t.Logf("✓ Trace listed all three hops in order: %s", trace)
+}
+
+func TestAggregate(t *testing.T) {
+	ctx := context.Background()
+	nw := createTestNetwork(t, ctx)
+
+	serviceConfigs := []ServiceConfig{
+		{Name: "aggregate-a", Port: "8080"},
+		{Name: "aggregate-b", Port: "8080"},
+		{Name: "aggregate-c", Port: "8080"},
+	}
+	services := createServices(t, ctx, nw, serviceConfigs)
+
+	url := fmt.Sprintf("http://localhost:%s/proxy/%s:%s/proxy/%s:%s?aggregate=true",
+		services[0].Port, services[1].Name, serviceConfigs[1].Port, services[2].Name, serviceConfigs[2].Port)
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	type hop struct {
+		Service    string          `json:"service"`
+		Status     int             `json:"status"`
+		Downstream json.RawMessage `json:"downstream"`
+	}
+	var current hop
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&current))
+	for i, service := range services {
+		assert.Equal(t, service.Name, current.Service, "hop %d", i)
+		assert.Equal(t, http.StatusOK, current.Status, "hop %d", i)
+		if i == len(services)-1 {
+			assert.Empty(t, current.Downstream, "the last hop answers directly")
+			break
+		}
+		require.NotEmpty(t, current.Downstream, "hop %d should wrap the next hop's response", i)
+		next := hop{}
+		require.NoError(t, json.Unmarshal(current.Downstream, &next))
+		current = next
+	}
+	t.Logf("✓ Aggregated response nested all three hops in order")
+}
+
+func TestConnectionReset(t *testing.T) {
+	ctx := context.Background()
+	nw := createTestNetwork(t, ctx)
+
+	services := createServices(t, ctx, nw, []ServiceConfig{{Name: "reset-svc", Port: "8080"}})
+
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%s/reset/512", services[0].Port))
+	if err == nil {
+		defer func() { _ = resp.Body.Close() }()
+		_, err = io.ReadAll(resp.Body)
+	}
+
+	require.Error(t, err, "response should not complete")
+	assert.ErrorIs(t, err, syscall.ECONNRESET, "client should see a connection reset rather than EOF")
+	t.Logf("✓ Client observed a connection reset: %v", err)
+}
+
+func TestMultipart(t *testing.T) {
+	ctx := context.Background()
+	nw := createTestNetwork(t, ctx)
+
+	serviceConfigs := []ServiceConfig{
+		{Name: "multipart-a", Port: "8080"},
+		{Name: "multipart-b", Port: "8080"},
+	}
+	services := createServices(t, ctx, nw, serviceConfigs)
+
+	url := fmt.Sprintf("http://localhost:%s/proxy/%s:%s/multipart/4",
+		services[0].Port, services[1].Name, serviceConfigs[1].Port)
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/mixed", mediaType)
+
+	reader := multipart.NewReader(resp.Body, params["boundary"])
+	count := 0
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		var body struct {
+			Part    int    `json:"part"`
+			Service string `json:"service"`
+		}
+		require.NoError(t, json.NewDecoder(part).Decode(&body))
+		count++
+		assert.Equal(t, count, body.Part)
+		assert.Equal(t, services[1].Name, body.Service)
+	}
+	assert.Equal(t, 4, count)
+	t.Logf("✓ Parsed %d parts from %s through %s", count, services[1].Name, services[0].Name)
+}
+
+func TestTrailers(t *testing.T) {
+	ctx := context.Background()
+	nw := createTestNetwork(t, ctx)
+
+	serviceConfigs := []ServiceConfig{
+		{Name: "trailer-a", Port: "8080"},
+		{Name: "trailer-b", Port: "8080"},
+	}
+	services := createServices(t, ctx, nw, serviceConfigs)
+
+	url := fmt.Sprintf("http://localhost:%s/proxy/%s:%s/trailer/x-checksum/abc123",
+		services[0].Port, services[1].Name, serviceConfigs[1].Port)
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	// Trailers arrive after the body, so it must be read first
+	_, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"chunked"}, resp.TransferEncoding)
+	assert.Equal(t, "abc123", resp.Trailer.Get("X-Checksum"))
+	t.Logf("✓ Trailer X-Checksum from %s received through %s", services[1].Name, services[0].Name)
+}
+
+func TestChunked(t *testing.T) {
+	ctx := context.Background()
+	nw := createTestNetwork(t, ctx)
+
+	serviceConfigs := []ServiceConfig{
+		{Name: "chunked-a", Port: "8080"},
+	}
+	services := createServices(t, ctx, nw, serviceConfigs)
+
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%s/chunked", services[0].Port))
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	var body map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"chunked"}, resp.TransferEncoding)
+	assert.Equal(t, services[0].Name, body["service"])
+	t.Logf("✓ %s streamed a chunked response", services[0].Name)
+}
+
+func TestGRPCStatus(t *testing.T) {
+	ctx := context.Background()
+	nw := createTestNetwork(t, ctx)
+
+	serviceConfigs := []ServiceConfig{
+		{Name: "grpc-status-a", Port: "8080"},
+	}
+	services := createServices(t, ctx, nw, serviceConfigs)
+
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%s/grpc-status/14", services[0].Port))
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	// Trailers arrive after the body, so it must be read first
+	_, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/grpc", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "14", resp.Trailer.Get("Grpc-Status"))
+	assert.Equal(t, "Fault injected: 14 Unavailable", resp.Trailer.Get("Grpc-Message"))
+	t.Logf("✓ %s answered with grpc-status 14 in trailers", services[0].Name)
+}
+
+func TestSlowBody(t *testing.T) {
+	ctx := context.Background()
+	nw := createTestNetwork(t, ctx)
+
+	serviceConfigs := []ServiceConfig{
+		{Name: "slowbody-a", Port: "8080"},
+	}
+	services := createServices(t, ctx, nw, serviceConfigs)
+
+	const interval = 20 * time.Millisecond
+	start := time.Now()
+	resp, err := http.Get(fmt.Sprintf("http://localhost:%s/slowbody/%d", services[0].Port, interval.Milliseconds()))
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	elapsed := time.Since(start)
+
+	// Bytes after the first each wait one interval; allow generous slack for container networking
+	expected := time.Duration(len(body)-1) * interval
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.GreaterOrEqual(t, elapsed, expected)
+	assert.Less(t, elapsed, expected+2*time.Second)
+	t.Logf("✓ %d byte body took %s (expected about %s)", len(body), elapsed, expected)
+}
+
+func TestWebSocketThroughHop(t *testing.T) {
+	ctx := context.Background()
+	nw := createTestNetwork(t, ctx)
+
+	// WebSocket echo upstream on the host: completes the handshake, then echoes raw bytes
+	echo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, buf, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		sum := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11")) // #nosec G401 -- WebSocket handshake
+		_, _ = fmt.Fprintf(buf, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+			base64.StdEncoding.EncodeToString(sum[:]))
+		_ = buf.Flush()
+		_, _ = io.Copy(conn, buf)
+	}))
+	defer echo.Close()
+	echoPort, err := strconv.Atoi(echo.URL[strings.LastIndex(echo.URL, ":")+1:])
+	require.NoError(t, err)
+
+	serviceConfigs := []ServiceConfig{
+		{Name: "websocket-a", Port: "8080", ExtraFlags: []string{"--enable-websocket"}, HostAccessPorts: []int{echoPort}},
+	}
+	services := createServices(t, ctx, nw, serviceConfigs)
+
+	conn, err := net.Dial("tcp", "localhost:"+services[0].Port)
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	require.NoError(t, conn.SetDeadline(time.Now().Add(10*time.Second)))
+
+	_, err = fmt.Fprintf(conn, "GET /proxy/%s:%d HTTP/1.1\r\nHost: localhost\r\nConnection: Upgrade\r\nUpgrade: websocket\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n",
+		testcontainers.HostInternal, echoPort)
+	require.NoError(t, err)
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", resp.Header.Get("Sec-WebSocket-Accept"))
+
+	_, err = conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	echoed := make([]byte, len("hello"))
+	_, err = io.ReadFull(reader, echoed)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(echoed))
+	t.Logf("✓ WebSocket message echoed through %s", services[0].Name)
+}
+
+func TestHopHeaders(t *testing.T) {
+	ctx := context.Background()
+	nw := createTestNetwork(t, ctx)
+
+	serviceConfigs := []ServiceConfig{
+		{Name: "hop-a", Port: "8080"},
+		{Name: "hop-b", Port: "8080"},
+		{Name: "hop-c", Port: "8080"},
+	}
+	services := createServices(t, ctx, nw, serviceConfigs)
+
+	url := fmt.Sprintf("http://localhost:%s/proxy/%s:%s/proxy/%s:%s",
+		services[0].Port, services[1].Name, serviceConfigs[1].Port, services[2].Name, serviceConfigs[2].Port)
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	for _, service := range services {
+		value := resp.Header.Get("X-Hop-" + service.Name)
+		assert.NotEmpty(t, value, service.Name)
+		_, err := time.Parse(time.RFC3339Nano, value)
+		assert.NoError(t, err, service.Name)
+	}
+	t.Logf("✓ Final response carried a hop header from each of %d services", len(services))
+}
+
+func TestLatencyProfile(t *testing.T) {
+	ctx := context.Background()
+	nw := createTestNetwork(t, ctx)
+
+	serviceConfigs := []ServiceConfig{
+		{Name: "latency-a", Port: "8080"},
+	}
+	services := createServices(t, ctx, nw, serviceConfigs)
+
+	// Warm up the connection pool so the first samples don't include connection setup
+	url := fmt.Sprintf("http://localhost:%s/latency/20,60,120", services[0].Port)
+	client := &http.Client{Timeout: 5 * time.Second}
+	for range 5 {
+		resp, err := client.Get(fmt.Sprintf("http://localhost:%s/", services[0].Port))
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+	}
+
+	const samples = 600
+	const workers = 10
+	durations := make([]time.Duration, samples)
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := w; i < samples; i += workers {
+				start := time.Now()
+				resp, err := client.Get(url)
+				if !assert.NoError(t, err) {
+					return
+				}
+				_, _ = io.Copy(io.Discard, resp.Body)
+				_ = resp.Body.Close()
+				durations[i] = time.Since(start)
+			}
+		}()
+	}
+	wg.Wait()
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	// Measured times include the round trip, so allow for network overhead and sampling noise
+	percentile := func(q float64) time.Duration { return durations[int(q*samples)] }
+	for _, tc := range []struct {
+		q         float64
+		want      time.Duration
+		tolerance time.Duration
+	}{
+		{q: 0.50, want: 20 * time.Millisecond, tolerance: 10 * time.Millisecond},
+		{q: 0.95, want: 60 * time.Millisecond, tolerance: 15 * time.Millisecond},
+		{q: 0.99, want: 120 * time.Millisecond, tolerance: 40 * time.Millisecond},
+	} {
+		got := percentile(tc.q)
+		assert.InDelta(t, float64(tc.want), float64(got), float64(tc.tolerance), "p%.0f: got %s, want %s", tc.q*100, got, tc.want)
+	}
+	t.Logf("✓ Measured p50=%s p95=%s p99=%s for /latency/20,60,120", percentile(0.50), percentile(0.95), percentile(0.99))
+}
@@ -23,6 +23,8 @@ type ServiceConfig struct {
 	CertFile   string
 	KeyFile    string
 	ExtraFlags []string
+	// HostAccessPorts exposes these host ports to the container at testcontainers.HostInternal
+	HostAccessPorts []int
 }
 
 // ServiceResult represents a created service with its container and mapped port
@@ -66,8 +68,9 @@ func createServices(t *testing.T, ctx context.Context, nw *testcontainers.Docker
 					Context:    "../..",
 					Dockerfile: "Dockerfile",
 				},
-				ExposedPorts: []string{exposedPort},
-				Networks:     []string{nw.Name},
+				ExposedPorts:    []string{exposedPort},
+				HostAccessPorts: config.HostAccessPorts,
+				Networks:        []string{nw.Name},
 				NetworkAliases: map[string][]string{
 					nw.Name: {config.Name},
 				},